@@ -0,0 +1,340 @@
+/*
+Copyright © 2022 david amick git@davidamick.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/snarlysodboxer/predictable-yaml/internal/embedded"
+	"github.com/snarlysodboxer/predictable-yaml/pkg/compare"
+	"github.com/snarlysodboxer/predictable-yaml/pkg/remote"
+	"github.com/spf13/cobra"
+	"go.yaml.in/yaml/v3"
+)
+
+var checkConfigsCmd = &cobra.Command{
+	Use:   "check-configs [config-dir]",
+	Short: "Check a whole config set for problems",
+	Long: `Check every config in a config set, rather than only the parts target files reach.
+Configs are loaded as lint and fix would load them, from the given config dir or the usual config sources,
+then every ditto is resolved and unknown directives, duplicate kinds, misplaced 'first' keys,
+and sequences with more than one entry are reported. Exits non-zero when there are problems.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		workDir, err := os.Getwd()
+		if err != nil {
+			log.Fatal(err)
+		}
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			log.Fatal(err)
+		}
+		projectCfg, projectCfgDir := loadProjectConfig(workDir, homeDir)
+		configDirFlag := resolveConfigDir(projectCfg, projectCfgDir)
+		if len(args) == 1 {
+			configDirFlag = args[0]
+		}
+
+		configFiles, problems := loadCheckConfigFiles(configDirFlag, workDir, homeDir, projectCfg, projectCfgDir)
+
+		// dittos are resolved against the config set as lint and fix would load it, from the files they can load,
+		//   so a problem in one file doesn't hide the ditto problems of the others
+		configNodes, mergedNodes, extendsErrs := loadCheckConfigNodes(configFiles)
+		for _, file := range configFiles {
+			fileProblems := checkConfigFile(file, nil)
+			if err, ok := extendsErrs[file.path]; ok {
+				fileProblems = append(fileProblems, configProblem{file.path, 0, err})
+			}
+			if merged, ok := mergedNodes[file.path]; ok {
+				// check the merged config, its lines are not the file's
+				fileProblems = append(fileProblems, checkConfigDittos(configFile{path: file.path, name: file.name, node: merged, noLines: true}, configNodes)...)
+			} else if compare.GetFileConfigs(file.node).Extends == "" {
+				fileProblems = append(fileProblems, checkConfigDittos(file, configNodes)...)
+			}
+			sort.SliceStable(fileProblems, func(i, j int) bool {
+				return fileProblems[i].line < fileProblems[j].line
+			})
+			problems = append(problems, fileProblems...)
+		}
+
+		for _, problem := range problems {
+			fmt.Println(problem)
+		}
+		if len(problems) != 0 {
+			log.Fatal("FAIL" + problemCountSuffix(len(problems)))
+		}
+		log.Printf("SUCCESS (%d configs checked)", len(configFiles))
+	},
+}
+
+// configFile is a config file loaded by check-configs
+type configFile struct {
+	path            string
+	name            string
+	node            *compare.Node
+	noLines         bool   // the node's lines don't match the file, as for the schema format
+	source          string // the directory the file was loaded from, or 'embedded:'
+	extendsDefaults bool   // the file can extend the embedded defaults, as local configs can without a remote config
+}
+
+// configProblem is a problem in a config file, printed as 'path:line: error'
+type configProblem struct {
+	path string
+	line int // 0 when unknown
+	err  error
+}
+
+func (problem configProblem) String() string {
+	if problem.line == 0 {
+		return fmt.Sprintf("%s: %v", problem.path, problem.err)
+	}
+
+	return fmt.Sprintf("%s:%d: %v", problem.path, problem.line, problem.err)
+}
+
+// checkConfigFile returns a config file's problems, with dittos resolved in configNodes unless it's nil
+func checkConfigFile(file configFile, configNodes compare.ConfigNodes) []configProblem {
+	return fileConfigProblems(file, compare.CheckConfig(file.node, configNodes))
+}
+
+// checkConfigDittos returns the problems with a config file's dittos, resolved in configNodes
+func checkConfigDittos(file configFile, configNodes compare.ConfigNodes) []configProblem {
+	return fileConfigProblems(file, compare.CheckConfigDittos(file.node, configNodes))
+}
+
+// fileConfigProblems returns the problems of a config file, without lines when they aren't the file's
+func fileConfigProblems(file configFile, found []compare.ConfigProblem) []configProblem {
+	problems := []configProblem{}
+	for _, problem := range found {
+		line := problem.Line
+		if file.noLines {
+			line = 0
+		}
+		problems = append(problems, configProblem{file.path, line, problem.Err})
+	}
+
+	return problems
+}
+
+// loadCheckConfigNodes loads the config set from config files as getConfigNodesByPath would, later sources
+// replacing configs of earlier ones, but skipping files it would exit on rather than exiting. It returns the
+// config set, the merged configs of files that extend another config, and the errors of those that can't.
+func loadCheckConfigNodes(configFiles []configFile) (compare.ConfigNodes, map[string]*compare.Node, map[string]error) {
+	configNodes := compare.ConfigNodes{}
+	mergedNodes := map[string]*compare.Node{}
+	extendsErrs := map[string]error{}
+	var upstreamNodes compare.ConfigNodes
+	for start := 0; start < len(configFiles); {
+		// the files of one source
+		end := start
+		for end < len(configFiles) && configFiles[end].source == configFiles[start].source {
+			end++
+		}
+		newNodes := compare.ConfigNodes{}
+		pathsByName := map[string]string{}
+		for _, file := range configFiles[start:end] {
+			if validateConfigNode(file.node) != nil {
+				continue
+			}
+			// as when loading a directory, the last file of a name wins
+			newNodes[file.name] = file.node
+			pathsByName[file.name] = file.path
+		}
+		if upstreamNodes == nil && configFiles[start].extendsDefaults && hasExtendingConfigs(newNodes) {
+			upstreamNodes = loadEmbeddedConfigNodes()
+		}
+		resolved := compare.ConfigNodes{}
+		for name := range newNodes {
+			node, err := resolveExtends(name, newNodes, configNodes, upstreamNodes, resolved, map[string]bool{})
+			if err != nil {
+				extendsErrs[pathsByName[name]] = fmt.Errorf("error extending config '%s': %w", name, err)
+				continue
+			}
+			if compare.GetFileConfigs(newNodes[name]).Extends != "" {
+				mergedNodes[pathsByName[name]] = node
+			}
+		}
+		for name, node := range resolved {
+			configNodes[name] = node
+		}
+		start = end
+	}
+
+	return configNodes, mergedNodes, extendsErrs
+}
+
+// loadCheckConfigFiles loads the config files from the same sources as getConfigNodesByPath,
+// reporting problems rather than exiting on the first one.
+func loadCheckConfigFiles(configDirFlag, workDir, homeDir string, projectCfg *ProjectConfig, projectCfgDir string) ([]configFile, []configProblem) {
+	var configDirs []string
+	if configDirFlag != "" {
+		if _, err := os.Stat(configDirFlag); err != nil {
+			log.Fatalf("error reading config dir '%s': %v", configDirFlag, err)
+		}
+		configDirs = []string{configDirFlag}
+	} else {
+		var err error
+		configDirs, err = walkFindParentConfigDirs(workDir, homeDir, []string{})
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	configDirs = filterEmptyConfigDirs(configDirs)
+
+	// remote configs, from the project config file or .remote files
+	sourceDirs := []string{}
+	if projectCfg != nil && projectCfg.Remote.Version != "" {
+		remoteURL := projectCfg.Remote.URL
+		if remoteURL == "" {
+			remoteURL = remote.DefaultRemote
+		}
+		cacheBaseDir := filepath.Join(projectCfgDir, configDirName)
+		if err := os.MkdirAll(cacheBaseDir, 0755); err != nil {
+			log.Fatalf("error creating config directory '%s': %v", cacheBaseDir, err)
+		}
+		cachePath, err := remote.FetchIfNeeded(remoteURL, projectCfg.Remote.Version, cacheBaseDir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		sourceDirs = append(sourceDirs, cachePath)
+	} else {
+		for _, dir := range configDirs {
+			remotePath := filepath.Join(dir, remote.RemoteFileName)
+			if _, err := os.Stat(remotePath); os.IsNotExist(err) {
+				continue
+			}
+			legacyConfig, err := remote.ParseRemoteConfig(remotePath)
+			if err != nil {
+				log.Fatal(err)
+			}
+			cachePath, err := remote.FetchIfNeeded(legacyConfig.Remote, legacyConfig.Version, dir)
+			if err != nil {
+				log.Fatal(err)
+			}
+			sourceDirs = append(sourceDirs, cachePath)
+		}
+	}
+
+	configFiles := []configFile{}
+	problems := []configProblem{}
+	if len(sourceDirs) == 0 && len(configDirs) == 0 {
+		files, err := embedded.GetConfigFiles()
+		if err != nil {
+			log.Fatalf("error loading embedded default configs: %v", err)
+		}
+		return parseCheckConfigFiles("embedded:", files, false)
+	}
+	for _, dir := range append(sourceDirs, configDirs...) {
+		files, err := readConfigDir(dir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		// without a remote config, local configs can extend the embedded defaults
+		dirFiles, dirProblems := parseCheckConfigFiles(dir+"/", files, len(sourceDirs) == 0)
+		configFiles = append(configFiles, dirFiles...)
+		problems = append(problems, dirProblems...)
+	}
+
+	return configFiles, problems
+}
+
+// readConfigDir reads the config files in a directory, by file name
+func readConfigDir(dir string) (map[string][]byte, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading dir '%s': %w", dir, err)
+	}
+	files := map[string][]byte{}
+	for _, entry := range entries {
+		if entry.IsDir() || !yamlFileRegex.MatchString(entry.Name()) || entry.Name() == remote.RemoteFileName {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("error reading '%s': %w", filepath.Join(dir, entry.Name()), err)
+		}
+		files[entry.Name()] = data
+	}
+
+	return files, nil
+}
+
+// parseCheckConfigFiles parses the config files of one source, reporting files that can't be parsed or named,
+// and configs defined by more than one file.
+func parseCheckConfigFiles(pathPrefix string, files map[string][]byte, extendsDefaults bool) ([]configFile, []configProblem) {
+	names := []string{}
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	configFiles := []configFile{}
+	problems := []configProblem{}
+	pathsByConfigName := map[string]string{}
+	for _, name := range names {
+		path := pathPrefix + name
+		cNode := &yaml.Node{}
+		if err := parseConfigYAML(cNode, name, files[name]); err != nil {
+			problems = append(problems, configProblem{path, 0, err})
+			continue
+		}
+		configNode := &compare.Node{Node: cNode}
+		compare.WalkConvertYamlNodeToMainNode(configNode)
+		compare.WalkParseLoadConfigComments(configNode)
+		configName := configNameForFile(name, configNode)
+		if configName == "" {
+			problems = append(problems, configProblem{path, 0, fmt.Errorf("configuration error: unable to determine a kind or fragment name")})
+			continue
+		}
+		noLines := compare.IsSchemaFile(name)
+		if otherPath, ok := pathsByConfigName[configName]; ok {
+			line := 0
+			if !noLines && len(configNode.NodeContent) != 0 {
+				line = configNode.NodeContent[0].Line
+			}
+			problems = append(problems, configProblem{path, line, fmt.Errorf("configuration error: duplicate config '%s', also defined in '%s'", configName, otherPath)})
+		} else {
+			pathsByConfigName[configName] = path
+		}
+		configFiles = append(configFiles, configFile{
+			path:            path,
+			name:            configName,
+			node:            configNode,
+			noLines:         noLines,
+			source:          pathPrefix,
+			extendsDefaults: extendsDefaults,
+		})
+	}
+
+	return configFiles, problems
+}
+
+// problemCountSuffix returns the problem count for the final FAIL line
+func problemCountSuffix(count int) string {
+	if count == 1 {
+		return " (1 problem)"
+	}
+
+	return fmt.Sprintf(" (%d problems)", count)
+}
+
+func init() {
+	rootCmd.AddCommand(checkConfigsCmd)
+}
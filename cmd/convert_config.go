@@ -0,0 +1,102 @@
+/*
+Copyright © 2022 david amick git@davidamick.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/snarlysodboxer/predictable-yaml/pkg/compare"
+	"github.com/spf13/cobra"
+	"go.yaml.in/yaml/v3"
+)
+
+// flags
+var (
+	convertOutput string
+)
+
+var convertConfigCmd = &cobra.Command{
+	Use:   "convert-config <config-file>",
+	Short: "Convert a config between the comment and schema formats",
+	Long: `Convert a config between the comment format and the structured schema format.
+Files named '*` + compare.SchemaFileSuffix + `' are converted to the comment format, any other config to the schema format.
+The result is printed, or written to the file given with --output.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		output, err := convertConfig(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if convertOutput == "" {
+			fmt.Print(string(output))
+			return
+		}
+		err = os.WriteFile(convertOutput, output, 0644)
+		if err != nil {
+			log.Fatalf("error writing '%s': %v", convertOutput, err)
+		}
+	},
+}
+
+// convertConfig returns a config file converted to the other format
+func convertConfig(filePath string) ([]byte, error) {
+	if compare.IsSchemaFile(filePath) {
+		node := &yaml.Node{}
+		err := getConfigYAML(node, filePath)
+		if err != nil {
+			return nil, err
+		}
+		var buffer bytes.Buffer
+		encoder := yaml.NewEncoder(&buffer)
+		encoder.SetIndent(2)
+		err = encoder.Encode(node)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding '%s': %w", filePath, err)
+		}
+		return append([]byte("---\n"), buffer.Bytes()...), nil
+	}
+
+	node := &yaml.Node{}
+	_, err := getYAML(node, filePath)
+	if err != nil {
+		return nil, err
+	}
+	configNode := &compare.Node{Node: node}
+	compare.WalkConvertYamlNodeToMainNode(configNode)
+	compare.WalkParseLoadConfigComments(configNode)
+	if err := validateConfigNode(configNode); err != nil {
+		return nil, fmt.Errorf("error validating config file '%s': %w", filePath, err)
+	}
+	schema, err := compare.ConfigToSchema(configNode)
+	if err != nil {
+		return nil, fmt.Errorf("error converting '%s': %w", filePath, err)
+	}
+	output, err := compare.MarshalSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte("---\n"), output...), nil
+}
+
+func init() {
+	rootCmd.AddCommand(convertConfigCmd)
+	convertConfigCmd.Flags().StringVarP(&convertOutput, "output", "o", "", "write the converted config to this file")
+}
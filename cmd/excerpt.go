@@ -0,0 +1,115 @@
+/*
+Copyright © 2022 david amick git@davidamick.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// sourceLines holds the lines of target files by path, read when first needed
+type sourceLines map[string][]string
+
+func (s sourceLines) lines(filePath string) []string {
+	if lines, ok := s[filePath]; ok {
+		return lines
+	}
+	// stdin can't be read again, so excerpts are of what was read
+	if stdinContents != nil && filePath == inputName(stdinPath) {
+		s[filePath] = strings.Split(string(stdinContents), "\n")
+		return s[filePath]
+	}
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		// without the file there's nothing to excerpt
+		s[filePath] = nil
+		return nil
+	}
+	s[filePath] = strings.Split(string(content), "\n")
+
+	return s[filePath]
+}
+
+// codeFrame returns an excerpt of lines showing the key at a line and column, underlined with '^' and a note.
+// When afterLine is given, the key on that line is shown too, underlined with '-' and afterNote.
+func codeFrame(lines []string, line, column int, note string, afterLine int, afterNote string) string {
+	type mark struct {
+		line, column int
+		underline    string
+		note         string
+	}
+	marks := []mark{{line, column, "^", note}}
+	if afterLine != 0 && afterLine != line {
+		after := mark{line: afterLine, underline: "-", note: afterNote}
+		if afterLine < line {
+			marks = append([]mark{after}, marks...)
+		} else {
+			marks = append(marks, after)
+		}
+	}
+
+	width := len(fmt.Sprint(marks[len(marks)-1].line))
+	var builder strings.Builder
+	for i, m := range marks {
+		if m.line < 1 || m.line > len(lines) {
+			continue
+		}
+		if i > 0 && m.line > marks[i-1].line+1 {
+			fmt.Fprintf(&builder, "%*s ...\n", width, "")
+		}
+		source := lines[m.line-1]
+		// without a column, the key is the first on its line
+		column := m.column
+		if column == 0 {
+			column = len(source) - len(strings.TrimLeft(source, " \t")) + 1
+		}
+		fmt.Fprintf(&builder, "%*d | %s\n", width, m.line, source)
+		underline := strings.Repeat(m.underline, keyWidth(source, column))
+		fmt.Fprintf(&builder, "%*s | %s%s", width, "", strings.Repeat(" ", column-1), underline)
+		if m.note != "" {
+			builder.WriteString(" " + m.note)
+		}
+		builder.WriteString("\n")
+	}
+
+	return builder.String()
+}
+
+// keyWidth returns the width of the key or item starting at a column of a line
+func keyWidth(source string, column int) int {
+	if column < 1 || column > len(source) {
+		return 1
+	}
+	rest := source[column-1:]
+	if end := strings.IndexAny(rest, ":#"); end != -1 {
+		rest = rest[:end]
+	}
+	if width := len(strings.TrimRight(rest, " \t")); width != 0 {
+		return width
+	}
+
+	return 1
+}
+
+// indentLines indents each line of text
+func indentLines(text, indent string) string {
+	if text == "" {
+		return ""
+	}
+
+	return indent + strings.ReplaceAll(strings.TrimSuffix(text, "\n"), "\n", "\n"+indent) + "\n"
+}
@@ -0,0 +1,100 @@
+/*
+Copyright © 2022 david amick git@davidamick.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCodeFrame(t *testing.T) {
+	lines := strings.Split(`metadata:
+  name: example
+  labels:
+    app: example
+  namespace: default  # deploy here
+spec:
+  - image: example`, "\n")
+
+	type testCase struct {
+		note      string
+		line      int
+		column    int
+		message   string
+		afterLine int
+		afterNote string
+		expected  string
+	}
+
+	testCases := []testCase{
+		{
+			note:    "key",
+			line:    5,
+			column:  3,
+			message: "move up",
+			expected: `5 |   namespace: default  # deploy here
+  |   ^^^^^^^^^ move up
+`,
+		},
+		{
+			note:      "key and the key it comes after, with lines between",
+			line:      5,
+			column:    3,
+			message:   "move up",
+			afterLine: 2,
+			afterNote: "'namespace' comes after this",
+			expected: `2 |   name: example
+  |   ---- 'namespace' comes after this
+  ...
+5 |   namespace: default  # deploy here
+  |   ^^^^^^^^^ move up
+`,
+		},
+		{
+			note:      "key and the key it comes after, on the next line",
+			line:      1,
+			column:    1,
+			message:   "move down",
+			afterLine: 2,
+			afterNote: "'metadata' comes after this",
+			expected: `1 | metadata:
+  | ^^^^^^^^ move down
+2 |   name: example
+  |   ---- 'metadata' comes after this
+`,
+		},
+		{
+			note:     "sequence item, without a note",
+			line:     7,
+			column:   5,
+			expected: "7 |   - image: example\n  |     ^^^^^\n",
+		},
+		{
+			note:     "line outside the file",
+			line:     12,
+			column:   1,
+			message:  "move up",
+			expected: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		got := codeFrame(lines, tc.line, tc.column, tc.message, tc.afterLine, tc.afterNote)
+		if got != tc.expected {
+			t.Errorf("Description: %s: codeFrame(...): \n-expected:\n%v\n+got:\n%v\n", tc.note, tc.expected, got)
+		}
+	}
+}
@@ -18,19 +18,21 @@ package cmd
 import (
 	"bufio"
 	"bytes"
-	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"regexp"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/hexops/gotextdiff"
 	"github.com/hexops/gotextdiff/myers"
 	"github.com/hexops/gotextdiff/span"
 	"github.com/snarlysodboxer/predictable-yaml/pkg/compare"
+	"github.com/snarlysodboxer/predictable-yaml/pkg/documents"
 	"github.com/snarlysodboxer/predictable-yaml/pkg/moves"
 	"github.com/snarlysodboxer/predictable-yaml/pkg/whitespace"
 	"github.com/spf13/cobra"
@@ -47,25 +49,19 @@ var (
 	addPreferreds           bool
 	validate                bool
 	disablePostProcessing   bool
+	renameSuggested         bool
+	copyMissingEntries      bool
+	listChanged             bool
+	showDiff                bool
 )
 
 // fixCmd represents the fix command
 var fixCmd = &cobra.Command{
 	Use:   "fix [flags] <file-or-dir-path> ...",
 	Short: "Lint YAML key order",
-	Long:  `Compare YAML files to config files, reordering keys.`,
-	Args: func(cmd *cobra.Command, args []string) error {
-		if len(args) < 1 {
-			return fmt.Errorf("requires file path argument(s)")
-		}
-		for _, arg := range args {
-			if _, err := os.Stat(arg); errors.Is(err, os.ErrNotExist) {
-				return fmt.Errorf("file '%s' doesn't exist: %v", arg, err)
-			}
-		}
-
-		return nil
-	},
+	Long: `Compare YAML files to config files, reordering keys.
+    A path of '-' reads a file from stdin and writes it fixed to stdout.`,
+	Args: checkPathArgs,
 	Run: func(cmd *cobra.Command, filePaths []string) {
 		// setup
 		workDir, err := os.Getwd()
@@ -84,141 +80,195 @@ var fixCmd = &cobra.Command{
 		// Load project config and apply settings for flags not explicitly set
 		projectCfg, projectCfgDir := loadProjectConfig(workDir, homeDir)
 		configDirFlag := resolveConfigDir(projectCfg, projectCfgDir)
-		if projectCfg != nil {
-			f := projectCfg.Fixer
-			if f.IndentationLevel != nil && !cmd.Flags().Changed("indentation-level") {
-				indentationLevel = *f.IndentationLevel
-			}
-			if f.CompactLists != nil && !cmd.Flags().Changed("compact-lists") {
-				compactLists = *f.CompactLists
-			}
-			if f.AddPreferred != nil && !cmd.Flags().Changed("add-preferred") {
-				addPreferreds = *f.AddPreferred
-			}
-			if f.DisablePostProcessing != nil && !cmd.Flags().Changed("disable-post-processing") {
-				disablePostProcessing = *f.DisablePostProcessing
-			}
-			if f.Prompt != nil && !cmd.Flags().Changed("prompt") {
-				prompt = *f.Prompt
-			}
-			if f.PromptIfLineCountChange != nil && !cmd.Flags().Changed("prompt-if-line-count-change") {
-				promptIfLineCountChange = *f.PromptIfLineCountChange
-			}
-			if f.UnmatchedToBeginning != nil && !cmd.Flags().Changed("unmatched-to-beginning") {
-				unmatchedToBeginning = *f.UnmatchedToBeginning
-			}
-			if f.Validate != nil && !cmd.Flags().Changed("validate") {
-				validate = *f.Validate
+		applyFixerConfig(cmd, projectCfg)
+
+		cfgNodesByPaths := getConfigNodesByPath(configDirFlag, workDir, homeDir, inputNames(allFilePaths), projectCfg, projectCfgDir)
+		var outputs map[string]string
+		if outputDir != "" {
+			outputs, err = outputPaths(filePaths, allFilePaths, outputDir)
+			if err != nil {
+				log.Fatal(err)
 			}
 		}
-
-		cfgNodesByPaths := getConfigNodesByPath(configDirFlag, workDir, homeDir, allFilePaths, projectCfg, projectCfgDir)
+		order := documentOrder(projectCfg)
+		// in check modes, files are listed or diffed on stdout instead of written, and changes are errors
+		//   as in lint. stdin is fixed to stdout. either way, reports go to stderr.
+		checkOnly := listChanged || showDiff
+		fromStdin := slices.Contains(filePaths, stdinPath)
+		reportOut, changeSeverity := io.Writer(os.Stdout), severityInfo
+		if checkOnly {
+			changeSeverity = severityError
+		}
+		if checkOnly || fromStdin {
+			reportOut = os.Stderr
+		}
+		rep, err := commandReporter(reportOut, changeSeverity)
+		if err != nil {
+			log.Fatal(err)
+		}
+		// prompts would be mixed into machine-readable output, check modes don't write, prompts can't
+		//   be answered when stdin is a target file, and output directories are for pipelines
+		if outputFormat != formatText || checkOnly || fromStdin || outputDir != "" {
+			prompt = false
+			promptIfLineCountChange = false
+		}
 
 		success := true
+		documentCount := 0
 
-		for _, filePath := range allFilePaths {
-			fNode := &yaml.Node{}
-			existingFileContents, err := getYAML(fNode, filePath)
+		for _, inputPath := range allFilePaths {
+			filePath := inputName(inputPath)
+			start := time.Now()
+			kinds := []string{}
+			existingFileContents, docs, err := getDocuments(inputPath)
 			if err != nil {
 				log.Fatalf("error parsing yaml for target file: %s: %v", filePath, err)
 			}
-			fileNode := &compare.Node{Node: fNode}
-			compare.WalkConvertYamlNodeToMainNode(fileNode)
-			fileConfigs := compare.GetFileConfigs(fileNode)
-			if fileConfigs.Ignore {
-				continue
-			}
-			if fileConfigs.Kind == "" {
-				log.Printf("WARNING: unable to determine a schema for target file: %s", filePath)
-				continue
-			}
-
 			configNodes := configNodesForPath(cfgNodesByPaths, filePath)
-			configNode, ok := configNodes[fileConfigs.Kind]
-			if !ok {
-				log.Printf("WARNING: no config found for schema '%s' in file: %s", fileConfigs.Kind, filePath)
-				continue
-			}
+			fixedDocs := append([]documents.Document{}, docs...)
+			fixedChanges := []documentChanges{}
+			failed := false
+			for docIndex, doc := range docs {
+				ref := docRef{file: filePath, name: documentName(filePath, docIndex, len(docs)), document: docIndex + 1}
+				docName := ref.name
+				fNode := &yaml.Node{}
+				err := yaml.Unmarshal(doc.Padded(), fNode)
+				if err != nil {
+					log.Fatalf("error parsing yaml for target file: %s: %v", docName, err)
+				}
+				if len(fNode.Content) == 0 && len(docs) > 1 {
+					continue
+				}
+				fileNode := &compare.Node{Node: fNode}
+				compare.WalkConvertYamlNodeToMainNode(fileNode)
+				fileConfigs := compare.GetFileConfigs(fileNode)
+				if fileConfigs.Ignore {
+					continue
+				}
+				documentCount++
+				if fileConfigs.Kind == "" {
+					log.Printf("WARNING: unable to determine a schema for target file: %s", docName)
+					continue
+				}
+				kinds = append(kinds, fileConfigs.Kind)
 
-			// do it
-			addedFields := []compare.AddedField{}
-			sortConfigs := compare.SortConfigs{
-				ConfigNodes:          configNodes,
-				FileConfigs:          fileConfigs,
-				UnmatchedToBeginning: unmatchedToBeginning,
-				AddPreferreds:        addPreferreds,
-				AddedFields:          &addedFields,
-			}
-			// check for null values before sorting
-			nullErrs := compare.WalkFindNullValues(configNode, fileNode, sortConfigs, compare.ValidationErrors{})
-			if len(nullErrs) != 0 {
-				success = false
-				log.Printf("File '%s' has fix errors:\n%v", filePath, compare.GetValidationErrorStrings(nullErrs))
-				continue
-			}
+				configNode, configName, usedFallback, ok := findConfigNode(configNodes, fileConfigs, projectCfg)
+				if !ok {
+					log.Printf("WARNING: no config found for schema '%s' in file: %s", fileConfigs.SchemaName(), docName)
+					continue
+				}
+				if usedFallback {
+					log.Printf("No config found for schema '%s', using fallback config '%s' for file: %s", fileConfigs.SchemaName(), configName, docName)
+				}
 
-			// parse a separate copy for move summary comparison
-			oldFileNode, err := parseNodeFromBytes(existingFileContents)
-			if err != nil {
-				log.Fatalf("error parsing yaml for target file: %s: %v", filePath, err)
-			}
-			commentCount := 0
-			if !disablePostProcessing {
-				commentCount = moves.CountComments(oldFileNode)
-			}
+				// do it
+				changes := []compare.Change{}
+				warnings := compare.FileConfigWarnings(fileNode)
+				sortConfigs := compare.SortConfigs{
+					ConfigNodes:          configNodes,
+					FileConfigs:          fileConfigs,
+					UnmatchedToBeginning: unmatchedToBeginning,
+					AddPreferreds:        addPreferreds,
+					RenameSuggested:      renameSuggested,
+					Changes:              &changes,
+					Warnings:             &warnings,
+				}
+				// check for null values before sorting
+				nullErrs := compare.WalkFindNullValues(configNode, fileNode, sortConfigs, compare.ValidationErrors{})
+				if len(nullErrs) != 0 {
+					failed = true
+					rep.errors(ref, "fix errors", nullErrs)
+					continue
+				}
 
-			errs, changed := compare.WalkAndSort(configNode, fileNode, sortConfigs, compare.ValidationErrors{})
-			if len(errs) != 0 {
-				success = false
-				log.Printf("File '%s' has fix errors:\n%v", filePath, compare.GetValidationErrorStrings(errs))
-				continue
-			}
+				commentCount := 0
+				if !disablePostProcessing {
+					commentCount = moves.CountComments(fileNode)
+				}
 
-			// skip if nothing changed (prevents whitespace-only changes from encoding)
-			if validate && !changed && len(addedFields) == 0 {
-				continue
-			}
+				// copy entries to keys marked 'equals' or 'subset-of', before sorting places them
+				copied := copyMissingEntries && compare.WalkCopyMissingEntries(configNode, fileNode, sortConfigs)
 
-			// setup to check if contents changed, re-add `---` if it was there before
-			var buf bytes.Buffer
-			firstLine := bytes.Split(existingFileContents, []byte(`\n`))[0]
-			hasTripleDash := regexp.MustCompile(`^---\s`).Match(firstLine)
-			if hasTripleDash {
-				_, err := buf.Write([]byte("---\n"))
-				if err != nil {
-					log.Printf("Buffer for '%s' has write error:\n%v", filePath, err)
+				errs, changed := compare.WalkAndSort(configNode, fileNode, sortConfigs, compare.ValidationErrors{})
+				changed = changed || copied
+				if len(errs) != 0 {
+					failed = true
+					rep.errors(ref, "fix errors", errs)
 					continue
 				}
-			}
-			encoder := yaml.NewEncoder(&buf)
-			encoder.SetIndent(indentationLevel)
-			if compactLists && !disablePostProcessing {
-				encoder.CompactSeqIndent()
-			}
-			err = encoder.Encode(fileNode.Node)
-			if err != nil {
-				log.Printf("File '%s' has encode errors:\n%v\n", filePath, err)
-				continue
-			}
-			fileContents := buf.Bytes()
 
-			if !disablePostProcessing {
-				fileContents, err = whitespace.PreserveComments(existingFileContents, fileContents)
+				// convert maps and sequences to their config key's style, after sorting may have copied the parent's
+				if compare.WalkFixStyles(configNode, fileNode, sortConfigs) {
+					changed = true
+				}
+				// remove empty maps and sequences of keys marked 'omit-empty', including ones sorting added
+				if compare.WalkRemoveEmptyValues(configNode, fileNode, sortConfigs) {
+					changed = true
+				}
+				if len(warnings) != 0 {
+					rep.warnings(ref, warnings)
+				}
+
+				// keys marked 'blank-before' or 'no-blank' are spaced after encoding
+				existingDocContents := []byte(doc.Body)
+				spacingErrs, err := whitespace.CheckBlankLines(existingDocContents, configNode, sortConfigs)
 				if err != nil {
-					log.Println(err)
+					rep.errors(ref, "fix errors", compare.ValidationErrors{err})
+					failed = true
 					continue
 				}
-				fileContents, err = whitespace.PreserveEmptyLines(existingFileContents, fileContents)
+
+				// skip if nothing changed (prevents whitespace-only changes from encoding)
+				if validate && !changed && len(changes) == 0 && len(spacingErrs) == 0 {
+					continue
+				}
+
+				docContents, err := encodeDocument(configNode, fileNode, sortConfigs, existingDocContents)
 				if err != nil {
-					log.Println(err)
+					rep.errors(ref, "fix errors", compare.ValidationErrors{err})
+					failed = true
 					continue
 				}
+				fixedDocs[docIndex].Body = string(docContents)
+
+				if len(changes) != 0 {
+					fixedChanges = append(fixedChanges, documentChanges{ref, changes, commentCount})
+				}
+			}
+			rep.file(filePath, kinds, time.Since(start))
+			if failed {
+				success = false
+				if len(docs) > 1 {
+					log.Printf("File '%s' has been skipped because of errors!", filePath)
+				}
+				// the '--output-dir' tree stays complete, with files that have errors copied unchanged
+				if outputDir != "" && inputPath != stdinPath && !checkOnly {
+					output := outputs[inputPath]
+					if err := writeOutput(inputPath, output, existingFileContents); err != nil {
+						log.Printf("File '%s' has write errors:\n%v\n", output, err)
+						continue
+					}
+					log.Printf("File '%s' has been copied unchanged to '%s' because of errors!", filePath, output)
+				}
+				continue
 			}
 
+			// reorder documents to the project's policy
+			var reorderIndexes []int
+			if order != nil {
+				indexes := order.Sort(fixedDocs)
+				if !documents.IsOrdered(indexes) {
+					reorderIndexes = indexes
+					fixedDocs = documents.Reorder(fixedDocs, indexes)
+				}
+			}
+			fileContents := documents.Join(fixedDocs)
+
 			// check if contents changed
 			fileContentsStr := string(fileContents)
 			existingFileContentsStr := string(existingFileContents)
-			if fileContentsStr != existingFileContentsStr {
+			changed := fileContentsStr != existingFileContentsStr
+			if changed {
 				shouldPrompt := false
 				switch {
 				case promptIfLineCountChange:
@@ -228,14 +278,28 @@ var fixCmd = &cobra.Command{
 				case prompt:
 					shouldPrompt = true
 				}
+
+				// text summaries accompany prompts, other formats always report changes
+				if shouldPrompt || outputFormat != formatText {
+					for _, fixed := range fixedChanges {
+						rep.changes(fixed.ref, fixed.changes, fixed.commentCount)
+					}
+					if reorderIndexes != nil {
+						rep.reordered(filePath, reorderIndexes)
+					}
+				}
+				if checkOnly {
+					success = false
+					if listChanged {
+						fmt.Println(filePath)
+					}
+					if showDiff {
+						fmt.Print(generateDiff(filePath, existingFileContentsStr, fileContentsStr))
+					}
+					continue
+				}
 				doFix := true
 				if shouldPrompt {
-					// show structural summary
-					descriptions := moves.ComputeDescriptions(oldFileNode, fileNode)
-					summary := moves.FormatSummary(filePath, descriptions, addedFields, commentCount)
-					if summary != "" {
-						fmt.Printf("\n%s\n", summary)
-					}
 					doFix = promptForConfirmation(filePath, existingFileContentsStr, fileContentsStr)
 				}
 
@@ -243,6 +307,28 @@ var fixCmd = &cobra.Command{
 					log.Printf("File '%s' has been skipped!", filePath)
 					continue
 				}
+			}
+			if checkOnly {
+				continue
+			}
+
+			// stdin is fixed to stdout and '--output-dir' gets every file, changed or not,
+			//   while files are otherwise written only when they change
+			switch {
+			case inputPath == stdinPath:
+				if _, err := os.Stdout.Write(fileContents); err != nil {
+					log.Printf("File '%s' has write errors:\n%v\n", filePath, err)
+				}
+			case outputDir != "":
+				output := outputs[inputPath]
+				if err := writeOutput(inputPath, output, fileContents); err != nil {
+					log.Printf("File '%s' has write errors:\n%v\n", output, err)
+					continue
+				}
+				if changed {
+					log.Printf("File '%s' has been fixed to '%s'!", filePath, output)
+				}
+			case changed:
 				fileStat, err := os.Stat(filePath)
 				if err != nil {
 					log.Println(err)
@@ -257,6 +343,7 @@ var fixCmd = &cobra.Command{
 			}
 		}
 
+		rep.finish(runSummary{Files: len(allFilePaths), Documents: documentCount, Success: success})
 		if !success {
 			log.Fatal("FAIL")
 		}
@@ -267,6 +354,80 @@ var fixCmd = &cobra.Command{
 	},
 }
 
+// applyFixerConfig applies the project config's fixer settings, for flags not set on the command line
+func applyFixerConfig(cmd *cobra.Command, projectCfg *ProjectConfig) {
+	if projectCfg == nil {
+		return
+	}
+	f := projectCfg.Fixer
+	if f.IndentationLevel != nil && !cmd.Flags().Changed("indentation-level") {
+		indentationLevel = *f.IndentationLevel
+	}
+	if f.CompactLists != nil && !cmd.Flags().Changed("compact-lists") {
+		compactLists = *f.CompactLists
+	}
+	if f.AddPreferred != nil && !cmd.Flags().Changed("add-preferred") {
+		addPreferreds = *f.AddPreferred
+	}
+	if f.DisablePostProcessing != nil && !cmd.Flags().Changed("disable-post-processing") {
+		disablePostProcessing = *f.DisablePostProcessing
+	}
+	if f.Prompt != nil && !cmd.Flags().Changed("prompt") {
+		prompt = *f.Prompt
+	}
+	if f.PromptIfLineCountChange != nil && !cmd.Flags().Changed("prompt-if-line-count-change") {
+		promptIfLineCountChange = *f.PromptIfLineCountChange
+	}
+	if f.UnmatchedToBeginning != nil && !cmd.Flags().Changed("unmatched-to-beginning") {
+		unmatchedToBeginning = *f.UnmatchedToBeginning
+	}
+	if f.Validate != nil && !cmd.Flags().Changed("validate") {
+		validate = *f.Validate
+	}
+	if f.RenameSuggested != nil && !cmd.Flags().Changed("rename-suggested") {
+		renameSuggested = *f.RenameSuggested
+	}
+	if f.CopyMissingEntries != nil && !cmd.Flags().Changed("copy-missing-entries") {
+		copyMissingEntries = *f.CopyMissingEntries
+	}
+}
+
+// encodeDocument encodes a sorted document, then restores the comments and empty lines of its
+// existing contents, and spaces it for keys marked 'blank-before' or 'no-blank'
+func encodeDocument(configNode, fileNode *compare.Node, sortConfigs compare.SortConfigs, existingDocContents []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(indentationLevel)
+	if compactLists && !disablePostProcessing {
+		encoder.CompactSeqIndent()
+	}
+	if err := encoder.Encode(fileNode.Node); err != nil {
+		return nil, fmt.Errorf("encode error: %w", err)
+	}
+	docContents := buf.Bytes()
+
+	var err error
+	if !disablePostProcessing {
+		docContents, err = whitespace.PreserveComments(existingDocContents, docContents)
+		if err != nil {
+			return nil, err
+		}
+		docContents, err = whitespace.PreserveEmptyLines(existingDocContents, docContents)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return whitespace.FixBlankLines(docContents, configNode, sortConfigs)
+}
+
+// documentChanges is the change log of a fixed document, reported once it's known whether the file changed
+type documentChanges struct {
+	ref          docRef
+	changes      []compare.Change
+	commentCount int
+}
+
 func init() {
 	rootCmd.AddCommand(fixCmd)
 	fixCmd.PersistentFlags().BoolVar(&prompt, "prompt", true, "show diff and prompt before making changes")
@@ -276,7 +437,17 @@ func init() {
 	fixCmd.PersistentFlags().BoolVar(&unmatchedToBeginning, "unmatched-to-beginning", false, "move keys not in the config to the beginning of their map instead of the end")
 	fixCmd.PersistentFlags().BoolVar(&addPreferreds, "add-preferred", false, "add lines marked as preferred when adding missing keys")
 	fixCmd.PersistentFlags().BoolVar(&validate, "validate", true, "use validation to determine if sorting should happen. (only sort if validation fails. this can prevent whitespace changes when unnecessary.)")
-	fixCmd.PersistentFlags().BoolVarP(&disablePostProcessing, "disable-post-processing", "d", false, "disable all post-processing (empty line preservation, comment preservation, compact lists)")
+	fixCmd.PersistentFlags().BoolVar(&renameSuggested, "rename-suggested", false, "rename unknown keys to the config key they most likely misspell, when there is exactly one candidate")
+	fixCmd.PersistentFlags().BoolVar(&copyMissingEntries, "copy-missing-entries", false, "copy missing map entries between keys marked 'equals' or 'subset-of' and their paths")
+	fixCmd.PersistentFlags().StringVar(&outputFormat, "format", formatText, "format of reported errors, warnings and changes: "+strings.Join(outputFormats, ", ")+". formats other than text don't prompt")
+	fixCmd.PersistentFlags().StringVar(&junitReportPath, "junit-report", "", "also write a JUnit XML report to this path")
+	fixCmd.PersistentFlags().StringVar(&junitGroupBy, "junit-group-by", junitGroupByDirectory, "group JUnit test suites by file 'directory' or document 'kind'")
+	fixCmd.PersistentFlags().BoolVar(&showExcerpts, "excerpts", false, "show the source lines of errors, warnings and changes in the text format")
+	fixCmd.PersistentFlags().BoolVar(&disablePostProcessing, "disable-post-processing", false, "disable all post-processing (empty line preservation, comment preservation, compact lists)")
+	fixCmd.PersistentFlags().BoolVarP(&listChanged, "list", "l", false, "list files whose contents would change instead of writing them, failing if there are any")
+	fixCmd.PersistentFlags().BoolVarP(&showDiff, "diff", "d", false, "print diffs of files whose contents would change instead of writing them, failing if there are any")
+	fixCmd.PersistentFlags().StringVar(&stdinFilename, "stdin-filename", "", "name of the file read from stdin ('-'), to resolve configs and report by")
+	fixCmd.PersistentFlags().StringVar(&outputDir, "output-dir", "", "write fixed files to this directory, mirroring the trees of the path arguments, instead of in place. files with errors are copied unchanged")
 }
 
 func generateDiff(filePath, oldContent, newContent string) string {
@@ -418,6 +589,19 @@ func showExternalDiff(filePath, oldContent, newContent string) error {
 	return nil
 }
 
+// formatReorderSummary describes the documents of a file being reordered, in the style of moves.FormatSummary
+func formatReorderSummary(filePath string, indexes []int) string {
+	var stringBuilder strings.Builder
+	fmt.Fprintf(&stringBuilder, "File: %s\n\n  Changes:\n", filePath)
+	for position, index := range indexes {
+		if position != index {
+			fmt.Fprintf(&stringBuilder, "    document %d moved to %d\n", index+1, position+1)
+		}
+	}
+
+	return stringBuilder.String()
+}
+
 func countLines(str string, separator rune) int {
 	count := 0
 	for _, character := range str {
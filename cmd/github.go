@@ -0,0 +1,74 @@
+/*
+Copyright © 2022 david amick git@davidamick.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// githubReporter writes the findings as GitHub Actions workflow commands, which annotate the lines of pull requests
+type githubReporter struct {
+	findingCollector
+	out io.Writer
+}
+
+func (r *githubReporter) finish(summary runSummary) {
+	for _, f := range r.findings {
+		fmt.Fprintln(r.out, githubCommand(f))
+	}
+}
+
+// githubCommand returns the workflow command annotating a finding, e.g. '::error file=app.yaml,line=7,col=3::message'
+func githubCommand(f finding) string {
+	properties := []string{"file=" + githubEscapeProperty(f.File)}
+	if f.Line != 0 {
+		properties = append(properties, fmt.Sprintf("line=%d", f.Line))
+		if f.Column != 0 {
+			properties = append(properties, fmt.Sprintf("col=%d", f.Column))
+		}
+	}
+	title := "predictable-yaml"
+	if f.Rule != "" {
+		title = fmt.Sprintf("predictable-yaml (%s)", f.Rule)
+	}
+	properties = append(properties, "title="+githubEscapeProperty(title))
+
+	return fmt.Sprintf("::%s %s::%s", githubCommandName(f.Severity), strings.Join(properties, ","), githubEscapeData(f.Message))
+}
+
+// githubCommandName returns the workflow command of a finding's severity
+func githubCommandName(severity string) string {
+	switch severity {
+	case severityWarning:
+		return "warning"
+	case severityInfo:
+		return "notice"
+	}
+
+	return "error"
+}
+
+// githubEscapeData escapes the message of a workflow command, which ends at a line break
+func githubEscapeData(text string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(text)
+}
+
+// githubEscapeProperty escapes a property of a workflow command, which also ends at a ',' or ':'
+func githubEscapeProperty(text string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(text)
+}
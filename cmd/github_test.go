@@ -0,0 +1,60 @@
+/*
+Copyright © 2022 david amick git@davidamick.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"testing"
+
+	"github.com/snarlysodboxer/predictable-yaml/pkg/compare"
+)
+
+func TestGitHubCommand(t *testing.T) {
+	type testCase struct {
+		note     string
+		finding  finding
+		expected string
+	}
+
+	testCases := []testCase{
+		{
+			note:     "error with a position",
+			finding:  finding{File: "apps/deployment.yaml", Line: 7, Column: 3, Rule: compare.RuleFirst, Severity: severityError, Message: "'.metadata.name' (line 7): move to top"},
+			expected: "::error file=apps/deployment.yaml,line=7,col=3,title=predictable-yaml (first)::'.metadata.name' (line 7): move to top",
+		},
+		{
+			note:     "warning",
+			finding:  finding{File: "service.yaml", Line: 3, Column: 3, Rule: compare.RulePreferred, Severity: severityWarning, Message: "warning: missing preferred key 'labels'"},
+			expected: "::warning file=service.yaml,line=3,col=3,title=predictable-yaml (preferred)::warning: missing preferred key 'labels'",
+		},
+		{
+			note:     "change made by fix, without a position",
+			finding:  finding{File: "bundle.yaml", Document: 2, Rule: compare.RuleDocumentOrder, Severity: severityInfo, Message: "document 2: move to 1"},
+			expected: "::notice file=bundle.yaml,title=predictable-yaml (document-order)::document 2: move to 1",
+		},
+		{
+			note:     "escaped file and message",
+			finding:  finding{File: "odd,name:100%.yaml", Line: 1, Severity: severityError, Message: "two\nlines at 100%"},
+			expected: "::error file=odd%2Cname%3A100%25.yaml,line=1,title=predictable-yaml::two%0Alines at 100%25",
+		},
+	}
+
+	for _, tc := range testCases {
+		got := githubCommand(tc.finding)
+		if got != tc.expected {
+			t.Errorf("Description: %s: githubCommand(...): \n-expected:\n%v\n+got:\n%v\n", tc.note, tc.expected, got)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+/*
+Copyright © 2022 david amick git@davidamick.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+)
+
+// gitlabIssue is an issue of a GitLab Code Quality report
+type gitlabIssue struct {
+	Description string         `json:"description"`
+	CheckName   string         `json:"check_name"`
+	Fingerprint string         `json:"fingerprint"`
+	Severity    string         `json:"severity"`
+	Location    gitlabLocation `json:"location"`
+}
+
+type gitlabLocation struct {
+	Path  string      `json:"path"`
+	Lines gitlabLines `json:"lines"`
+}
+
+type gitlabLines struct {
+	Begin int `json:"begin"`
+}
+
+// gitlabReporter writes the findings as a GitLab Code Quality report, for merge request widgets
+type gitlabReporter struct {
+	findingCollector
+	out io.Writer
+}
+
+func (r *gitlabReporter) finish(summary runSummary) {
+	issues := make([]gitlabIssue, 0, len(r.findings))
+	occurrences := map[string]int{}
+	for _, f := range r.findings {
+		checkName := f.Rule
+		if checkName == "" {
+			checkName = "predictable-yaml"
+		}
+		// reports require a line, so findings about whole documents are on the first
+		line := f.Line
+		if line == 0 {
+			line = 1
+		}
+		fingerprint := gitlabFingerprint(f)
+		occurrences[fingerprint]++
+		if count := occurrences[fingerprint]; count > 1 {
+			fingerprint = gitlabHash(fmt.Sprintf("%s\x00%d", fingerprint, count))
+		}
+		issues = append(issues, gitlabIssue{
+			Description: f.Message,
+			CheckName:   checkName,
+			Fingerprint: fingerprint,
+			Severity:    gitlabSeverity(f.Severity),
+			Location:    gitlabLocation{Path: filepath.ToSlash(f.File), Lines: gitlabLines{Begin: line}},
+		})
+	}
+
+	encoder := json.NewEncoder(r.out)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(issues)
+}
+
+// lineNumbers matches the line numbers in messages, e.g. "(line 7)"
+var lineNumbers = regexp.MustCompile(` ?\(line \d+\)`)
+
+// gitlabFingerprint identifies a finding across runs. Line numbers are left out, so that a finding
+// keeps its fingerprint when lines above it are added or removed.
+func gitlabFingerprint(f finding) string {
+	message := lineNumbers.ReplaceAllString(f.Message, "")
+
+	return gitlabHash(fmt.Sprintf("%s\x00%d\x00%s\x00%s\x00%s", filepath.ToSlash(f.File), f.Document, f.Rule, f.Path, message))
+}
+
+func gitlabHash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// gitlabSeverity returns the Code Quality severity of a finding's severity
+func gitlabSeverity(severity string) string {
+	switch severity {
+	case severityWarning:
+		return "minor"
+	case severityInfo:
+		return "info"
+	}
+
+	return "major"
+}
@@ -0,0 +1,69 @@
+/*
+Copyright © 2022 david amick git@davidamick.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/snarlysodboxer/predictable-yaml/pkg/compare"
+)
+
+func TestGitLabReporter(t *testing.T) {
+	var buf bytes.Buffer
+	rep, err := newReporter(formatGitLab, &buf, severityError)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref := docRef{file: "apps/service.yaml", name: "apps/service.yaml", document: 1}
+	duplicate := &compare.Diagnostic{Rule: compare.RuleUnknownKey, Path: ".spec.prots", Line: 9, Column: 3, Message: "validation error: unknown key at '.spec.prots' (line 9)"}
+	rep.errors(ref, "validation errors", compare.ValidationErrors{duplicate, duplicate})
+	rep.warnings(ref, compare.ValidationErrors{&compare.Diagnostic{Rule: compare.RulePreferred, Path: ".metadata", Line: 3, Column: 3, Message: "warning: missing preferred key 'labels' at '.metadata' (line 3)"}})
+	rep.errors(docRef{file: "bundle.yaml", name: "bundle.yaml"}, "validation errors", compare.ValidationErrors{&compare.Diagnostic{Rule: compare.RuleDocumentOrder, Message: "documents out of order"}})
+	rep.finish(runSummary{})
+
+	var issues []gitlabIssue
+	if err := json.Unmarshal(buf.Bytes(), &issues); err != nil {
+		t.Fatalf("failed parsing report: %v\n%s", err, buf.String())
+	}
+	expected := []gitlabIssue{
+		{Description: duplicate.Message, CheckName: "unknown-key", Severity: "major", Location: gitlabLocation{Path: "apps/service.yaml", Lines: gitlabLines{Begin: 9}}},
+		{Description: duplicate.Message, CheckName: "unknown-key", Severity: "major", Location: gitlabLocation{Path: "apps/service.yaml", Lines: gitlabLines{Begin: 9}}},
+		{Description: "warning: missing preferred key 'labels' at '.metadata' (line 3)", CheckName: "preferred", Severity: "minor", Location: gitlabLocation{Path: "apps/service.yaml", Lines: gitlabLines{Begin: 3}}},
+		{Description: "documents out of order", CheckName: "document-order", Severity: "major", Location: gitlabLocation{Path: "bundle.yaml", Lines: gitlabLines{Begin: 1}}},
+	}
+	if len(issues) != len(expected) {
+		t.Fatalf("gitlabReporter: expected %d issues, got %d\n%s", len(expected), len(issues), buf.String())
+	}
+	fingerprints := map[string]bool{}
+	for i, issue := range issues {
+		if fingerprints[issue.Fingerprint] {
+			t.Errorf("issue %d: duplicate fingerprint %s", i, issue.Fingerprint)
+		}
+		fingerprints[issue.Fingerprint] = true
+		issue.Fingerprint = ""
+		if issue != expected[i] {
+			t.Errorf("issue %d: \n-expected:\n%+v\n+got:\n%+v\n", i, expected[i], issue)
+		}
+	}
+
+	// fingerprints don't change when the line of a finding does
+	moved := finding{File: "apps/service.yaml", Document: 1, Path: ".spec.prots", Line: 12, Rule: compare.RuleUnknownKey, Message: "validation error: unknown key at '.spec.prots' (line 12)"}
+	if !fingerprints[gitlabFingerprint(moved)] {
+		t.Errorf("gitlabFingerprint(...): expected the fingerprint of the finding on line 9")
+	}
+}
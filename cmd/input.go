@@ -0,0 +1,143 @@
+/*
+Copyright © 2022 david amick git@davidamick.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// stdinPath is the path argument that reads a target file from stdin
+const stdinPath = "-"
+
+// flags
+var (
+	stdinFilename string
+	outputDir     string
+)
+
+// stdinContents is what was read from stdin, which can't be read again
+var stdinContents []byte
+
+// checkPathArgs checks the path arguments of lint and fix, which must exist, except for stdin
+func checkPathArgs(cmd *cobra.Command, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("requires file path argument(s)")
+	}
+	stdinCount := 0
+	for _, arg := range args {
+		if arg == stdinPath {
+			stdinCount++
+			continue
+		}
+		if _, err := os.Stat(arg); errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("file '%s' doesn't exist: %v", arg, err)
+		}
+	}
+	if stdinCount > 1 {
+		return fmt.Errorf("stdin ('%s') can only be read once", stdinPath)
+	}
+
+	return nil
+}
+
+// inputName returns the name a target file is reported by and resolves configs by,
+// which for stdin is '--stdin-filename'
+func inputName(filePath string) string {
+	if filePath != stdinPath {
+		return filePath
+	}
+	if stdinFilename != "" {
+		return stdinFilename
+	}
+
+	return "<stdin>"
+}
+
+// inputNames returns the names of target files, see inputName
+func inputNames(filePaths []string) []string {
+	names := make([]string, 0, len(filePaths))
+	for _, filePath := range filePaths {
+		names = append(names, inputName(filePath))
+	}
+
+	return names
+}
+
+// readInput reads a target file, or stdin for stdinPath
+func readInput(filePath string) ([]byte, error) {
+	if filePath != stdinPath {
+		return os.ReadFile(filePath)
+	}
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return nil, err
+	}
+	stdinContents = data
+
+	return data, nil
+}
+
+// outputPaths returns where '--output-dir' puts each target file, mirroring the tree under the path
+// argument it was found by: files found in a directory keep their path relative to it, while files
+// given directly keep their name.
+func outputPaths(args, filePaths []string, dir string) (map[string]string, error) {
+	outputs := map[string]string{}
+	sources := map[string]string{}
+	for _, filePath := range filePaths {
+		if filePath == stdinPath {
+			continue
+		}
+		relPath := filepath.Base(filePath)
+		// the longest directory argument the file is under
+		root := ""
+		for _, arg := range args {
+			if arg == filePath || arg == stdinPath || len(arg) <= len(root) {
+				continue
+			}
+			if rel, err := filepath.Rel(arg, filePath); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				root, relPath = arg, rel
+			}
+		}
+		output := filepath.Join(dir, relPath)
+		if source, ok := sources[output]; ok {
+			return nil, fmt.Errorf("files '%s' and '%s' would both be written to '%s'", source, filePath, output)
+		}
+		sources[output] = filePath
+		outputs[filePath] = output
+	}
+
+	return outputs, nil
+}
+
+// writeOutput writes a fixed target file to its path in '--output-dir', with the mode of the target file
+func writeOutput(filePath, output string, contents []byte) error {
+	fileStat, err := os.Stat(filePath)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(output, contents, fileStat.Mode())
+}
@@ -0,0 +1,81 @@
+/*
+Copyright © 2022 david amick git@davidamick.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOutputPaths(t *testing.T) {
+	type testCase struct {
+		note      string
+		args      []string
+		filePaths []string
+		expected  map[string]string
+		expectErr bool
+	}
+
+	testCases := []testCase{
+		{
+			note:      "files in a directory keep their path relative to it",
+			args:      []string{"manifests"},
+			filePaths: []string{"manifests/a.yaml", "manifests/apps/b.yaml"},
+			expected:  map[string]string{"manifests/a.yaml": "out/a.yaml", "manifests/apps/b.yaml": "out/apps/b.yaml"},
+		},
+		{
+			note:      "files given directly keep their name",
+			args:      []string{"manifests/apps/b.yaml", "c.yaml"},
+			filePaths: []string{"manifests/apps/b.yaml", "c.yaml"},
+			expected:  map[string]string{"manifests/apps/b.yaml": "out/b.yaml", "c.yaml": "out/c.yaml"},
+		},
+		{
+			note:      "the longest directory a file is in is its root",
+			args:      []string{"manifests", "manifests/apps"},
+			filePaths: []string{"manifests/a.yaml", "manifests/apps/b.yaml"},
+			expected:  map[string]string{"manifests/a.yaml": "out/a.yaml", "manifests/apps/b.yaml": "out/b.yaml"},
+		},
+		{
+			note:      "names starting with '..' are in their directory",
+			args:      []string{".", "manifests"},
+			filePaths: []string{"..data/a.yaml", "manifests/..b.yaml"},
+			expected:  map[string]string{"..data/a.yaml": "out/..data/a.yaml", "manifests/..b.yaml": "out/..b.yaml"},
+		},
+		{
+			note:      "stdin is skipped",
+			args:      []string{"-", "c.yaml"},
+			filePaths: []string{"-", "c.yaml"},
+			expected:  map[string]string{"c.yaml": "out/c.yaml"},
+		},
+		{
+			note:      "files written to the same path",
+			args:      []string{"manifests/a.yaml", "other/a.yaml"},
+			filePaths: []string{"manifests/a.yaml", "other/a.yaml"},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		got, err := outputPaths(tc.args, tc.filePaths, "out")
+		if tc.expectErr != (err != nil) {
+			t.Errorf("Description: %s: expected error to be %v, got: %v", tc.note, tc.expectErr, err)
+			continue
+		}
+		if !tc.expectErr && !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("Description: %s: outputPaths():\n-expected:\n%v\n+got:\n%v\n", tc.note, tc.expected, got)
+		}
+	}
+}
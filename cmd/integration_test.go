@@ -16,11 +16,19 @@ limitations under the License.
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
+	"strconv"
 	"strings"
 	"testing"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/snarlysodboxer/predictable-yaml/pkg/compare"
 )
 
 // findRepoRoot walks up from the current directory to find the repo root
@@ -47,6 +55,8 @@ func findRepoRoot(t *testing.T) string {
 // and returns its path.
 func buildBinary(t *testing.T) string {
 	t.Helper()
+	// when tests run in GitHub Actions, the binary shouldn't default to the github format
+	t.Setenv("GITHUB_ACTIONS", "")
 	binary := filepath.Join(t.TempDir(), "predictable-yaml")
 	repoRoot := findRepoRoot(t)
 	cmd := exec.Command("go", "build", "-o", binary, ".")
@@ -58,13 +68,57 @@ func buildBinary(t *testing.T) string {
 	return binary
 }
 
+// writeOrderConfigFile writes a project config with a document order policy,
+// returning its path.
+func writeOrderConfigFile(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), ".predictable-yaml.yaml")
+	content := "documents:\n  kind-order: [Namespace, \"*\"]\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// writeOmitEmptyConfigDir writes a copy of the example configs whose Deployment and Pod annotations,
+// Pod securityContext and container envFrom keys are marked 'omit-empty', returning its path
+func writeOmitEmptyConfigDir(t *testing.T, repoRoot string) string {
+	t.Helper()
+	dir := t.TempDir()
+	entries, err := os.ReadDir(filepath.Join(repoRoot, "example-configs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	replacer := strings.NewReplacer(
+		"annotations: {}  # open\n", "annotations: {}  # open, omit-empty\n",
+		"  securityContext: {}  # open\n  initContainers", "  securityContext: {}  # open, omit-empty\n  initContainers",
+		"envFrom: []\n", "envFrom: []  # omit-empty\n",
+	)
+	for _, entry := range entries {
+		content, err := os.ReadFile(filepath.Join(repoRoot, "example-configs", entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry.Name() == "Deployment.yaml" || entry.Name() == "Pod.yaml" {
+			content = []byte(replacer.Replace(string(content)))
+		}
+		if err := os.WriteFile(filepath.Join(dir, entry.Name()), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
 func TestIntegrationLint(t *testing.T) {
 	binary := buildBinary(t)
 	repoRoot := findRepoRoot(t)
 	configDir := filepath.Join(repoRoot, "example-configs")
+	omitEmptyConfigDir := writeOmitEmptyConfigDir(t, repoRoot)
+	orderConfigFile := writeOrderConfigFile(t)
 
 	type testCase struct {
 		note           string
+		flags          []string
 		files          []string
 		expectFail     bool
 		expectInOutput string
@@ -107,11 +161,120 @@ func TestIntegrationLint(t *testing.T) {
 			files:      []string{filepath.Join(repoRoot, "test-data")},
 			expectFail: true, // directory contains invalid files too
 		},
+		{
+			note:       "strict valid service passes",
+			flags:      []string{"--strict"},
+			files:      []string{filepath.Join(repoRoot, "test-data", "service.valid.yaml")},
+			expectFail: false,
+		},
+		{
+			note:           "strict reports unknown keys",
+			flags:          []string{"--strict"},
+			files:          []string{filepath.Join(repoRoot, "test-data", "service.unknown-keys.yaml")},
+			expectFail:     true,
+			expectInOutput: "unknown key at '.spec.ports[0].protocl' (line 17)",
+		},
+		{
+			note:           "unknown keys pass without strict",
+			files:          []string{filepath.Join(repoRoot, "test-data", "service.unknown-keys.yaml")},
+			expectFail:     false,
+			expectInOutput: "did you mean 'protocol'?",
+		},
+		{
+			note:           "warnings are counted",
+			files:          []string{filepath.Join(repoRoot, "test-data", "deployment.valid.yaml")},
+			expectFail:     false,
+			expectInOutput: "SUCCESS (4 warnings)",
+		},
+		{
+			note:           "missing preferred keys are warnings",
+			files:          []string{filepath.Join(repoRoot, "test-data", "deployment.valid.yaml")},
+			expectFail:     false,
+			expectInOutput: "warning: missing preferred key 'command' at '.spec.template.spec.containers[0]' (line 40)",
+		},
+		{
+			note:           "fail on warnings",
+			flags:          []string{"--fail-on-warnings"},
+			files:          []string{filepath.Join(repoRoot, "test-data", "service.unknown-keys.yaml")},
+			expectFail:     true,
+			expectInOutput: "FAIL (1 warning)",
+		},
+		{
+			note:           "fallback config for kinds without a config",
+			files:          []string{filepath.Join(repoRoot, "test-data", "certificate.valid.yaml")},
+			expectFail:     false,
+			expectInOutput: "using fallback config '_default'",
+		},
+		{
+			note:           "fallback config enforces top level order",
+			files:          []string{filepath.Join(repoRoot, "test-data", "certificate.invalid.yaml")},
+			expectFail:     true,
+			expectInOutput: "apiVersion: cert-manager.io/v1  # move to top",
+		},
+		{
+			note:           "selector labels must be a subset of template labels",
+			files:          []string{filepath.Join(repoRoot, "test-data", "deployment.mismatched-labels.yaml")},
+			expectFail:     true,
+			expectInOutput: "'.spec.selector.matchLabels' (line 14) must be a subset of '.spec.template.metadata.labels' (line 21): 'tier' is missing at '.spec.template.metadata.labels'",
+		},
+		{
+			note:           "probe with two handlers fails",
+			files:          []string{filepath.Join(repoRoot, "test-data", "deployment.probe-handlers.yaml")},
+			expectFail:     true,
+			expectInOutput: "only one of 'httpGet', 'tcpSocket', 'exec', 'grpc' is allowed at '.spec.template.spec.containers[0].livenessProbe', found 'httpGet' (line 26), 'exec' (line 29)",
+		},
+		{
+			note:           "empty values marked omit-empty fail",
+			flags:          []string{"--config-dir", omitEmptyConfigDir},
+			files:          []string{filepath.Join(repoRoot, "test-data", "deployment.empty-values.yaml")},
+			expectFail:     true,
+			expectInOutput: "empty value at '.spec.template.spec.containers[0].envFrom' (line 30) — remove it",
+		},
+		{
+			note:           "errors are prefixed with their position",
+			flags:          []string{"--config-dir", omitEmptyConfigDir},
+			files:          []string{filepath.Join(repoRoot, "test-data", "deployment.empty-values.yaml")},
+			expectFail:     true,
+			expectInOutput: "deployment.empty-values.yaml:30:9: validation error: empty value at '.spec.template.spec.containers[0].envFrom' (line 30) — remove it",
+		},
+		{
+			note:           "excerpts show moved keys and the keys they come after",
+			flags:          []string{"--excerpts"},
+			files:          []string{filepath.Join(repoRoot, "test-data", "deployment.invalid.yaml")},
+			expectFail:     true,
+			expectInOutput: "deployment.invalid.yaml:8:3: '.metadata.namespace' (line 8): move up\n  7 |   name: cool-app  # the app name\n    |   ---- 'namespace' comes after this\n  8 |   namespace: default\n    |   ^^^^^^^^^ move up\n",
+		},
+		{
+			note:           "each document of a bundle is linted",
+			files:          []string{filepath.Join(repoRoot, "test-data", "bundle.unordered.yaml")},
+			expectFail:     true,
+			expectInOutput: "bundle.unordered.yaml (document 2)\n\n  Changes:\n    apiVersion: v1  # move to top",
+		},
+		{
+			note:           "documents out of kind-order fail",
+			flags:          []string{"--config-file", orderConfigFile},
+			files:          []string{filepath.Join(repoRoot, "test-data", "bundle.unordered-fixed.yaml")},
+			expectFail:     true,
+			expectInOutput: "documents are out of order, expected: Namespace 'example' (line 38), Service 'api' (line 20), Service 'web' (line 3)",
+		},
+		{
+			note:       "documents in kind-order pass",
+			flags:      []string{"--config-file", orderConfigFile},
+			files:      []string{filepath.Join(repoRoot, "test-data", "bundle.ordered.yaml")},
+			expectFail: false,
+		},
+		{
+			note:       "fail on warnings passes without warnings",
+			flags:      []string{"--fail-on-warnings"},
+			files:      []string{filepath.Join(repoRoot, "test-data", "service.valid.yaml")},
+			expectFail: false,
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.note, func(t *testing.T) {
 			args := []string{"lint", "--config-dir", configDir}
+			args = append(args, tc.flags...)
 			args = append(args, tc.files...)
 			cmd := exec.Command(binary, args...)
 			out, err := cmd.CombinedOutput()
@@ -134,13 +297,138 @@ func TestIntegrationLint(t *testing.T) {
 	}
 }
 
+func TestIntegrationLintAPIVersion(t *testing.T) {
+	binary := buildBinary(t)
+	configDir := t.TempDir()
+	filesDir := t.TempDir()
+
+	configs := map[string]string{
+		"gateway-api-gateway.yaml": `# predictable-yaml: kind=Gateway, apiVersion=gateway.networking.k8s.io/*
+apiVersion: gateway.networking.k8s.io/v1  # first, required
+kind: Gateway  # required
+metadata:  # required
+  name: TODO  # first, required
+spec:  # required
+  gatewayClassName: TODO  # first, required
+  listeners: []  # ditto=gateway.networking.k8s.io/*/ListenerSet.spec.listeners
+`,
+		"gateway-api-listenerset.yaml": `# predictable-yaml: kind=ListenerSet, apiVersion=gateway.networking.k8s.io/*
+apiVersion: gateway.networking.k8s.io/v1  # first, required
+kind: ListenerSet  # required
+spec:
+  listeners:
+  - name: TODO  # first, required
+    port: 80
+    protocol: HTTP
+`,
+		"istio-gateway.yaml": `# predictable-yaml: kind=Gateway, apiVersion=networking.istio.io/*
+apiVersion: networking.istio.io/v1  # first, required
+kind: Gateway  # required
+metadata:  # required
+  name: TODO  # first, required
+spec:  # required
+  selector: {}  # first
+  servers: []
+`,
+	}
+	for name, content := range configs {
+		if err := os.WriteFile(filepath.Join(configDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files := map[string]string{
+		"istio.yaml": `apiVersion: networking.istio.io/v1beta1
+kind: Gateway
+metadata:
+  name: example
+spec:
+  selector:
+    istio: ingressgateway
+  servers: []
+`,
+		"gateway-api.valid.yaml": `apiVersion: gateway.networking.k8s.io/v1
+kind: Gateway
+metadata:
+  name: example
+spec:
+  gatewayClassName: example
+  listeners:
+  - name: http
+    port: 80
+    protocol: HTTP
+`,
+		"gateway-api.invalid.yaml": `apiVersion: gateway.networking.k8s.io/v1beta1
+kind: Gateway
+metadata:
+  name: example
+spec:
+  gatewayClassName: example
+  listeners:
+  - port: 80
+    name: http
+    protocol: HTTP
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(filesDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	type testCase struct {
+		note           string
+		file           string
+		expectFail     bool
+		expectInOutput string
+	}
+
+	testCases := []testCase{
+		{
+			note: "istio gateway uses the istio config",
+			file: "istio.yaml",
+		},
+		{
+			note: "gateway api gateway uses the gateway api config",
+			file: "gateway-api.valid.yaml",
+		},
+		{
+			note:           "qualified ditto is followed",
+			file:           "gateway-api.invalid.yaml",
+			expectFail:     true,
+			expectInOutput: "listeners[0]:",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.note, func(t *testing.T) {
+			cmd := exec.Command(binary, "lint", "--config-dir", configDir, filepath.Join(filesDir, tc.file))
+			out, err := cmd.CombinedOutput()
+			output := string(out)
+
+			if tc.expectFail && err == nil {
+				t.Errorf("expected failure but got success\noutput: %s", output)
+			}
+			if !tc.expectFail && err != nil {
+				t.Errorf("expected success but got failure\noutput: %s", output)
+			}
+			if tc.expectInOutput != "" && !strings.Contains(output, tc.expectInOutput) {
+				t.Errorf("expected output to contain %q\noutput: %s", tc.expectInOutput, output)
+			}
+		})
+	}
+}
+
 func TestIntegrationFix(t *testing.T) {
 	binary := buildBinary(t)
 	repoRoot := findRepoRoot(t)
 	configDir := filepath.Join(repoRoot, "example-configs")
+	omitEmptyConfigDir := writeOmitEmptyConfigDir(t, repoRoot)
+	orderConfigFile := writeOrderConfigFile(t)
 
 	type testCase struct {
 		note         string
+		flags        []string
 		sourceFile   string
 		expectedFile string // if set, compare fixed output against this file
 	}
@@ -164,6 +452,55 @@ func TestIntegrationFix(t *testing.T) {
 			sourceFile:   filepath.Join(repoRoot, "test-data", "service.invalid.yaml"),
 			expectedFile: filepath.Join(repoRoot, "test-data", "service.invalid-fixed.yaml"),
 		},
+		{
+			note:       "misspelled keys unchanged without rename-suggested",
+			sourceFile: filepath.Join(repoRoot, "test-data", "service.unknown-keys.yaml"),
+		},
+		{
+			note:         "misspelled keys renamed with rename-suggested",
+			flags:        []string{"--rename-suggested"},
+			sourceFile:   filepath.Join(repoRoot, "test-data", "service.unknown-keys.yaml"),
+			expectedFile: filepath.Join(repoRoot, "test-data", "service.unknown-keys-renamed.yaml"),
+		},
+		{
+			note:         "labels in open maps not renamed with rename-suggested",
+			flags:        []string{"--rename-suggested"},
+			sourceFile:   filepath.Join(repoRoot, "test-data", "service.open-labels.yaml"),
+			expectedFile: filepath.Join(repoRoot, "test-data", "service.open-labels-fixed.yaml"),
+		},
+		{
+			note:       "mismatched labels unchanged without copy-missing-entries",
+			sourceFile: filepath.Join(repoRoot, "test-data", "deployment.mismatched-labels.yaml"),
+		},
+		{
+			note:         "mismatched labels copied with copy-missing-entries",
+			flags:        []string{"--copy-missing-entries"},
+			sourceFile:   filepath.Join(repoRoot, "test-data", "deployment.mismatched-labels.yaml"),
+			expectedFile: filepath.Join(repoRoot, "test-data", "deployment.mismatched-labels-copied.yaml"),
+		},
+		{
+			note:         "empty values marked omit-empty removed",
+			flags:        []string{"--config-dir", omitEmptyConfigDir},
+			sourceFile:   filepath.Join(repoRoot, "test-data", "deployment.empty-values.yaml"),
+			expectedFile: filepath.Join(repoRoot, "test-data", "deployment.empty-values-removed.yaml"),
+		},
+		{
+			note:         "each document of a bundle gets fixed",
+			sourceFile:   filepath.Join(repoRoot, "test-data", "bundle.unordered.yaml"),
+			expectedFile: filepath.Join(repoRoot, "test-data", "bundle.unordered-fixed.yaml"),
+		},
+		{
+			note:         "documents reordered with kind-order",
+			flags:        []string{"--config-file", orderConfigFile},
+			sourceFile:   filepath.Join(repoRoot, "test-data", "bundle.unordered.yaml"),
+			expectedFile: filepath.Join(repoRoot, "test-data", "bundle.ordered.yaml"),
+		},
+		{
+			note:         "documents reordered with kind-order keep the file header at the top",
+			flags:        []string{"--config-file", orderConfigFile},
+			sourceFile:   filepath.Join(repoRoot, "test-data", "bundle.header.yaml"),
+			expectedFile: filepath.Join(repoRoot, "test-data", "bundle.header-ordered.yaml"),
+		},
 	}
 
 	for _, tc := range testCases {
@@ -180,7 +517,9 @@ func TestIntegrationFix(t *testing.T) {
 			}
 
 			// Run fix without prompting
-			args := []string{"fix", "--config-dir", configDir, "--prompt=false", tmpFile}
+			args := []string{"fix", "--config-dir", configDir, "--prompt=false"}
+			args = append(args, tc.flags...)
+			args = append(args, tmpFile)
 			cmd := exec.Command(binary, args...)
 			out, err := cmd.CombinedOutput()
 			if err != nil {
@@ -264,3 +603,1091 @@ func TestIntegrationFixIdempotent(t *testing.T) {
 		t.Errorf("fix is not idempotent\nfirst fix:\n%s\nsecond fix:\n%s", firstFix, secondFix)
 	}
 }
+
+func TestIntegrationFixCheckModes(t *testing.T) {
+	binary := buildBinary(t)
+	repoRoot := findRepoRoot(t)
+	configDir := filepath.Join(repoRoot, "example-configs")
+
+	type testCase struct {
+		note           string
+		flags          []string
+		files          []string
+		expectFail     bool
+		expectedStdout string // exact stdout, unless expectInStdout is set
+		expectInStdout []string
+	}
+
+	testCases := []testCase{
+		{
+			note:           "list files that would change",
+			flags:          []string{"-l"},
+			files:          []string{"deployment.invalid.yaml", "deployment.valid.yaml", "service.invalid.yaml"},
+			expectFail:     true,
+			expectedStdout: "deployment.invalid.yaml\nservice.invalid.yaml\n",
+		},
+		{
+			note:           "list nothing when nothing would change",
+			flags:          []string{"--list"},
+			files:          []string{"deployment.valid.yaml"},
+			expectedStdout: "",
+		},
+		{
+			note:           "diff files that would change",
+			flags:          []string{"-d"},
+			files:          []string{"deployment.valid.yaml", "service.invalid.yaml"},
+			expectFail:     true,
+			expectInStdout: []string{"--- a/service.invalid.yaml\n+++ b/service.invalid.yaml\n", "-  namespace: example  # deploy to example namespace\n   name: example\n+  namespace: example  # deploy to example namespace\n"},
+		},
+		{
+			note:           "list and diff together",
+			flags:          []string{"-l", "-d"},
+			files:          []string{"service.invalid.yaml"},
+			expectFail:     true,
+			expectInStdout: []string{"service.invalid.yaml\n--- a/service.invalid.yaml\n"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.note, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			originals := map[string][]byte{}
+			for _, file := range tc.files {
+				original, err := os.ReadFile(filepath.Join(repoRoot, "test-data", file))
+				if err != nil {
+					t.Fatal(err)
+				}
+				originals[file] = original
+				if err := os.WriteFile(filepath.Join(tmpDir, file), original, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// check modes don't prompt, even though prompting is the default
+			var stdout, stderr bytes.Buffer
+			args := []string{"fix", "--config-dir", configDir}
+			args = append(args, tc.flags...)
+			args = append(args, tc.files...)
+			cmd := exec.Command(binary, args...)
+			cmd.Dir = tmpDir
+			cmd.Stdout = &stdout
+			cmd.Stderr = &stderr
+			err := cmd.Run()
+			if tc.expectFail != (err != nil) {
+				t.Errorf("Description: %s: expected failure to be %v, got: %v\nstdout: %s\nstderr: %s", tc.note, tc.expectFail, err, stdout.String(), stderr.String())
+			}
+			if tc.expectInStdout == nil && stdout.String() != tc.expectedStdout {
+				t.Errorf("Description: %s: stdout: \n-expected:\n%v\n+got:\n%v\n", tc.note, tc.expectedStdout, stdout.String())
+			}
+			for _, expected := range tc.expectInStdout {
+				if !strings.Contains(stdout.String(), expected) {
+					t.Errorf("Description: %s: expected stdout to contain:\n%s\ngot:\n%s", tc.note, expected, stdout.String())
+				}
+			}
+
+			// files are left alone
+			for file, original := range originals {
+				content, err := os.ReadFile(filepath.Join(tmpDir, file))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(content, original) {
+					t.Errorf("Description: %s: file '%s' was written", tc.note, file)
+				}
+			}
+		})
+	}
+}
+
+func TestIntegrationStdin(t *testing.T) {
+	binary := buildBinary(t)
+	repoRoot := findRepoRoot(t)
+	configDir := filepath.Join(repoRoot, "example-configs")
+
+	// a config only found by the directory of '--stdin-filename'
+	tmpDir := t.TempDir()
+	widgetConfigDir := filepath.Join(tmpDir, "widgets", ".predictable-yaml")
+	if err := os.MkdirAll(widgetConfigDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	widgetConfig := "---\nkind: Widget  # first, required\nsize: TODO  # required\n"
+	if err := os.WriteFile(filepath.Join(widgetConfigDir, "Widget.yaml"), []byte(widgetConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		note           string
+		args           []string
+		stdinFile      string // test-data file piped to stdin
+		stdin          string // piped to stdin, unless stdinFile is set
+		expectFail     bool
+		expectedFile   string // test-data file stdout should match, unless expectedStdout or expectInStdout is set
+		expectedStdout string
+		expectInStdout []string
+		expectInStderr []string
+	}
+
+	testCases := []testCase{
+		{
+			note:         "fix writes the fixed file to stdout",
+			args:         []string{"fix", "--config-dir", configDir, "-"},
+			stdinFile:    "deployment.invalid.yaml",
+			expectedFile: "deployment.invalid-fixed.yaml",
+		},
+		{
+			note:         "fix writes an unchanged file to stdout",
+			args:         []string{"fix", "--config-dir", configDir, "-"},
+			stdinFile:    "service.valid.yaml",
+			expectedFile: "service.valid.yaml",
+		},
+		{
+			note:           "fix reports on stderr by the stdin filename",
+			args:           []string{"fix", "--config-dir", configDir, "--format", "json", "--stdin-filename", "apps/service.yaml", "-"},
+			stdinFile:      "service.invalid.yaml",
+			expectedFile:   "service.invalid-fixed.yaml",
+			expectInStderr: []string{`"file": "apps/service.yaml"`},
+		},
+		{
+			note:           "lint fails on stdin",
+			args:           []string{"lint", "--config-dir", configDir, "-"},
+			stdinFile:      "service.invalid.yaml",
+			expectFail:     true,
+			expectInStdout: []string{"File: <stdin>", "name: example  # move to top (<stdin>:6:3)"},
+		},
+		{
+			note:           "fix -l lists stdin by its filename",
+			args:           []string{"fix", "--config-dir", configDir, "-l", "--stdin-filename", "apps/service.yaml", "-"},
+			stdinFile:      "service.invalid.yaml",
+			expectFail:     true,
+			expectedStdout: "apps/service.yaml\n",
+		},
+		{
+			note:           "stdin filename resolves configs",
+			args:           []string{"lint", "--stdin-filename", "widgets/widget.yaml", "-"},
+			stdin:          "kind: Widget\n",
+			expectFail:     true,
+			expectInStdout: []string{"size: TODO  # add (widgets/widget.yaml:1:1)"},
+		},
+		{
+			note:           "stdin can only be read once",
+			args:           []string{"lint", "--config-dir", configDir, "-", "-"},
+			stdin:          "kind: Widget\n",
+			expectFail:     true,
+			expectedStdout: "",
+			expectInStderr: []string{"stdin ('-') can only be read once"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.note, func(t *testing.T) {
+			stdin := []byte(tc.stdin)
+			if tc.stdinFile != "" {
+				var err error
+				stdin, err = os.ReadFile(filepath.Join(repoRoot, "test-data", tc.stdinFile))
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			expectedStdout := tc.expectedStdout
+			if tc.expectedFile != "" {
+				expected, err := os.ReadFile(filepath.Join(repoRoot, "test-data", tc.expectedFile))
+				if err != nil {
+					t.Fatal(err)
+				}
+				expectedStdout = string(expected)
+			}
+
+			var stdout, stderr bytes.Buffer
+			cmd := exec.Command(binary, tc.args...)
+			cmd.Dir = tmpDir
+			cmd.Stdin = bytes.NewReader(stdin)
+			cmd.Stdout = &stdout
+			cmd.Stderr = &stderr
+			err := cmd.Run()
+			if tc.expectFail != (err != nil) {
+				t.Errorf("Description: %s: expected failure to be %v, got: %v\nstdout: %s\nstderr: %s", tc.note, tc.expectFail, err, stdout.String(), stderr.String())
+			}
+			if tc.expectInStdout == nil && stdout.String() != expectedStdout {
+				t.Errorf("Description: %s: stdout: \n-expected:\n%v\n+got:\n%v\n", tc.note, expectedStdout, stdout.String())
+			}
+			for _, expected := range tc.expectInStdout {
+				if !strings.Contains(stdout.String(), expected) {
+					t.Errorf("Description: %s: expected stdout to contain:\n%s\ngot:\n%s", tc.note, expected, stdout.String())
+				}
+			}
+			for _, expected := range tc.expectInStderr {
+				if !strings.Contains(stderr.String(), expected) {
+					t.Errorf("Description: %s: expected stderr to contain:\n%s\ngot:\n%s", tc.note, expected, stderr.String())
+				}
+			}
+		})
+	}
+
+	// without its filename, stdin isn't found to be a widget
+	cmd := exec.Command(binary, "lint", "-")
+	cmd.Dir = tmpDir
+	cmd.Stdin = strings.NewReader("kind: Widget\n")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("expected lint of stdin without a filename to pass, got: %v\noutput: %s", err, out)
+	}
+}
+
+func TestIntegrationFixOutputDir(t *testing.T) {
+	binary := buildBinary(t)
+	repoRoot := findRepoRoot(t)
+	configDir := filepath.Join(repoRoot, "example-configs")
+
+	tmpDir := t.TempDir()
+	inputs := map[string]string{
+		"manifests/deployment.yaml":     "deployment.invalid.yaml",
+		"manifests/apps/service.yaml":   "service.invalid.yaml",
+		"manifests/apps/unchanged.yaml": "service.valid.yaml",
+		"extra.yaml":                    "service.invalid.yaml",
+	}
+	for input, source := range inputs {
+		content, err := os.ReadFile(filepath.Join(repoRoot, "test-data", source))
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(tmpDir, input)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// prompting is the default, but isn't done for output directories
+	cmd := exec.Command(binary, "fix", "--config-dir", configDir, "--output-dir", "out", "manifests", "extra.yaml")
+	cmd.Dir = tmpDir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("fix command failed: %v\noutput: %s", err, out)
+	}
+
+	outputs := map[string]string{
+		"out/deployment.yaml":     "deployment.invalid-fixed.yaml",
+		"out/apps/service.yaml":   "service.invalid-fixed.yaml",
+		"out/apps/unchanged.yaml": "service.valid.yaml",
+		"out/extra.yaml":          "service.invalid-fixed.yaml",
+	}
+	for output, expectedFile := range outputs {
+		expected, err := os.ReadFile(filepath.Join(repoRoot, "test-data", expectedFile))
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := os.ReadFile(filepath.Join(tmpDir, output))
+		if err != nil {
+			t.Errorf("expected output file '%s': %v", output, err)
+			continue
+		}
+		if string(content) != string(expected) {
+			t.Errorf("output file '%s' does not match expected file %s\ngot:\n%s\nexpected:\n%s", output, expectedFile, content, expected)
+		}
+	}
+
+	// inputs are left alone
+	for input, source := range inputs {
+		original, err := os.ReadFile(filepath.Join(repoRoot, "test-data", source))
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := os.ReadFile(filepath.Join(tmpDir, input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(content, original) {
+			t.Errorf("input file '%s' was written", input)
+		}
+	}
+}
+
+func TestIntegrationFixOutputDirErrors(t *testing.T) {
+	binary := buildBinary(t)
+	repoRoot := findRepoRoot(t)
+	configDir := filepath.Join(repoRoot, "example-configs")
+
+	tmpDir := t.TempDir()
+	broken := "apiVersion: v1\nkind: Service\nmetadata:\n  name: broken\nspec:\n  ports:\n"
+	if err := os.MkdirAll(filepath.Join(tmpDir, "manifests"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "manifests", "broken.yaml"), []byte(broken), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// the run fails, but files with errors are copied unchanged to keep the tree complete
+	cmd := exec.Command(binary, "fix", "--config-dir", configDir, "--output-dir", "out", "manifests")
+	cmd.Dir = tmpDir
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		t.Errorf("expected failure\noutput: %s", out)
+	}
+	content, err := os.ReadFile(filepath.Join(tmpDir, "out", "broken.yaml"))
+	if err != nil {
+		t.Fatalf("expected output file 'out/broken.yaml': %v\noutput: %s", err, out)
+	}
+	if string(content) != broken {
+		t.Errorf("output file 'out/broken.yaml' was changed\ngot:\n%s\nexpected:\n%s", content, broken)
+	}
+}
+
+func TestIntegrationFormatJSON(t *testing.T) {
+	binary := buildBinary(t)
+	repoRoot := findRepoRoot(t)
+	configDir := filepath.Join(repoRoot, "example-configs")
+	omitEmptyConfigDir := writeOmitEmptyConfigDir(t, repoRoot)
+
+	type testCase struct {
+		note            string
+		command         string
+		flags           []string
+		file            string
+		expectFail      bool
+		expectedSummary runSummary
+		expectedFinding finding
+	}
+
+	testCases := []testCase{
+		{
+			note:            "lint reports moves as findings",
+			command:         "lint",
+			file:            "deployment.invalid.yaml",
+			expectFail:      true,
+			expectedSummary: runSummary{Files: 1, Documents: 1, Warnings: 2, Changes: 4},
+			expectedFinding: finding{
+				Document: 1,
+				Path:     ".metadata.name",
+				Line:     7,
+				Column:   3,
+				Rule:     compare.RuleFirst,
+				Severity: severityError,
+				Message:  "'.metadata.name' (line 7): move to top",
+				Change:   &findingChange{Action: "move to top", Key: "name", Value: "cool-app"},
+			},
+		},
+		{
+			note:            "lint reports validation errors as findings",
+			command:         "lint",
+			flags:           []string{"--config-dir", omitEmptyConfigDir},
+			file:            "deployment.empty-values.yaml",
+			expectFail:      true,
+			expectedSummary: runSummary{Files: 1, Documents: 1, Errors: 3, Warnings: 2},
+			expectedFinding: finding{
+				Document: 1,
+				Path:     ".spec.template.spec.containers[0].envFrom",
+				Line:     30,
+				Column:   9,
+				Rule:     compare.RuleOmitEmpty,
+				Severity: severityError,
+				Message:  "validation error: empty value at '.spec.template.spec.containers[0].envFrom' (line 30) — remove it",
+			},
+		},
+		{
+			note:            "fix reports changes made",
+			command:         "fix",
+			flags:           []string{"--config-dir", omitEmptyConfigDir},
+			file:            "deployment.empty-values.yaml",
+			expectedSummary: runSummary{Files: 1, Documents: 1, Warnings: 2, Changes: 3, Success: true},
+			expectedFinding: finding{
+				Document: 1,
+				Path:     ".metadata.annotations",
+				Line:     9,
+				Column:   3,
+				Rule:     compare.RuleOmitEmpty,
+				Severity: severityInfo,
+				Message:  "'.metadata.annotations' (line 9): remove",
+				Change:   &findingChange{Action: "remove", Key: "annotations"},
+			},
+		},
+		{
+			note:            "lint reports documents without a schema as warnings",
+			command:         "lint",
+			flags:           []string{"--fail-on-warnings"},
+			file:            "no-kind.yaml",
+			expectFail:      true,
+			expectedSummary: runSummary{Files: 1, Documents: 1, Warnings: 1},
+			expectedFinding: finding{
+				Document: 1,
+				Rule:     compare.RuleSchema,
+				Severity: severityWarning,
+				Message:  "warning: unable to determine a schema",
+			},
+		},
+		{
+			note:            "lint reports warnings of documents with null values",
+			command:         "lint",
+			file:            "service.null-value.yaml",
+			expectFail:      true,
+			expectedSummary: runSummary{Files: 1, Documents: 1, Errors: 1, Warnings: 1},
+			expectedFinding: finding{
+				Document: 1,
+				Line:     2,
+				Rule:     compare.RuleDirective,
+				Severity: severityWarning,
+				Message:  "warning: unknown directive 'ignroe-requireds' in '# predictable-yaml:' comment (line 2)",
+			},
+		},
+		{
+			note:            "fix reports copied entries with their values and source",
+			command:         "fix",
+			flags:           []string{"--copy-missing-entries"},
+			file:            "deployment.mismatched-labels.yaml",
+			expectedSummary: runSummary{Files: 1, Documents: 1, Warnings: 4, Changes: 1, Success: true},
+			expectedFinding: finding{
+				Document: 1,
+				Path:     ".spec.template.metadata.labels.tier",
+				Line:     22,
+				Column:   9,
+				Rule:     compare.RuleSubsetOf,
+				Severity: severityInfo,
+				Message:  "'.spec.template.metadata.labels.tier' (line 22): copy from .spec.selector.matchLabels",
+				Change:   &findingChange{Action: "copy from .spec.selector.matchLabels", Key: "tier", Value: "web"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.note, func(t *testing.T) {
+			original, err := os.ReadFile(filepath.Join(repoRoot, "test-data", tc.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tmpFile := filepath.Join(t.TempDir(), tc.file)
+			if err := os.WriteFile(tmpFile, original, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			// stdout holds only the report, logs go to stderr
+			var stdout, stderr bytes.Buffer
+			args := append([]string{tc.command, "--config-dir", configDir, "--format", "json"}, tc.flags...)
+			cmd := exec.Command(binary, append(args, tmpFile)...)
+			cmd.Stdout = &stdout
+			cmd.Stderr = &stderr
+			err = cmd.Run()
+			if tc.expectFail != (err != nil) {
+				t.Errorf("expected failure to be %v, got: %v\nstdout: %s\nstderr: %s", tc.expectFail, err, stdout.String(), stderr.String())
+			}
+
+			var report struct {
+				Findings []finding  `json:"findings"`
+				Summary  runSummary `json:"summary"`
+			}
+			if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+				t.Fatalf("failed parsing report: %v\nstdout: %s", err, stdout.String())
+			}
+			if report.Summary != tc.expectedSummary {
+				t.Errorf("summary: \n-expected:\n%+v\n+got:\n%+v\n", tc.expectedSummary, report.Summary)
+			}
+			tc.expectedFinding.File = tmpFile
+			expected, _ := json.Marshal(tc.expectedFinding)
+			found := false
+			for _, f := range report.Findings {
+				got, _ := json.Marshal(f)
+				if bytes.Equal(got, expected) {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("expected finding %s\nstdout: %s", expected, stdout.String())
+			}
+		})
+	}
+}
+
+func TestIntegrationFormatSARIF(t *testing.T) {
+	binary := buildBinary(t)
+	repoRoot := findRepoRoot(t)
+	configDir := filepath.Join(repoRoot, "example-configs")
+
+	schemaFile, err := os.Open(filepath.Join(repoRoot, "test-data", "sarif-schema-2.1.0.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer schemaFile.Close()
+	schemaDoc, err := jsonschema.UnmarshalJSON(schemaFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(sarifSchema, schemaDoc); err != nil {
+		t.Fatal(err)
+	}
+	schema, err := compiler.Compile(sarifSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		note          string
+		command       string
+		flags         []string
+		files         []string
+		expectedRules []string
+		fixedFile     string // if set, applying the suggested fixes to the first file must give this file
+	}
+
+	testCases := []testCase{
+		{
+			note:          "lint results with fixes",
+			command:       "lint",
+			files:         []string{"deployment.invalid.yaml"},
+			expectedRules: []string{"preferred", "preferred", "first", "order", "first", "order"},
+			fixedFile:     "deployment.invalid-fixed.yaml",
+		},
+		{
+			note:          "lint results without positions",
+			command:       "lint",
+			flags:         []string{"--config-file", writeOrderConfigFile(t)},
+			files:         []string{"bundle.unordered-fixed.yaml"},
+			expectedRules: []string{"document-order"},
+		},
+		{
+			note:          "fix results are notes",
+			command:       "fix",
+			flags:         []string{"--config-dir", writeOmitEmptyConfigDir(t, repoRoot)},
+			files:         []string{"deployment.empty-values.yaml"},
+			expectedRules: []string{"preferred", "preferred", "omit-empty", "omit-empty", "omit-empty"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.note, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			args := []string{tc.command, "--config-dir", configDir, "--format", "sarif"}
+			args = append(args, tc.flags...)
+			for _, file := range tc.files {
+				original, err := os.ReadFile(filepath.Join(repoRoot, "test-data", file))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(tmpDir, file), original, 0644); err != nil {
+					t.Fatal(err)
+				}
+				args = append(args, file)
+			}
+
+			var stdout, stderr bytes.Buffer
+			cmd := exec.Command(binary, args...)
+			cmd.Dir = tmpDir
+			cmd.Stdout = &stdout
+			cmd.Stderr = &stderr
+			_ = cmd.Run()
+
+			instance, err := jsonschema.UnmarshalJSON(bytes.NewReader(stdout.Bytes()))
+			if err != nil {
+				t.Fatalf("failed parsing SARIF log: %v\nstdout: %s\nstderr: %s", err, stdout.String(), stderr.String())
+			}
+			if err := schema.Validate(instance); err != nil {
+				t.Errorf("SARIF log is not valid: %v\nstdout: %s", err, stdout.String())
+			}
+
+			var log sarifLog
+			if err := json.Unmarshal(stdout.Bytes(), &log); err != nil {
+				t.Fatal(err)
+			}
+			rules := []string{}
+			for _, result := range log.Runs[0].Results {
+				rules = append(rules, result.RuleID)
+				if tc.command == "fix" && result.Level != "note" && result.Level != "warning" {
+					t.Errorf("expected fix results to be notes or warnings, got: %s", result.Level)
+				}
+			}
+			if strings.Join(rules, ", ") != strings.Join(tc.expectedRules, ", ") {
+				t.Errorf("rules: \n-expected:\n%v\n+got:\n%v\n", tc.expectedRules, rules)
+			}
+			if tc.fixedFile == "" {
+				return
+			}
+
+			original, err := os.ReadFile(filepath.Join(tmpDir, tc.files[0]))
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected, err := os.ReadFile(filepath.Join(repoRoot, "test-data", tc.fixedFile))
+			if err != nil {
+				t.Fatal(err)
+			}
+			fixed := string(original)
+			for _, result := range log.Runs[0].Results {
+				for _, fix := range result.Fixes {
+					replacement := fix.ArtifactChanges[0].Replacements[0]
+					fixed = applyReplacement(t, fixed, replacement)
+				}
+			}
+			if fixed != string(expected) {
+				t.Errorf("fixed: \n-expected:\n%s\n+got:\n%s\n", expected, fixed)
+			}
+		})
+	}
+}
+
+// applyReplacement applies a SARIF replacement given in lines and columns to content
+func applyReplacement(t *testing.T, content string, replacement sarifReplacement) string {
+	t.Helper()
+	lines := strings.SplitAfter(content, "\n")
+	offset := func(line, column int) int {
+		position := 0
+		for _, l := range lines[:line-1] {
+			position += len(l)
+		}
+		return position + column - 1
+	}
+	region := replacement.DeletedRegion
+	start, end := offset(region.StartLine, region.StartColumn), offset(region.EndLine, region.EndColumn)
+
+	return content[:start] + replacement.InsertedContent.Text + content[end:]
+}
+
+func TestIntegrationJUnitReport(t *testing.T) {
+	binary := buildBinary(t)
+	repoRoot := findRepoRoot(t)
+	configDir := filepath.Join(repoRoot, "example-configs")
+
+	// files in two directories, one of them invalid
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"apps/deployment.yaml": "deployment.invalid.yaml",
+		"apps/service.yaml":    "service.valid.yaml",
+		"certs/cert.yaml":      "certificate.valid.yaml",
+	}
+	for path, source := range files {
+		content, err := os.ReadFile(filepath.Join(repoRoot, "test-data", source))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.MkdirAll(filepath.Join(tmpDir, filepath.Dir(path)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(tmpDir, path), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	type testCase struct {
+		note           string
+		groupBy        string
+		expectedSuites map[string][]string
+	}
+
+	testCases := []testCase{
+		{
+			note:    "grouped by directory",
+			groupBy: junitGroupByDirectory,
+			expectedSuites: map[string][]string{
+				"apps":  {"apps/deployment.yaml", "apps/service.yaml"},
+				"certs": {"certs/cert.yaml"},
+			},
+		},
+		{
+			note:    "grouped by kind",
+			groupBy: junitGroupByKind,
+			expectedSuites: map[string][]string{
+				"Deployment":  {"apps/deployment.yaml"},
+				"Service":     {"apps/service.yaml"},
+				"Certificate": {"certs/cert.yaml"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.note, func(t *testing.T) {
+			reportPath := filepath.Join(t.TempDir(), "report.xml")
+			// the JUnit report is written alongside the text output
+			var stdout, stderr bytes.Buffer
+			cmd := exec.Command(binary, "lint", "--config-dir", configDir, "--junit-report", reportPath, "--junit-group-by", tc.groupBy, "apps", "certs")
+			cmd.Dir = tmpDir
+			cmd.Stdout = &stdout
+			cmd.Stderr = &stderr
+			if err := cmd.Run(); err == nil {
+				t.Errorf("expected failure\nstdout: %s\nstderr: %s", stdout.String(), stderr.String())
+			}
+			if !strings.Contains(stdout.String(), "File: apps/deployment.yaml") {
+				t.Errorf("expected text output, got: %s", stdout.String())
+			}
+
+			content, err := os.ReadFile(reportPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var report junitTestSuites
+			if err := xml.Unmarshal(content, &report); err != nil {
+				t.Fatalf("failed parsing report: %v\n%s", err, content)
+			}
+			if report.Tests != 3 || report.Failures != 1 {
+				t.Errorf("expected 3 tests and 1 failure, got %d and %d\n%s", report.Tests, report.Failures, content)
+			}
+			suites := map[string][]string{}
+			for _, suite := range report.Suites {
+				for _, testCase := range suite.Cases {
+					suites[suite.Name] = append(suites[suite.Name], testCase.Name)
+					if _, err := strconv.ParseFloat(testCase.Time, 64); err != nil {
+						t.Errorf("test case '%s' has an invalid time: %v", testCase.Name, err)
+					}
+					failed := testCase.Failure != nil
+					if failed != (testCase.Name == "apps/deployment.yaml") {
+						t.Errorf("test case '%s': unexpected failure: %+v", testCase.Name, testCase.Failure)
+					}
+				}
+			}
+			if !reflect.DeepEqual(suites, tc.expectedSuites) {
+				t.Errorf("Description: %s: suites: \n-expected:\n%v\n+got:\n%v\n", tc.note, tc.expectedSuites, suites)
+			}
+		})
+	}
+}
+
+func TestIntegrationGitHubActions(t *testing.T) {
+	binary := buildBinary(t)
+	repoRoot := findRepoRoot(t)
+	configDir := filepath.Join(repoRoot, "example-configs")
+
+	type testCase struct {
+		note        string
+		command     string
+		flags       []string
+		expected    string
+		notExpected string
+	}
+
+	testCases := []testCase{
+		{
+			note:     "github format is selected in GitHub Actions",
+			command:  "lint",
+			expected: "::error file=deployment.invalid.yaml,line=7,col=3,title=predictable-yaml (first)::'.metadata.name' (line 7): move to top\n",
+		},
+		{
+			note:     "a given format is kept",
+			command:  "lint",
+			flags:    []string{"--format", "text"},
+			expected: "File: deployment.invalid.yaml\n",
+		},
+		{
+			note:        "fix keeps the text format in GitHub Actions",
+			command:     "fix",
+			flags:       []string{"--list"},
+			expected:    "deployment.invalid.yaml\n",
+			notExpected: "::",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.note, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			args := []string{tc.command, "--config-dir", configDir}
+			args = append(args, tc.flags...)
+			args = append(args, "deployment.invalid.yaml")
+			cmd := exec.Command(binary, args...)
+			cmd.Dir = filepath.Join(repoRoot, "test-data")
+			cmd.Env = append(os.Environ(), "GITHUB_ACTIONS=true")
+			cmd.Stdout = &stdout
+			cmd.Stderr = &stderr
+			if err := cmd.Run(); err == nil {
+				t.Errorf("expected failure\nstdout: %s\nstderr: %s", stdout.String(), stderr.String())
+			}
+			if !strings.Contains(stdout.String(), tc.expected) {
+				t.Errorf("Description: %s: expected stdout to contain:\n%s\ngot:\n%s", tc.note, tc.expected, stdout.String())
+			}
+			if tc.notExpected != "" && strings.Contains(stdout.String(), tc.notExpected) {
+				t.Errorf("Description: %s: expected stdout not to contain:\n%s\ngot:\n%s", tc.note, tc.notExpected, stdout.String())
+			}
+		})
+	}
+}
+
+func TestIntegrationConvertConfig(t *testing.T) {
+	binary := buildBinary(t)
+	repoRoot := findRepoRoot(t)
+	configDir := filepath.Join(repoRoot, "example-configs")
+
+	// convert every example config to the schema format
+	schemaDir := t.TempDir()
+	entries, err := os.ReadDir(configDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".yaml")
+		schemaPath := filepath.Join(schemaDir, name+".pyschema.yaml")
+		cmd := exec.Command(binary, "convert-config", filepath.Join(configDir, entry.Name()), "--output", schemaPath)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("convert-config %s failed: %v\noutput: %s", entry.Name(), err, out)
+		}
+
+		// converting back and forth again is stable
+		cmd = exec.Command(binary, "convert-config", schemaPath)
+		commentFormat, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("convert-config %s failed: %v", schemaPath, err)
+		}
+		commentPath := filepath.Join(t.TempDir(), entry.Name())
+		if err := os.WriteFile(commentPath, commentFormat, 0644); err != nil {
+			t.Fatal(err)
+		}
+		cmd = exec.Command(binary, "convert-config", commentPath)
+		again, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("convert-config %s failed: %v", commentPath, err)
+		}
+		schema, err := os.ReadFile(schemaPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(again) != string(schema) {
+			t.Errorf("converting %s is not stable\nfirst:\n%s\nsecond:\n%s", entry.Name(), schema, again)
+		}
+	}
+
+	// the schema configs fix files the same as the originals
+	for _, file := range []string{"deployment.invalid.yaml", "service.invalid.yaml"} {
+		original, err := os.ReadFile(filepath.Join(repoRoot, "test-data", file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		fixed := []string{}
+		for _, dir := range []string{configDir, schemaDir} {
+			tmpFile := filepath.Join(t.TempDir(), file)
+			if err := os.WriteFile(tmpFile, original, 0644); err != nil {
+				t.Fatal(err)
+			}
+			cmd := exec.Command(binary, "fix", "--config-dir", dir, "--prompt=false", tmpFile)
+			out, err := cmd.CombinedOutput()
+			if err != nil {
+				t.Fatalf("fix %s with %s failed: %v\noutput: %s", file, dir, err, out)
+			}
+			result, err := os.ReadFile(tmpFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fixed = append(fixed, string(result))
+		}
+		if fixed[0] != fixed[1] {
+			t.Errorf("fixing %s with schema configs differs\ncomment configs:\n%s\nschema configs:\n%s", file, fixed[0], fixed[1])
+		}
+	}
+}
+
+func TestIntegrationCheckConfigs(t *testing.T) {
+	binary := buildBinary(t)
+	repoRoot := findRepoRoot(t)
+
+	brokenDir := t.TempDir()
+	configs := map[string]string{
+		"deployment.yaml": `apiVersion: apps/v1  # first, required
+kind: Deployment  # requried
+spec:
+  template: {}  # ditto=ReplicaSet.spec.template
+`,
+		"deployment-copy.yaml": `apiVersion: apps/v1  # first, required
+kind: Deployment  # required
+`,
+		"pod.yaml": `apiVersion: v1  # first, required
+kind: Pod  # required
+spec:
+  volumes: {}  # ditto=.spec.volumes
+`,
+	}
+	for name, content := range configs {
+		if err := os.WriteFile(filepath.Join(brokenDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	type testCase struct {
+		note           string
+		configDir      string
+		expectFail     bool
+		expectInOutput []string
+	}
+
+	testCases := []testCase{
+		{
+			note:           "example configs are valid",
+			configDir:      filepath.Join(repoRoot, "example-configs"),
+			expectInOutput: []string{"SUCCESS (4 configs checked)"},
+		},
+		{
+			note:       "problems are reported with file and line",
+			configDir:  brokenDir,
+			expectFail: true,
+			expectInOutput: []string{
+				filepath.Join(brokenDir, "deployment.yaml") + ":1: configuration error: duplicate config 'Deployment', also defined in '" + filepath.Join(brokenDir, "deployment-copy.yaml") + "'",
+				filepath.Join(brokenDir, "deployment.yaml") + ":2: configuration error: unknown directive 'requried' at path: .kind",
+				// a problem in one file doesn't hide the ditto problems of any
+				filepath.Join(brokenDir, "deployment.yaml") + ":4: configuration error: no config found for schema 'ReplicaSet' specified at path: .spec.template",
+				filepath.Join(brokenDir, "pod.yaml") + ":4: configuration error: ditto cycle '.spec.volumes' -> '.spec.volumes' specified at path: .spec.volumes",
+				"FAIL (4 problems)",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.note, func(t *testing.T) {
+			cmd := exec.Command(binary, "check-configs", tc.configDir)
+			out, err := cmd.CombinedOutput()
+			output := string(out)
+
+			if tc.expectFail != (err != nil) {
+				t.Errorf("expected failure: %v, got error: %v\noutput: %s", tc.expectFail, err, output)
+			}
+			for _, expected := range tc.expectInOutput {
+				if !strings.Contains(output, expected) {
+					t.Errorf("expected output to contain %q\noutput: %s", expected, output)
+				}
+			}
+		})
+	}
+
+	// once each file is valid, only the ditto problems are left
+	if err := os.Remove(filepath.Join(brokenDir, "deployment-copy.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	fixed := strings.Replace(configs["deployment.yaml"], "requried", "required", 1)
+	if err := os.WriteFile(filepath.Join(brokenDir, "deployment.yaml"), []byte(fixed), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command(binary, "check-configs", brokenDir).CombinedOutput()
+	if err == nil {
+		t.Errorf("expected failure\noutput: %s", out)
+	}
+	for _, expected := range []string{
+		filepath.Join(brokenDir, "deployment.yaml") + ":4: configuration error: no config found for schema 'ReplicaSet' specified at path: .spec.template",
+		filepath.Join(brokenDir, "pod.yaml") + ":4: configuration error: ditto cycle '.spec.volumes' -> '.spec.volumes' specified at path: .spec.volumes",
+		"FAIL (2 problems)",
+	} {
+		if !strings.Contains(string(out), expected) {
+			t.Errorf("expected output to contain %q\noutput: %s", expected, out)
+		}
+	}
+
+	// configs that can't extend are reported rather than ending the check
+	service := "# predictable-yaml: kind=Service, extends=Missing\nspec: {}  # required\n"
+	if err := os.WriteFile(filepath.Join(brokenDir, "service.yaml"), []byte(service), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out, err = exec.Command(binary, "check-configs", brokenDir).CombinedOutput()
+	if err == nil {
+		t.Errorf("expected failure\noutput: %s", out)
+	}
+	for _, expected := range []string{
+		filepath.Join(brokenDir, "service.yaml") + ": error extending config 'Service': configuration error: no config 'Missing' to extend",
+		"FAIL (3 problems)",
+	} {
+		if !strings.Contains(string(out), expected) {
+			t.Errorf("expected output to contain %q\noutput: %s", expected, out)
+		}
+	}
+}
+
+func TestIntegrationChainedDittos(t *testing.T) {
+	binary := buildBinary(t)
+
+	// 'extras' is resolved through 'spares' to 'parts'
+	chainedDir := t.TempDir()
+	chained := `---
+kind: Widget  # first, required
+spec:
+  parts:
+  - name: TODO  # first, required
+    size: TODO
+  spares: []  # ditto=.spec.parts
+  extras: []  # ditto=.spec.spares
+`
+	if err := os.WriteFile(filepath.Join(chainedDir, "Widget.yaml"), []byte(chained), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cyclicDir := t.TempDir()
+	cyclic := `---
+kind: Widget  # first, required
+spec:
+  spares: []  # ditto=.spec.extras
+  extras: []  # ditto=.spec.spares
+`
+	if err := os.WriteFile(filepath.Join(cyclicDir, "Widget.yaml"), []byte(cyclic), 0644); err != nil {
+		t.Fatal(err)
+	}
+	widget := `---
+kind: Widget
+spec:
+  extras:
+  - size: 2
+    name: a
+`
+	fixedWidget := `---
+kind: Widget
+spec:
+  extras:
+  - name: a
+    size: 2
+`
+
+	type testCase struct {
+		note           string
+		command        string
+		configDir      string
+		expectFail     bool
+		expectInOutput string
+		expectedFile   string // the file after the command
+	}
+
+	testCases := []testCase{
+		{
+			note:           "lint follows chained dittos",
+			command:        "lint",
+			configDir:      chainedDir,
+			expectFail:     true,
+			expectInOutput: "name: a  # move to top",
+			expectedFile:   widget,
+		},
+		{
+			note:         "fix follows chained dittos",
+			command:      "fix",
+			configDir:    chainedDir,
+			expectedFile: fixedWidget,
+		},
+		{
+			note:           "lint fails on ditto cycles",
+			command:        "lint",
+			configDir:      cyclicDir,
+			expectFail:     true,
+			expectInOutput: "configuration error: ditto cycle '.spec.extras' -> '.spec.spares' -> '.spec.extras' specified at path: .spec.extras",
+			expectedFile:   widget,
+		},
+		{
+			note:           "fix fails on ditto cycles",
+			command:        "fix",
+			configDir:      cyclicDir,
+			expectFail:     true,
+			expectInOutput: "configuration error: ditto cycle '.spec.extras' -> '.spec.spares' -> '.spec.extras' specified at path: .spec.extras",
+			expectedFile:   widget,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.note, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "widget.yaml")
+			if err := os.WriteFile(filePath, []byte(widget), 0644); err != nil {
+				t.Fatal(err)
+			}
+			args := []string{tc.command, "--config-dir", tc.configDir}
+			if tc.command == "fix" {
+				args = append(args, "--prompt=false")
+			}
+			out, err := exec.Command(binary, append(args, filePath)...).CombinedOutput()
+			if tc.expectFail != (err != nil) {
+				t.Errorf("Description: %s: expected failure to be %v, got: %v\noutput: %s", tc.note, tc.expectFail, err, out)
+			}
+			if !strings.Contains(string(out), tc.expectInOutput) {
+				t.Errorf("Description: %s: expected output to contain %q\noutput: %s", tc.note, tc.expectInOutput, out)
+			}
+			content, err := os.ReadFile(filePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != tc.expectedFile {
+				t.Errorf("Description: %s: file: \n-expected:\n%v\n+got:\n%v\n", tc.note, tc.expectedFile, string(content))
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+/*
+Copyright © 2022 david amick git@davidamick.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// the values of the '--junit-group-by' flag
+const (
+	junitGroupByDirectory = "directory"
+	junitGroupByKind      = "kind"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+	elapsed  time.Duration
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	SystemOut *junitOutput  `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",cdata"`
+}
+
+type junitOutput struct {
+	Text string `xml:",cdata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+// junitFile is a checked file, a test case of the report
+type junitFile struct {
+	path    string
+	kinds   []string // kinds of its documents' schemas
+	elapsed time.Duration
+}
+
+// junitReporter writes the checked files as JUnit XML test cases, failing with the errors found in them.
+// Test suites group the files by directory or by the kinds of their documents.
+type junitReporter struct {
+	findingCollector
+	out     io.Writer
+	path    string // file to write the report to instead of out, for '--junit-report'
+	groupBy string
+	files   []junitFile
+}
+
+func (r *junitReporter) file(filePath string, kinds []string, elapsed time.Duration) {
+	r.files = append(r.files, junitFile{path: filePath, kinds: kinds, elapsed: elapsed})
+}
+
+func (r *junitReporter) finish(summary runSummary) {
+	findingsByFile := map[string][]finding{}
+	for _, f := range r.findings {
+		findingsByFile[f.File] = append(findingsByFile[f.File], f)
+	}
+
+	report := junitTestSuites{Name: "predictable-yaml"}
+	suiteIndexes := map[string]int{}
+	var elapsed time.Duration
+	for _, file := range r.files {
+		suiteName := r.suiteName(file)
+		index, ok := suiteIndexes[suiteName]
+		if !ok {
+			index = len(report.Suites)
+			suiteIndexes[suiteName] = index
+			report.Suites = append(report.Suites, junitTestSuite{Name: suiteName})
+		}
+		suite := &report.Suites[index]
+
+		testCase := junitTestCase{Name: file.path, ClassName: suiteName, Time: junitTime(file.elapsed)}
+		failures, output := []string{}, []string{}
+		for _, f := range findingsByFile[file.path] {
+			if f.Severity == severityError {
+				failures = append(failures, junitLine(f))
+			} else {
+				output = append(output, junitLine(f))
+			}
+		}
+		switch {
+		case len(failures) != 0:
+			testCase.Failure = &junitFailure{
+				Message: junitFailureMessage(len(failures)),
+				Type:    "predictable-yaml",
+				Text:    strings.Join(failures, "\n"),
+			}
+			suite.Failures++
+		case len(file.kinds) == 0:
+			testCase.Skipped = &junitSkipped{Message: "no document with a config"}
+			suite.Skipped++
+		}
+		if len(output) != 0 {
+			testCase.SystemOut = &junitOutput{Text: strings.Join(output, "\n")}
+		}
+
+		suite.Cases = append(suite.Cases, testCase)
+		suite.Tests++
+		suite.elapsed += file.elapsed
+		suite.Time = junitTime(suite.elapsed)
+		elapsed += file.elapsed
+	}
+	for _, suite := range report.Suites {
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Skipped += suite.Skipped
+	}
+	report.Time = junitTime(elapsed)
+
+	content, _ := xml.MarshalIndent(report, "", "  ")
+	content = append([]byte(xml.Header), content...)
+	content = append(content, '\n')
+	if r.path == "" {
+		_, _ = r.out.Write(content)
+		return
+	}
+	if err := os.WriteFile(r.path, content, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "error writing JUnit report '%s': %v\n", r.path, err)
+	}
+}
+
+// suiteName returns the name of the test suite of a file, its directory or the kinds of its documents
+func (r *junitReporter) suiteName(file junitFile) string {
+	if r.groupBy != junitGroupByKind {
+		return filepath.ToSlash(filepath.Dir(file.path))
+	}
+	if len(file.kinds) == 0 {
+		return "(no kind)"
+	}
+	kinds := []string{}
+	seen := map[string]bool{}
+	for _, kind := range file.kinds {
+		if !seen[kind] {
+			seen[kind] = true
+			kinds = append(kinds, kind)
+		}
+	}
+	sort.Strings(kinds)
+
+	return strings.Join(kinds, ", ")
+}
+
+// junitLine formats a finding as a line of a failure or of the output of a test case
+func junitLine(f finding) string {
+	location := f.File
+	if f.Line != 0 {
+		location = fmt.Sprintf("%s:%d:%d", f.File, f.Line, f.Column)
+	}
+	if f.Document != 0 {
+		location += fmt.Sprintf(" (document %d)", f.Document)
+	}
+	if f.Rule == "" {
+		return fmt.Sprintf("%s: %s", location, f.Message)
+	}
+
+	return fmt.Sprintf("%s: [%s] %s", location, f.Rule, f.Message)
+}
+
+// junitFailureMessage summarizes the failures of a test case
+func junitFailureMessage(count int) string {
+	if count == 1 {
+		return "1 violation"
+	}
+
+	return fmt.Sprintf("%d violations", count)
+}
+
+// junitTime formats a duration in seconds, as JUnit reports do
+func junitTime(elapsed time.Duration) string {
+	return fmt.Sprintf("%.3f", elapsed.Seconds())
+}
@@ -0,0 +1,98 @@
+/*
+Copyright © 2022 david amick git@davidamick.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/snarlysodboxer/predictable-yaml/pkg/compare"
+)
+
+func TestJUnitReporter(t *testing.T) {
+	type testCase struct {
+		note     string
+		groupBy  string
+		expected string
+	}
+
+	testCases := []testCase{
+		{
+			note:    "grouped by directory",
+			groupBy: junitGroupByDirectory,
+			expected: `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="predictable-yaml" tests="3" failures="1" skipped="1" time="1.750">
+  <testsuite name="apps" tests="2" failures="1" skipped="0" time="1.500">
+    <testcase name="apps/deployment.yaml" classname="apps" time="1.250">
+      <failure message="2 violations" type="predictable-yaml"><![CDATA[apps/deployment.yaml (document 1): validation error: example
+apps/deployment.yaml:7:3 (document 1): [first] '.metadata.name' (line 7): move to top]]></failure>
+      <system-out><![CDATA[apps/deployment.yaml:3:3 (document 1): [preferred] warning: example]]></system-out>
+    </testcase>
+    <testcase name="apps/service.yaml" classname="apps" time="0.250"></testcase>
+  </testsuite>
+  <testsuite name="notes" tests="1" failures="0" skipped="1" time="0.250">
+    <testcase name="notes/values.yaml" classname="notes" time="0.250">
+      <skipped message="no document with a config"></skipped>
+    </testcase>
+  </testsuite>
+</testsuites>
+`,
+		},
+		{
+			note:    "grouped by kind",
+			groupBy: junitGroupByKind,
+			expected: `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="predictable-yaml" tests="3" failures="1" skipped="1" time="1.750">
+  <testsuite name="Deployment, Service" tests="1" failures="1" skipped="0" time="1.250">
+    <testcase name="apps/deployment.yaml" classname="Deployment, Service" time="1.250">
+      <failure message="2 violations" type="predictable-yaml"><![CDATA[apps/deployment.yaml (document 1): validation error: example
+apps/deployment.yaml:7:3 (document 1): [first] '.metadata.name' (line 7): move to top]]></failure>
+      <system-out><![CDATA[apps/deployment.yaml:3:3 (document 1): [preferred] warning: example]]></system-out>
+    </testcase>
+  </testsuite>
+  <testsuite name="Service" tests="1" failures="0" skipped="0" time="0.250">
+    <testcase name="apps/service.yaml" classname="Service" time="0.250"></testcase>
+  </testsuite>
+  <testsuite name="(no kind)" tests="1" failures="0" skipped="1" time="0.250">
+    <testcase name="notes/values.yaml" classname="(no kind)" time="0.250">
+      <skipped message="no document with a config"></skipped>
+    </testcase>
+  </testsuite>
+</testsuites>
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		var buf bytes.Buffer
+		rep := &junitReporter{findingCollector: findingCollector{changeSeverity: severityError}, out: &buf, groupBy: tc.groupBy}
+		ref := docRef{file: "apps/deployment.yaml", name: "apps/deployment.yaml (document 1)", document: 1}
+		rep.errors(ref, "validation errors", compare.ValidationErrors{fmt.Errorf("validation error: example")})
+		rep.warnings(ref, compare.ValidationErrors{&compare.Diagnostic{Rule: compare.RulePreferred, Path: ".metadata", Line: 3, Column: 3, Message: "warning: example"}})
+		rep.changes(ref, []compare.Change{}, 0)
+		rep.findings = append(rep.findings, finding{File: ref.file, Document: 1, Path: ".metadata.name", Line: 7, Column: 3, Rule: compare.RuleFirst, Severity: severityError, Message: "'.metadata.name' (line 7): move to top", Change: &findingChange{Action: "move to top", Key: "name"}})
+		rep.file(ref.file, []string{"Service", "Deployment", "Service"}, 1250*time.Millisecond)
+		rep.file("apps/service.yaml", []string{"Service"}, 250*time.Millisecond)
+		rep.file("notes/values.yaml", []string{}, 250*time.Millisecond)
+		rep.finish(runSummary{Files: 3, Documents: 4})
+
+		if buf.String() != tc.expected {
+			t.Errorf("Description: %s: junitReporter: \n-expected:\n%v\n+got:\n%v\n", tc.note, tc.expected, buf.String())
+		}
+	}
+}
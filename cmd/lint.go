@@ -16,35 +16,36 @@ limitations under the License.
 package cmd
 
 import (
-	"errors"
 	"fmt"
 	"log"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/snarlysodboxer/predictable-yaml/pkg/compare"
-	"github.com/snarlysodboxer/predictable-yaml/pkg/moves"
+	"github.com/snarlysodboxer/predictable-yaml/pkg/whitespace"
 	"github.com/spf13/cobra"
 	"go.yaml.in/yaml/v3"
 )
 
+// flags
+var (
+	strict          bool
+	failOnWarnings  bool
+	outputFormat    string
+	junitReportPath string
+	junitGroupBy    string
+	showExcerpts    bool
+)
+
 // lintCmd represents the lint command
 var lintCmd = &cobra.Command{
 	Use:   "lint [flags] <file-or-dir-path> ...",
 	Short: "Lint YAML key order",
 	Long: `Compare YAML files to config files, checking for matching
-    key order, missing required keys, and first key in sequence or map.`,
-	Args: func(cmd *cobra.Command, args []string) error {
-		if len(args) < 1 {
-			return fmt.Errorf("requires file path argument(s)")
-		}
-		for _, arg := range args {
-			if _, err := os.Stat(arg); errors.Is(err, os.ErrNotExist) {
-				return fmt.Errorf("file '%s' doesn't exist: %v", arg, err)
-			}
-		}
-
-		return nil
-	},
+    key order, missing required keys, and first key in sequence or map.
+    A path of '-' reads a file from stdin.`,
+	Args: checkPathArgs,
 	Run: func(cmd *cobra.Command, filePaths []string) {
 		// setup
 		workDir, err := os.Getwd()
@@ -61,84 +62,219 @@ var lintCmd = &cobra.Command{
 		}
 		projectCfg, projectCfgDir := loadProjectConfig(workDir, homeDir)
 		configDirFlag := resolveConfigDir(projectCfg, projectCfgDir)
-		cfgNodesByPaths := getConfigNodesByPath(configDirFlag, workDir, homeDir, allFilePaths, projectCfg, projectCfgDir)
+		if projectCfg != nil {
+			l := projectCfg.Linter
+			if l.Strict != nil && !cmd.Flags().Changed("strict") {
+				strict = *l.Strict
+			}
+			if l.FailOnWarnings != nil && !cmd.Flags().Changed("fail-on-warnings") {
+				failOnWarnings = *l.FailOnWarnings
+			}
+		}
+		// suggested fixes are encoded like fix would
+		applyFixerConfig(cmd, projectCfg)
+		cfgNodesByPaths := getConfigNodesByPath(configDirFlag, workDir, homeDir, inputNames(allFilePaths), projectCfg, projectCfgDir)
+		order := documentOrder(projectCfg)
+		// in GitHub Actions, violations are annotated on pull requests unless another format is given
+		if !cmd.Flags().Changed("format") && os.Getenv("GITHUB_ACTIONS") == "true" {
+			outputFormat = formatGitHub
+		}
+		rep, err := commandReporter(os.Stdout, severityError)
+		if err != nil {
+			log.Fatal(err)
+		}
 
 		success := true
-		for _, filePath := range allFilePaths {
-			fNode := &yaml.Node{}
-			fileContents, err := getYAML(fNode, filePath)
+		warningCount := 0
+		documentCount := 0
+		for _, inputPath := range allFilePaths {
+			filePath := inputName(inputPath)
+			start := time.Now()
+			kinds := []string{}
+			_, docs, err := getDocuments(inputPath)
 			if err != nil {
 				log.Fatalf("error parsing yaml for target file: %s: %v", filePath, err)
 			}
-			fileNode := &compare.Node{Node: fNode}
-			compare.WalkConvertYamlNodeToMainNode(fileNode)
-			fileConfigs := compare.GetFileConfigs(fileNode)
-			if fileConfigs.Ignore {
-				continue
-			}
-			if fileConfigs.Kind == "" {
-				log.Printf("WARNING: unable to determine a schema for target file: %s", filePath)
-				continue
-			}
-
 			configNodes := configNodesForPath(cfgNodesByPaths, filePath)
-			configNode, ok := configNodes[fileConfigs.Kind]
-			if !ok {
-				log.Printf("WARNING: no config found for schema '%s' in file: %s", fileConfigs.Kind, filePath)
-				continue
-			}
+			for docIndex, doc := range docs {
+				ref := docRef{file: filePath, name: documentName(filePath, docIndex, len(docs)), document: docIndex + 1}
+				docName := ref.name
+				fNode := &yaml.Node{}
+				fileContents := doc.Padded()
+				err := yaml.Unmarshal(fileContents, fNode)
+				if err != nil {
+					log.Fatalf("error parsing yaml for target file: %s: %v", docName, err)
+				}
+				if len(fNode.Content) == 0 && len(docs) > 1 {
+					continue
+				}
+				fileNode := &compare.Node{Node: fNode}
+				compare.WalkConvertYamlNodeToMainNode(fileNode)
+				fileConfigs := compare.GetFileConfigs(fileNode)
+				if fileConfigs.Ignore {
+					continue
+				}
+				documentCount++
+				if fileConfigs.Kind == "" {
+					warningCount++
+					rep.warnings(ref, compare.ValidationErrors{&compare.Diagnostic{Rule: compare.RuleSchema, Message: "warning: unable to determine a schema"}})
+					continue
+				}
+				kinds = append(kinds, fileConfigs.Kind)
 
-			// pre-flight null value check
-			addedFields := []compare.AddedField{}
-			sortConfigs := compare.SortConfigs{
-				ConfigNodes: configNodes,
-				FileConfigs: fileConfigs,
-				AddedFields: &addedFields,
-			}
-			nullErrs := compare.WalkFindNullValues(configNode, fileNode, sortConfigs, compare.ValidationErrors{})
-			if len(nullErrs) != 0 {
-				success = false
-				log.Printf("File '%s' has validation errors:\n%v", filePath, compare.GetValidationErrorStrings(nullErrs))
-				continue
-			}
+				configNode, configName, usedFallback, ok := findConfigNode(configNodes, fileConfigs, projectCfg)
+				if !ok {
+					warningCount++
+					rep.warnings(ref, compare.ValidationErrors{&compare.Diagnostic{Rule: compare.RuleSchema, Message: fmt.Sprintf("warning: no config found for schema '%s'", fileConfigs.SchemaName())}})
+					continue
+				}
+				if usedFallback {
+					log.Printf("No config found for schema '%s', using fallback config '%s' for file: %s", fileConfigs.SchemaName(), configName, docName)
+				}
 
-			// sort to detect what would change
-			errs, changed := compare.WalkAndSort(configNode, fileNode, sortConfigs, compare.ValidationErrors{})
-			if len(errs) != 0 {
-				success = false
-				log.Printf("File '%s' has errors:\n%v", filePath, compare.GetValidationErrorStrings(errs))
-				continue
-			}
+				// pre-flight null value check
+				changes := []compare.Change{}
+				warnings := compare.FileConfigWarnings(fileNode)
+				sortConfigs := compare.SortConfigs{
+					ConfigNodes: configNodes,
+					FileConfigs: fileConfigs,
+					Changes:     &changes,
+					Warnings:    &warnings,
+				}
+				nullErrs := compare.WalkFindNullValues(configNode, fileNode, sortConfigs, compare.ValidationErrors{})
+				if len(nullErrs) != 0 {
+					success = false
+					rep.errors(ref, "validation errors", nullErrs)
+					if len(warnings) != 0 {
+						warningCount += len(warnings)
+						rep.warnings(ref, warnings)
+					}
+					continue
+				}
 
-			if changed || len(addedFields) > 0 {
-				success = false
+				// strict mode: keys not in the config are errors
+				if strict {
+					unknownErrs := compare.WalkFindUnknownKeys(configNode, fileNode, sortConfigs, compare.ValidationErrors{})
+					if len(unknownErrs) != 0 {
+						success = false
+						rep.errors(ref, "validation errors", unknownErrs)
+					}
+				}
+
+				// keys marked 'equals' or 'subset-of' must match the values at their paths
+				relationErrs := compare.WalkFindRelationErrors(configNode, fileNode, sortConfigs, compare.ValidationErrors{})
+				if len(relationErrs) != 0 {
+					success = false
+					rep.errors(ref, "validation errors", relationErrs)
+				}
+
+				// maps must have exactly one key of each 'one-of' group, and at least one of each 'any-of' group
+				groupErrs := compare.WalkFindGroupErrors(configNode, fileNode, sortConfigs, compare.ValidationErrors{})
+				if len(groupErrs) != 0 {
+					success = false
+					rep.errors(ref, "validation errors", groupErrs)
+				}
+
+				// empty maps and sequences of keys marked 'omit-empty' must be removed
+				emptyErrs := compare.WalkFindEmptyValues(configNode, fileNode, sortConfigs, compare.ValidationErrors{})
+				if len(emptyErrs) != 0 {
+					success = false
+					rep.errors(ref, "validation errors", emptyErrs)
+				}
+
+				// maps and sequences must have their config key's style
+				styleErrs := compare.WalkFindStyleErrors(configNode, fileNode, sortConfigs, compare.ValidationErrors{})
+				if len(styleErrs) != 0 {
+					success = false
+					rep.errors(ref, "validation errors", styleErrs)
+				}
 
-				// unmarshal a second copy from the bytes we already have
-				oldFileNode, err := parseNodeFromBytes(fileContents)
+				// keys marked 'blank-before' or 'no-blank' must be spaced accordingly
+				spacingErrs, err := whitespace.CheckBlankLines(fileContents, configNode, sortConfigs)
 				if err != nil {
-					log.Fatalf("error parsing yaml for target file: %s: %v", filePath, err)
+					log.Fatalf("error parsing yaml for target file: %s: %v", docName, err)
+				}
+				if len(spacingErrs) != 0 {
+					success = false
+					rep.errors(ref, "validation errors", spacingErrs)
+				}
+
+				// sort to detect what would change
+				errs, changed := compare.WalkAndSort(configNode, fileNode, sortConfigs, compare.ValidationErrors{})
+				if len(errs) != 0 {
+					success = false
+					rep.errors(ref, "errors", errs)
+				}
+				if len(warnings) != 0 {
+					warningCount += len(warnings)
+					rep.warnings(ref, warnings)
+				}
+				if len(errs) != 0 {
+					continue
+				}
+
+				if changed || len(changes) > 0 {
+					success = false
+					rep.changes(ref, changes, 0)
 				}
 
-				descriptions := moves.ComputeDescriptions(oldFileNode, fileNode)
-				summary := moves.FormatSummary(filePath, descriptions, addedFields, 0)
-				if summary == "" {
-					summary = fmt.Sprintf("File: %s\n\n  Changes:\n    (keys reordered)\n", filePath)
+				// suggest the fixed document, for reporters that offer fixes
+				if suggester, ok := rep.(fixSuggester); ok {
+					fixConfigs := sortConfigs
+					fixConfigs.Changes, fixConfigs.Warnings = nil, nil
+					compare.WalkFixStyles(configNode, fileNode, fixConfigs)
+					compare.WalkRemoveEmptyValues(configNode, fileNode, fixConfigs)
+					fixed, err := encodeDocument(configNode, fileNode, fixConfigs, []byte(doc.Body))
+					if err != nil {
+						log.Printf("unable to suggest a fix for file '%s': %v", docName, err)
+					} else {
+						suggester.suggestFix(ref, doc, string(fixed))
+					}
 				}
-				fmt.Print("\n" + summary + "\n")
 			}
+
+			// documents must be in the order of the project's policy
+			if order != nil {
+				if err := order.Check(docs); err != nil {
+					success = false
+					rep.errors(docRef{file: filePath, name: filePath}, "validation errors", compare.ValidationErrors{&compare.Diagnostic{Rule: compare.RuleDocumentOrder, Message: err.Error()}})
+				}
+			}
+			rep.file(filePath, kinds, time.Since(start))
 		}
 
-		if !success {
-			log.Fatal("FAIL")
+		failed := !success || (failOnWarnings && warningCount != 0)
+		rep.finish(runSummary{Files: len(allFilePaths), Documents: documentCount, Success: !failed})
+		if failed {
+			log.Fatal("FAIL" + warningCountSuffix(warningCount))
 		}
 
 		if !quiet {
-			log.Println("SUCCESS")
+			log.Println("SUCCESS" + warningCountSuffix(warningCount))
 		}
 	},
 }
 
+// warningCountSuffix returns the warning count for the final SUCCESS/FAIL line
+func warningCountSuffix(count int) string {
+	switch count {
+	case 0:
+		return ""
+	case 1:
+		return " (1 warning)"
+	}
+
+	return fmt.Sprintf(" (%d warnings)", count)
+}
+
 func init() {
 	rootCmd.AddCommand(lintCmd)
 	lintCmd.PersistentFlags().BoolVar(&quiet, "quiet", false, "shush success messages")
+	lintCmd.PersistentFlags().BoolVar(&strict, "strict", false, "fail on keys that are not in the config, unless under a key marked 'open'")
+	lintCmd.PersistentFlags().BoolVar(&failOnWarnings, "fail-on-warnings", false, "fail when there are warnings, such as missing preferred keys")
+	lintCmd.PersistentFlags().StringVar(&outputFormat, "format", formatText, "format of reported errors, warnings and changes: "+strings.Join(outputFormats, ", "))
+	lintCmd.PersistentFlags().StringVar(&junitReportPath, "junit-report", "", "also write a JUnit XML report to this path")
+	lintCmd.PersistentFlags().StringVar(&junitGroupBy, "junit-group-by", junitGroupByDirectory, "group JUnit test suites by file 'directory' or document 'kind'")
+	lintCmd.PersistentFlags().BoolVar(&showExcerpts, "excerpts", false, "show the source lines of errors, warnings and changes in the text format")
+	lintCmd.PersistentFlags().StringVar(&stdinFilename, "stdin-filename", "", "name of the file read from stdin ('-'), to resolve configs and report by")
 }
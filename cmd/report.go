@@ -0,0 +1,448 @@
+/*
+Copyright © 2022 david amick git@davidamick.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/snarlysodboxer/predictable-yaml/pkg/compare"
+	"github.com/snarlysodboxer/predictable-yaml/pkg/documents"
+	"github.com/snarlysodboxer/predictable-yaml/pkg/moves"
+)
+
+// the values of the '--format' flag
+const (
+	formatText   = "text"
+	formatJSON   = "json"
+	formatSARIF  = "sarif"
+	formatJUnit  = "junit"
+	formatGitHub = "github"
+	formatGitLab = "gitlab-codequality"
+)
+
+var outputFormats = []string{formatText, formatJSON, formatSARIF, formatJUnit, formatGitHub, formatGitLab}
+
+// the severities of findings
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+	severityInfo    = "info" // changes fix has made
+)
+
+// docRef identifies a document of a target file in reports
+type docRef struct {
+	file     string // path of the file
+	name     string // display name, see documentName
+	document int    // index of the document in the file, starting at 1, or 0 for the whole file
+}
+
+// finding is a validation error, warning, or change in a target file
+type finding struct {
+	File     string         `json:"file"`
+	Document int            `json:"document,omitempty"`
+	Path     string         `json:"path,omitempty"`
+	Line     int            `json:"line,omitempty"`
+	Column   int            `json:"column,omitempty"`
+	Rule     string         `json:"rule,omitempty"`
+	Severity string         `json:"severity"`
+	Message  string         `json:"message"`
+	Change   *findingChange `json:"change,omitempty"`
+}
+
+// findingChange is the change that fixes, or fixed, a finding
+type findingChange struct {
+	Action    string `json:"action"` // e.g. "move up", "add", "rename from nmae", "copy from .metadata.labels"
+	Key       string `json:"key,omitempty"`
+	Value     string `json:"value,omitempty"`     // scalar value of the key, if any
+	After     string `json:"after,omitempty"`     // key it comes after once fixed, for moves and additions
+	AfterLine int    `json:"afterLine,omitempty"` // line of the After key, unless it's added too
+}
+
+// runSummary is the outcome of a lint or fix run
+type runSummary struct {
+	Files     int  `json:"files"`
+	Documents int  `json:"documents"`
+	Errors    int  `json:"errors"`
+	Warnings  int  `json:"warnings"`
+	Changes   int  `json:"changes"`
+	Success   bool `json:"success"`
+}
+
+// reporter presents what lint and fix find in target files. Reports go to stdout,
+// while progress and problems with the run itself are logged to stderr.
+type reporter interface {
+	// errors reports errors in a document, under a heading like "validation errors"
+	errors(ref docRef, heading string, errs compare.ValidationErrors)
+	// warnings reports warnings in a document
+	warnings(ref docRef, warnings compare.ValidationErrors)
+	// changes reports what sorting a document would change, or has changed
+	changes(ref docRef, changes []compare.Change, commentCount int)
+	// reordered reports the documents of a file being reordered to the project's policy
+	reordered(filePath string, indexes []int)
+	// file reports that a file has been checked, with the kinds of its documents and how long it took
+	file(filePath string, kinds []string, elapsed time.Duration)
+	// finish reports the outcome of the run
+	finish(summary runSummary)
+}
+
+// newReporter returns the reporter for a '--format' value. Changes are reported with changeSeverity,
+// errors for lint and info for fix.
+func newReporter(format string, out io.Writer, changeSeverity string) (reporter, error) {
+	switch format {
+	case formatText:
+		return &textReporter{out: out, excerpts: showExcerpts, sources: sourceLines{}}, nil
+	case formatJSON:
+		return &jsonReporter{findingCollector: findingCollector{changeSeverity: changeSeverity}, out: out}, nil
+	case formatSARIF:
+		return &sarifReporter{findingCollector: findingCollector{changeSeverity: changeSeverity}, out: out, fixes: map[docRef]sarifFix{}}, nil
+	case formatJUnit:
+		return &junitReporter{findingCollector: findingCollector{changeSeverity: changeSeverity}, out: out, groupBy: junitGroupBy}, nil
+	case formatGitHub:
+		return &githubReporter{findingCollector: findingCollector{changeSeverity: changeSeverity}, out: out}, nil
+	case formatGitLab:
+		return &gitlabReporter{findingCollector: findingCollector{changeSeverity: changeSeverity}, out: out}, nil
+	}
+
+	return nil, fmt.Errorf("unknown format '%s', expected one of: %s", format, strings.Join(outputFormats, ", "))
+}
+
+// commandReporter returns the reporter for the '--format' flag, writing to out, and also writing a JUnit
+// report to the '--junit-report' path when it's set
+func commandReporter(out io.Writer, changeSeverity string) (reporter, error) {
+	if junitGroupBy != junitGroupByDirectory && junitGroupBy != junitGroupByKind {
+		return nil, fmt.Errorf("unknown JUnit grouping '%s', expected one of: %s, %s", junitGroupBy, junitGroupByDirectory, junitGroupByKind)
+	}
+	rep, err := newReporter(outputFormat, out, changeSeverity)
+	if err != nil || junitReportPath == "" {
+		return rep, err
+	}
+	junit := &junitReporter{findingCollector: findingCollector{changeSeverity: changeSeverity}, path: junitReportPath, groupBy: junitGroupBy}
+
+	return multiReporter{rep, junit}, nil
+}
+
+// multiReporter reports to each of its reporters
+type multiReporter []reporter
+
+func (m multiReporter) errors(ref docRef, heading string, errs compare.ValidationErrors) {
+	for _, r := range m {
+		r.errors(ref, heading, errs)
+	}
+}
+
+func (m multiReporter) warnings(ref docRef, warnings compare.ValidationErrors) {
+	for _, r := range m {
+		r.warnings(ref, warnings)
+	}
+}
+
+func (m multiReporter) changes(ref docRef, changes []compare.Change, commentCount int) {
+	for _, r := range m {
+		r.changes(ref, changes, commentCount)
+	}
+}
+
+func (m multiReporter) reordered(filePath string, indexes []int) {
+	for _, r := range m {
+		r.reordered(filePath, indexes)
+	}
+}
+
+func (m multiReporter) file(filePath string, kinds []string, elapsed time.Duration) {
+	for _, r := range m {
+		r.file(filePath, kinds, elapsed)
+	}
+}
+
+func (m multiReporter) finish(summary runSummary) {
+	for _, r := range m {
+		r.finish(summary)
+	}
+}
+
+// suggestFix passes fixes on to the reporters that offer them
+func (m multiReporter) suggestFix(ref docRef, doc documents.Document, fixed string) {
+	for _, r := range m {
+		if suggester, ok := r.(fixSuggester); ok {
+			suggester.suggestFix(ref, doc, fixed)
+		}
+	}
+}
+
+// textReporter reports in the human-readable format of the change summaries
+type textReporter struct {
+	out      io.Writer
+	excerpts bool        // show code frames of the keys reported
+	sources  sourceLines // lines of target files, for code frames
+	lastErrs *docRef     // document whose errors were reported last, if nothing was reported since
+}
+
+// errors reports errors under one heading per document, so errors reported right after
+// others of the same document are listed under the heading already printed
+func (r *textReporter) errors(ref docRef, heading string, errs compare.ValidationErrors) {
+	if r.lastErrs == nil || *r.lastErrs != ref {
+		fmt.Fprintf(r.out, "File '%s' has %s:\n", ref.name, heading)
+	}
+	r.printErrors(ref, severityError, errs)
+	r.lastErrs = &ref
+}
+
+func (r *textReporter) warnings(ref docRef, warnings compare.ValidationErrors) {
+	fmt.Fprintf(r.out, "File '%s' has warnings:\n", ref.name)
+	r.printErrors(ref, severityWarning, warnings)
+	r.lastErrs = nil
+}
+
+// printErrors prints errors or warnings a line each, prefixed with their position
+func (r *textReporter) printErrors(ref docRef, severity string, errs compare.ValidationErrors) {
+	for _, f := range errorFindings(ref, severity, errs) {
+		fmt.Fprintf(r.out, "\t%s\n", textLine(f))
+		if r.excerpts && f.Line != 0 {
+			fmt.Fprint(r.out, indentLines(codeFrame(r.sources.lines(f.File), f.Line, f.Column, "", 0, ""), "\t"))
+		}
+	}
+}
+
+func (r *textReporter) changes(ref docRef, changes []compare.Change, commentCount int) {
+	r.lastErrs = nil
+	summary := moves.FormatSummary(ref.name, ref.file, changes, commentCount)
+	if summary == "" {
+		summary = fmt.Sprintf("File: %s\n\n  Changes:\n    (keys reordered)\n", ref.name)
+	}
+	fmt.Fprint(r.out, "\n"+summary+"\n")
+	if !r.excerpts {
+		return
+	}
+	for _, f := range changeFindings(ref, "", changes) {
+		if f.Line == 0 {
+			continue
+		}
+		lines := r.sources.lines(f.File)
+		frame := ""
+		switch {
+		case f.Change.Action == "add" && f.Change.AfterLine != 0:
+			// additions have the position of their map, so show the key they're added after
+			frame = codeFrame(lines, f.Change.AfterLine, 0, fmt.Sprintf("add '%s' after this", f.Change.Key), 0, "")
+		case f.Change.Action == "add" && f.Change.Key != "":
+			frame = codeFrame(lines, f.Line, f.Column, fmt.Sprintf("add '%s' to this map", f.Change.Key), 0, "")
+		case strings.HasPrefix(f.Change.Action, "copy from "):
+			// copied entries are appended to their map, so they have its position
+			frame = codeFrame(lines, f.Line, f.Column, fmt.Sprintf("copy '%s' to this map %s", f.Change.Key, strings.TrimPrefix(f.Change.Action, "copy ")), 0, "")
+		case f.Change.AfterLine != 0:
+			frame = codeFrame(lines, f.Line, f.Column, f.Change.Action, f.Change.AfterLine, fmt.Sprintf("'%s' comes after this", f.Change.Key))
+		case f.Change.After != "":
+			frame = codeFrame(lines, f.Line, f.Column, fmt.Sprintf("%s, after '%s'", f.Change.Action, f.Change.After), 0, "")
+		default:
+			frame = codeFrame(lines, f.Line, f.Column, f.Change.Action, 0, "")
+		}
+		fmt.Fprintf(r.out, "  %s\n%s\n", textLine(f), indentLines(frame, "  "))
+	}
+}
+
+func (r *textReporter) reordered(filePath string, indexes []int) {
+	r.lastErrs = nil
+	fmt.Fprint(r.out, "\n"+formatReorderSummary(filePath, indexes)+"\n")
+}
+
+func (r *textReporter) file(filePath string, kinds []string, elapsed time.Duration) {}
+
+func (r *textReporter) finish(summary runSummary) {}
+
+// textLine formats a finding as a line of text, prefixed with its 'file:line:column' if it has a position
+func textLine(f finding) string {
+	if f.Line == 0 {
+		return f.Message
+	}
+
+	return fmt.Sprintf("%s:%d:%d: %s", f.File, f.Line, f.Column, f.Message)
+}
+
+// findingCollector collects the findings of a run, for reporters that write them all at the end
+type findingCollector struct {
+	changeSeverity string
+	findings       []finding
+}
+
+func (c *findingCollector) errors(ref docRef, heading string, errs compare.ValidationErrors) {
+	c.findings = append(c.findings, errorFindings(ref, severityError, errs)...)
+}
+
+func (c *findingCollector) warnings(ref docRef, warnings compare.ValidationErrors) {
+	c.findings = append(c.findings, errorFindings(ref, severityWarning, warnings)...)
+}
+
+func (c *findingCollector) changes(ref docRef, changes []compare.Change, commentCount int) {
+	c.findings = append(c.findings, changeFindings(ref, c.changeSeverity, changes)...)
+}
+
+func (c *findingCollector) reordered(filePath string, indexes []int) {
+	c.findings = append(c.findings, reorderFindings(filePath, c.changeSeverity, indexes)...)
+}
+
+func (c *findingCollector) file(filePath string, kinds []string, elapsed time.Duration) {}
+
+// jsonReporter writes the findings and the run summary as one JSON object
+type jsonReporter struct {
+	findingCollector
+	out io.Writer
+}
+
+func (r *jsonReporter) finish(summary runSummary) {
+	summary.Errors, summary.Warnings, summary.Changes = countFindings(r.findings)
+	findings := r.findings
+	if findings == nil {
+		findings = []finding{}
+	}
+	encoder := json.NewEncoder(r.out)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(struct {
+		Findings []finding  `json:"findings"`
+		Summary  runSummary `json:"summary"`
+	}{findings, summary})
+}
+
+// errorFindings returns findings for errors or warnings, with the rule and position of those that are Diagnostics
+func errorFindings(ref docRef, severity string, errs compare.ValidationErrors) []finding {
+	findings := make([]finding, 0, len(errs))
+	for _, err := range errs {
+		f := finding{
+			File:     ref.file,
+			Document: ref.document,
+			Severity: severity,
+			Message:  err.Error(),
+		}
+		var diagnostic *compare.Diagnostic
+		if errors.As(err, &diagnostic) {
+			f.Rule = diagnostic.Rule
+			f.Path = diagnostic.Path
+			f.Line = diagnostic.Line
+			f.Column = diagnostic.Column
+		}
+		findings = append(findings, f)
+	}
+
+	return findings
+}
+
+// changeFindings returns findings for the keys a change summary shows: those moved up, plus the added,
+// renamed, restyled and removed keys
+func changeFindings(ref docRef, severity string, changes []compare.Change) []finding {
+	// moves are described by key, so look up the rule each was made for
+	rules := map[string]string{}
+	for _, change := range changes {
+		rules[change.Path+"."+change.Key] = change.Rule
+	}
+
+	findings := []finding{}
+	for _, description := range moves.DescribeChanges(changes) {
+		for _, key := range description.Keys {
+			path := strings.TrimSuffix("."+description.Path, ".") + "." + key.Key
+			findings = append(findings, finding{
+				File:     ref.file,
+				Document: ref.document,
+				Path:     path,
+				Line:     key.Line,
+				Column:   key.Column,
+				Rule:     rules[path],
+				Severity: severity,
+				Message:  changeMessage(path, key.Line, description.Action),
+				Change:   &findingChange{Action: description.Action, Key: key.Key, Value: key.Value, After: key.After, AfterLine: key.AfterLine},
+			})
+		}
+	}
+	for _, change := range moves.Additions(changes) {
+		path := change.Path + "." + change.Key
+		value := change.Value
+		switch change.Type {
+		case compare.SequenceItemAdded:
+			path = fmt.Sprintf("%s[%d]", change.Path, change.To)
+		case compare.StyleChanged:
+			value = ""
+		}
+		action := moves.Action(change)
+		after, afterLine := "", 0
+		if change.After != nil {
+			after = change.After.Value
+			afterLine, _ = moves.AfterPosition(change)
+		}
+		findings = append(findings, finding{
+			File:     ref.file,
+			Document: ref.document,
+			Path:     path,
+			Line:     change.Line,
+			Column:   change.Column,
+			Rule:     change.Rule,
+			Severity: severity,
+			Message:  changeMessage(path, change.Line, action),
+			Change:   &findingChange{Action: action, Key: change.Key, Value: value, After: after, AfterLine: afterLine},
+		})
+	}
+
+	return findings
+}
+
+// changeMessage describes a change to the key or item at a path
+func changeMessage(path string, line int, action string) string {
+	if line == 0 {
+		return fmt.Sprintf("'%s': %s", path, action)
+	}
+
+	return fmt.Sprintf("'%s' (line %d): %s", path, line, action)
+}
+
+// reorderFindings returns a finding for each document of a file that moves, given the indexes of documents.Order.Sort
+func reorderFindings(filePath, severity string, indexes []int) []finding {
+	findings := []finding{}
+	for position, index := range indexes {
+		if position == index {
+			continue
+		}
+		action := fmt.Sprintf("move to %d", position+1)
+		findings = append(findings, finding{
+			File:     filePath,
+			Document: index + 1,
+			Rule:     compare.RuleDocumentOrder,
+			Severity: severity,
+			Message:  fmt.Sprintf("document %d: %s", index+1, action),
+			Change:   &findingChange{Action: action},
+		})
+	}
+
+	return findings
+}
+
+// countFindings returns the numbers of errors, warnings, and changes among findings
+func countFindings(findings []finding) (int, int, int) {
+	errorCount, warningCount, changeCount := 0, 0, 0
+	for _, f := range findings {
+		switch {
+		case f.Change != nil:
+			changeCount++
+		case f.Severity == severityWarning:
+			warningCount++
+		default:
+			errorCount++
+		}
+	}
+
+	return errorCount, warningCount, changeCount
+}
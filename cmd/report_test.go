@@ -0,0 +1,172 @@
+/*
+Copyright © 2022 david amick git@davidamick.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/snarlysodboxer/predictable-yaml/pkg/compare"
+)
+
+func TestErrorFindings(t *testing.T) {
+	ref := docRef{file: "bundle.yaml", name: "bundle.yaml (document 2)", document: 2}
+
+	type testCase struct {
+		note     string
+		errs     compare.ValidationErrors
+		expected []finding
+	}
+
+	testCases := []testCase{
+		{
+			note: "diagnostics have a rule and position",
+			errs: compare.ValidationErrors{
+				&compare.Diagnostic{Rule: compare.RuleStyle, Path: ".spec.ports", Line: 9, Column: 3, Message: "validation error: '.spec.ports' (line 9) should be block style"},
+			},
+			expected: []finding{
+				{File: "bundle.yaml", Document: 2, Path: ".spec.ports", Line: 9, Column: 3, Rule: compare.RuleStyle, Severity: severityError, Message: "validation error: '.spec.ports' (line 9) should be block style"},
+			},
+		},
+		{
+			note: "other errors have only a message",
+			errs: compare.ValidationErrors{
+				fmt.Errorf("program error: expected Map: '.spec'"),
+			},
+			expected: []finding{
+				{File: "bundle.yaml", Document: 2, Severity: severityError, Message: "program error: expected Map: '.spec'"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		got := errorFindings(ref, severityError, tc.errs)
+		if !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("Description: %s: errorFindings(...): \n-expected:\n%+v\n+got:\n%+v\n", tc.note, tc.expected, got)
+		}
+	}
+}
+
+func TestReorderFindings(t *testing.T) {
+	expected := []finding{
+		{File: "bundle.yaml", Document: 3, Rule: compare.RuleDocumentOrder, Severity: severityInfo, Message: "document 3: move to 1", Change: &findingChange{Action: "move to 1"}},
+		{File: "bundle.yaml", Document: 1, Rule: compare.RuleDocumentOrder, Severity: severityInfo, Message: "document 1: move to 2", Change: &findingChange{Action: "move to 2"}},
+		{File: "bundle.yaml", Document: 2, Rule: compare.RuleDocumentOrder, Severity: severityInfo, Message: "document 2: move to 3", Change: &findingChange{Action: "move to 3"}},
+	}
+	got := reorderFindings("bundle.yaml", severityInfo, []int{2, 0, 1})
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("reorderFindings(...): \n-expected:\n%+v\n+got:\n%+v\n", expected, got)
+	}
+}
+
+func TestJSONReporter(t *testing.T) {
+	var buf bytes.Buffer
+	rep, err := newReporter(formatJSON, &buf, severityError)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref := docRef{file: "service.yaml", name: "service.yaml", document: 1}
+	rep.errors(ref, "validation errors", compare.ValidationErrors{fmt.Errorf("validation error: example")})
+	rep.warnings(ref, compare.ValidationErrors{&compare.Diagnostic{Rule: compare.RulePreferred, Path: ".metadata", Line: 3, Column: 3, Message: "warning: example"}})
+	rep.finish(runSummary{Files: 1, Documents: 1})
+
+	expected := `{
+  "findings": [
+    {
+      "file": "service.yaml",
+      "document": 1,
+      "severity": "error",
+      "message": "validation error: example"
+    },
+    {
+      "file": "service.yaml",
+      "document": 1,
+      "path": ".metadata",
+      "line": 3,
+      "column": 3,
+      "rule": "preferred",
+      "severity": "warning",
+      "message": "warning: example"
+    }
+  ],
+  "summary": {
+    "files": 1,
+    "documents": 1,
+    "errors": 1,
+    "warnings": 1,
+    "changes": 0,
+    "success": false
+  }
+}
+`
+	if buf.String() != expected {
+		t.Errorf("jsonReporter: \n-expected:\n%v\n+got:\n%v\n", expected, buf.String())
+	}
+
+	if _, err := newReporter("xml", &buf, severityError); err == nil {
+		t.Errorf("newReporter(\"xml\", ...): expected an error")
+	}
+}
+
+func TestTextReporter(t *testing.T) {
+	var buf bytes.Buffer
+	rep := &textReporter{out: &buf, excerpts: true, sources: sourceLines{
+		"service.yaml": {"apiVersion: v1", "kind: Service", "spec:", "  ports: [80]"},
+	}}
+	ref := docRef{file: "service.yaml", name: "service.yaml", document: 1}
+	rep.errors(ref, "validation errors", compare.ValidationErrors{
+		fmt.Errorf("validation error: example"),
+		&compare.Diagnostic{Rule: compare.RuleStyle, Path: ".spec.ports", Line: 4, Column: 3, Message: "validation error: '.spec.ports' (line 4) should be block style"},
+	})
+
+	expected := `File 'service.yaml' has validation errors:
+	validation error: example
+	service.yaml:4:3: validation error: '.spec.ports' (line 4) should be block style
+	4 |   ports: [80]
+	  |   ^^^^^
+`
+	if buf.String() != expected {
+		t.Errorf("textReporter: \n-expected:\n%v\n+got:\n%v\n", expected, buf.String())
+	}
+}
+
+func TestTextReporterGroupsErrors(t *testing.T) {
+	var buf bytes.Buffer
+	rep := &textReporter{out: &buf, sources: sourceLines{}}
+	first := docRef{file: "bundle.yaml", name: "bundle.yaml (document 1)", document: 1}
+	second := docRef{file: "bundle.yaml", name: "bundle.yaml (document 2)", document: 2}
+	rep.errors(first, "validation errors", compare.ValidationErrors{fmt.Errorf("validation error: style")})
+	rep.errors(first, "validation errors", compare.ValidationErrors{fmt.Errorf("validation error: spacing")})
+	rep.errors(second, "validation errors", compare.ValidationErrors{fmt.Errorf("validation error: style")})
+	rep.warnings(second, compare.ValidationErrors{fmt.Errorf("warning: example")})
+	rep.errors(second, "errors", compare.ValidationErrors{fmt.Errorf("error: example")})
+
+	expected := `File 'bundle.yaml (document 1)' has validation errors:
+	validation error: style
+	validation error: spacing
+File 'bundle.yaml (document 2)' has validation errors:
+	validation error: style
+File 'bundle.yaml (document 2)' has warnings:
+	warning: example
+File 'bundle.yaml (document 2)' has errors:
+	error: example
+`
+	if buf.String() != expected {
+		t.Errorf("textReporter: \n-expected:\n%v\n+got:\n%v\n", expected, buf.String())
+	}
+}
@@ -27,6 +27,7 @@ import (
 
 	"github.com/snarlysodboxer/predictable-yaml/internal/embedded"
 	"github.com/snarlysodboxer/predictable-yaml/pkg/compare"
+	"github.com/snarlysodboxer/predictable-yaml/pkg/documents"
 	"github.com/snarlysodboxer/predictable-yaml/pkg/remote"
 	"github.com/spf13/cobra"
 	"go.yaml.in/yaml/v3"
@@ -35,6 +36,10 @@ import (
 const configDirName = ".predictable-yaml"
 const projectConfigFileName = ".predictable-yaml.yaml"
 
+// fallbackConfigName is the name of the config used for kinds without a config,
+// loaded from a '_default.yaml' config file unless 'fallback-kind' is set in the project config.
+const fallbackConfigName = "_default"
+
 var (
 	cfgDir         string
 	cfgFile        string
@@ -118,9 +123,7 @@ func getConfigNodesByPath(configDirFlag, workDir, homeDir string, filePaths []st
 		remoteNodes := loadRemoteConfigNodesFromCache(cachePath)
 		if remoteNodes != nil {
 			hasRemoteConfig = true
-			for kind, node := range remoteNodes {
-				configNodes[kind] = node
-			}
+			mergeConfigNodes(configNodes, remoteNodes, nil)
 		}
 
 		// Warn if .predictable-yaml/.remote also exists
@@ -138,9 +141,7 @@ func getConfigNodesByPath(configDirFlag, workDir, homeDir string, filePaths []st
 			remoteNodes := loadRemoteConfigNodes(dir)
 			if remoteNodes != nil {
 				hasRemoteConfig = true
-				for kind, node := range remoteNodes {
-					configNodes[kind] = node
-				}
+				mergeConfigNodes(configNodes, remoteNodes, nil)
 			}
 		}
 	}
@@ -148,17 +149,18 @@ func getConfigNodesByPath(configDirFlag, workDir, homeDir string, filePaths []st
 	// If no remote config and no local configs found, try embedded defaults
 	if !hasRemoteConfig && len(configDirs) == 0 {
 		embeddedNodes := loadEmbeddedConfigNodes()
-		for kind, node := range embeddedNodes {
-			configNodes[kind] = node
-		}
+		mergeConfigNodes(configNodes, embeddedNodes, nil)
 	}
 
-	// Then, load local config files (override remote configs)
+	// Then, load local config files (override remote configs).
+	// Without a remote config, local configs can extend the embedded defaults.
+	var upstreamNodes compare.ConfigNodes
 	for _, dir := range configDirs {
 		localNodes := loadLocalConfigNodes(dir)
-		for kind, node := range localNodes {
-			configNodes[kind] = node
+		if upstreamNodes == nil && !hasRemoteConfig && hasExtendingConfigs(localNodes) {
+			upstreamNodes = loadEmbeddedConfigNodes()
 		}
+		mergeConfigNodes(configNodes, localNodes, upstreamNodes)
 	}
 
 	cfgNodesByPaths := []configNodesByPath{{path: "", ConfigNodes: configNodes}}
@@ -170,15 +172,11 @@ func getConfigNodesByPath(configDirFlag, workDir, homeDir string, filePaths []st
 
 		// Load remote configs for this override dir
 		remoteNodes := loadRemoteConfigNodes(dir)
-		for kind, node := range remoteNodes {
-			configNodes[kind] = node
-		}
+		mergeConfigNodes(configNodes, remoteNodes, cfgNodesByPaths[0].ConfigNodes)
 
-		// Load local config files (override remote)
+		// Load local config files (override remote), extending configs found here or at the root
 		localNodes := loadLocalConfigNodes(dir)
-		for kind, node := range localNodes {
-			configNodes[kind] = node
-		}
+		mergeConfigNodes(configNodes, localNodes, cfgNodesByPaths[0].ConfigNodes)
 
 		cfgNodesByPaths = append(cfgNodesByPaths, configNodesByPath{
 			path:        strings.ReplaceAll(dir, configDirName, ""),
@@ -193,6 +191,119 @@ func getConfigNodesByPath(configDirFlag, workDir, homeDir string, filePaths []st
 	return cfgNodesByPaths
 }
 
+// warnConfigDirectives logs unknown or malformed directives in a config file, check-configs reports them as errors
+func warnConfigDirectives(path string, configNode *compare.Node) {
+	if compare.IsSchemaFile(path) {
+		// the schema format has no directive comments of its own
+		return
+	}
+	for _, problem := range compare.CheckConfigDirectives(configNode) {
+		log.Printf("WARNING: %s:%d: %v", path, problem.Line, problem.Err)
+	}
+}
+
+// validateConfigNode validates a config file, unless it extends another config.
+// Extending configs are validated once merged, see mergeConfigNodes.
+func validateConfigNode(configNode *compare.Node) error {
+	if compare.GetFileConfigs(configNode).Extends != "" {
+		return nil
+	}
+
+	return compare.WalkAndValidateConfig(configNode)
+}
+
+// mergeConfigNodes adds newNodes to configNodes, replacing configs of the same name.
+// Configs marked 'extends=<name>' are merged onto the named config instead, which is looked up
+// in newNodes, then configNodes, then baseNodes. So a config can extend the config it replaces.
+func mergeConfigNodes(configNodes, newNodes, baseNodes compare.ConfigNodes) {
+	resolved := compare.ConfigNodes{}
+	for name := range newNodes {
+		_, err := resolveExtends(name, newNodes, configNodes, baseNodes, resolved, map[string]bool{})
+		if err != nil {
+			log.Fatalf("error extending config '%s': %v", name, err)
+		}
+	}
+	for name, node := range resolved {
+		configNodes[name] = node
+	}
+}
+
+// hasExtendingConfigs reports whether any of the configs extends another config
+func hasExtendingConfigs(configNodes compare.ConfigNodes) bool {
+	for _, node := range configNodes {
+		if compare.GetFileConfigs(node).Extends != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resolveExtends returns the config for name in newNodes, merged onto the config it extends, if any.
+func resolveExtends(name string, newNodes, configNodes, baseNodes, resolved compare.ConfigNodes, visiting map[string]bool) (*compare.Node, error) {
+	if node, ok := resolved[name]; ok {
+		return node, nil
+	}
+	node := newNodes[name]
+	extends := compare.GetFileConfigs(node).Extends
+	if extends == "" {
+		resolved[name] = node
+		return node, nil
+	}
+	if visiting[name] {
+		return nil, fmt.Errorf("configuration error: 'extends' cycle at config '%s'", name)
+	}
+	visiting[name] = true
+
+	var base *compare.Node
+	if _, ok := newNodes[extends]; ok && extends != name {
+		var err error
+		base, err = resolveExtends(extends, newNodes, configNodes, baseNodes, resolved, visiting)
+		if err != nil {
+			return nil, err
+		}
+	} else if configNode, ok := configNodes[extends]; ok {
+		base = configNode
+	} else if configNode, ok := baseNodes[extends]; ok {
+		base = configNode
+	} else {
+		return nil, fmt.Errorf("configuration error: no config '%s' to extend", extends)
+	}
+
+	merged, err := compare.MergeConfig(base, node)
+	if err != nil {
+		return nil, err
+	}
+	resolved[name] = merged
+
+	return merged, nil
+}
+
+// configNameForFile returns the name a config file is stored under,
+// the fallback config name for '_default.yaml' files, otherwise its (qualified) kind.
+func configNameForFile(fileName string, configNode *compare.Node) string {
+	if compare.TrimSchemaFileSuffix(fileName) == fallbackConfigName {
+		return fallbackConfigName
+	}
+
+	return compare.GetConfigName(configNode)
+}
+
+// findConfigNode returns the config for a target file's schema, or the fallback config if none matches.
+// The returned bool reports whether the fallback was used.
+func findConfigNode(configNodes compare.ConfigNodes, fileConfigs compare.FileConfigs, projectCfg *ProjectConfig) (*compare.Node, string, bool, bool) {
+	if configNode, configName, ok := configNodes.Find(fileConfigs); ok {
+		return configNode, configName, false, true
+	}
+	fallbackName := fallbackConfigName
+	if projectCfg != nil && projectCfg.FallbackKind != "" {
+		fallbackName = projectCfg.FallbackKind
+	}
+	configNode, ok := configNodes[fallbackName]
+
+	return configNode, fallbackName, true, ok
+}
+
 // loadLocalConfigNodes reads local YAML config files from a .predictable-yaml directory.
 func loadLocalConfigNodes(dir string) compare.ConfigNodes {
 	configNodes := compare.ConfigNodes{}
@@ -213,21 +324,22 @@ func loadLocalConfigNodes(dir string) compare.ConfigNodes {
 		}
 		cNode := &yaml.Node{}
 		path := fmt.Sprintf("%s/%s", dir, file.Name())
-		_, err := getYAML(cNode, path)
+		err := getConfigYAML(cNode, path)
 		if err != nil {
 			log.Fatalf("error parsing yaml for config file: %s: %v", path, err)
 		}
 		configNode := &compare.Node{Node: cNode}
 		compare.WalkConvertYamlNodeToMainNode(configNode)
 		compare.WalkParseLoadConfigComments(configNode)
-		if err := compare.WalkAndValidateConfig(configNode); err != nil {
+		warnConfigDirectives(path, configNode)
+		if err := validateConfigNode(configNode); err != nil {
 			log.Fatalf("error validating config file '%s': %v", path, err)
 		}
-		fileConfigs := compare.GetFileConfigs(configNode)
-		if fileConfigs.Kind == "" {
+		configName := configNameForFile(file.Name(), configNode)
+		if configName == "" {
 			log.Fatalf("error determining schema for config file: %s: %v", path, err)
 		}
-		configNodes[fileConfigs.Kind] = configNode
+		configNodes[configName] = configNode
 	}
 
 	return configNodes
@@ -269,21 +381,22 @@ func loadRemoteConfigNodes(configDir string) compare.ConfigNodes {
 		}
 		cNode := &yaml.Node{}
 		path := fmt.Sprintf("%s/%s", cachePath, file.Name())
-		_, err := getYAML(cNode, path)
+		err := getConfigYAML(cNode, path)
 		if err != nil {
 			log.Fatalf("error parsing yaml for cached config file: %s: %v", path, err)
 		}
 		configNode := &compare.Node{Node: cNode}
 		compare.WalkConvertYamlNodeToMainNode(configNode)
 		compare.WalkParseLoadConfigComments(configNode)
-		if err := compare.WalkAndValidateConfig(configNode); err != nil {
+		warnConfigDirectives(path, configNode)
+		if err := validateConfigNode(configNode); err != nil {
 			log.Fatalf("error validating cached config file '%s': %v", path, err)
 		}
-		fileConfigs := compare.GetFileConfigs(configNode)
-		if fileConfigs.Kind == "" {
+		configName := configNameForFile(file.Name(), configNode)
+		if configName == "" {
 			log.Fatalf("error determining schema for cached config file: %s: %v", path, err)
 		}
-		configNodes[fileConfigs.Kind] = configNode
+		configNodes[configName] = configNode
 	}
 
 	return configNodes
@@ -305,21 +418,22 @@ func loadRemoteConfigNodesFromCache(cachePath string) compare.ConfigNodes {
 		}
 		cNode := &yaml.Node{}
 		path := fmt.Sprintf("%s/%s", cachePath, file.Name())
-		_, err := getYAML(cNode, path)
+		err := getConfigYAML(cNode, path)
 		if err != nil {
 			log.Fatalf("error parsing yaml for cached config file: %s: %v", path, err)
 		}
 		configNode := &compare.Node{Node: cNode}
 		compare.WalkConvertYamlNodeToMainNode(configNode)
 		compare.WalkParseLoadConfigComments(configNode)
-		if err := compare.WalkAndValidateConfig(configNode); err != nil {
+		warnConfigDirectives(path, configNode)
+		if err := validateConfigNode(configNode); err != nil {
 			log.Fatalf("error validating cached config file '%s': %v", path, err)
 		}
-		fileConfigs := compare.GetFileConfigs(configNode)
-		if fileConfigs.Kind == "" {
+		configName := configNameForFile(file.Name(), configNode)
+		if configName == "" {
 			log.Fatalf("error determining schema for cached config file: %s: %v", path, err)
 		}
-		configNodes[fileConfigs.Kind] = configNode
+		configNodes[configName] = configNode
 	}
 
 	return configNodes
@@ -339,23 +453,24 @@ func loadEmbeddedConfigNodes() compare.ConfigNodes {
 			continue
 		}
 		cNode := &yaml.Node{}
-		if err := yaml.Unmarshal(data, cNode); err != nil {
+		if err := parseConfigYAML(cNode, name, data); err != nil {
 			log.Printf("WARNING: error parsing embedded config '%s': %v", name, err)
 			continue
 		}
 		configNode := &compare.Node{Node: cNode}
 		compare.WalkConvertYamlNodeToMainNode(configNode)
 		compare.WalkParseLoadConfigComments(configNode)
-		if err := compare.WalkAndValidateConfig(configNode); err != nil {
+		warnConfigDirectives("embedded:"+name, configNode)
+		if err := validateConfigNode(configNode); err != nil {
 			log.Printf("WARNING: error validating embedded config '%s': %v", name, err)
 			continue
 		}
-		fileConfigs := compare.GetFileConfigs(configNode)
-		if fileConfigs.Kind == "" {
+		configName := configNameForFile(name, configNode)
+		if configName == "" {
 			log.Printf("WARNING: unable to determine schema for embedded config '%s'", name)
 			continue
 		}
-		configNodes[fileConfigs.Kind] = configNode
+		configNodes[configName] = configNode
 	}
 
 	return configNodes
@@ -478,8 +593,8 @@ func configNodesForPath(cfgNodesByPaths []configNodesByPath, filePath string) co
 		if !strings.Contains(filePath, cfgNodeByPath.path) {
 			continue
 		}
-		for kind, cN := range cfgNodeByPath.ConfigNodes {
-			configNodes[kind] = cN
+		for name, cN := range cfgNodeByPath.ConfigNodes {
+			configNodes[name] = cN
 		}
 	}
 
@@ -500,17 +615,52 @@ func getYAML(node *yaml.Node, file string) ([]byte, error) {
 	return data, nil
 }
 
-// parseNodeFromBytes unmarshals YAML bytes into a compare.Node tree.
-func parseNodeFromBytes(data []byte) (*compare.Node, error) {
-	yamlNode := &yaml.Node{}
-	err := yaml.Unmarshal(data, yamlNode)
+// getDocuments reads a file, or stdin, splitting it into its YAML documents
+func getDocuments(file string) ([]byte, []documents.Document, error) {
+	data, err := readInput(file)
 	if err != nil {
-		return nil, err
+		return data, nil, fmt.Errorf("error reading '%s': %w", inputName(file), err)
 	}
-	node := &compare.Node{Node: yamlNode}
-	compare.WalkConvertYamlNodeToMainNode(node)
 
-	return node, nil
+	return data, documents.Split(data), nil
+}
+
+// documentName returns the name a document is reported by, which is the file path if it's the only one
+func documentName(filePath string, index, count int) string {
+	if count == 1 {
+		return filePath
+	}
+
+	return fmt.Sprintf("%s (document %d)", filePath, index+1)
+}
+
+// getConfigYAML reads a config file, converting it to the comment format if it's a '*.pyschema.yaml' file
+func getConfigYAML(node *yaml.Node, file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("error reading '%s': %w", file, err)
+	}
+
+	return parseConfigYAML(node, file, data)
+}
+
+// parseConfigYAML unmarshals a config, converting it to the comment format if it's a '*.pyschema.yaml' file
+func parseConfigYAML(node *yaml.Node, fileName string, data []byte) error {
+	if !compare.IsSchemaFile(fileName) {
+		err := yaml.Unmarshal(data, node)
+		if err != nil {
+			return fmt.Errorf("error unmarshaling '%s': %w", fileName, err)
+		}
+		return nil
+	}
+
+	schemaNode, err := compare.ParseSchema(data)
+	if err != nil {
+		return fmt.Errorf("error parsing schema '%s': %w", fileName, err)
+	}
+	*node = *schemaNode
+
+	return nil
 }
 
 // filterEmptyConfigDirs removes config dirs that contain no .remote file and no YAML files.
@@ -596,10 +746,14 @@ func getFilePathParentDirs(workDir, homeDir, filePath string, dirs map[string]bo
 	return getFilePathParentDirs(workDir, homeDir, parent, dirs)
 }
 
-// getAllFilePaths checks for paths that are directories, searching them for yaml files
+// getAllFilePaths checks for paths that are directories, searching them for yaml files. stdin is kept as is.
 func getAllFilePaths(filePaths []string) ([]string, error) {
 	allFilePaths := []string{}
 	for _, filePath := range filePaths {
+		if filePath == stdinPath {
+			allFilePaths = append(allFilePaths, filePath)
+			continue
+		}
 		fileStat, err := os.Stat(filePath)
 		if err != nil {
 			return filePaths, err
@@ -637,9 +791,12 @@ func getAllFilePaths(filePaths []string) ([]string, error) {
 
 // ProjectConfig represents the contents of a .predictable-yaml.yaml config file.
 type ProjectConfig struct {
-	ConfigDir string              `yaml:"config-dir"`
-	Remote    ProjectRemoteConfig `yaml:"remote"`
-	Fixer     ProjectFixerConfig  `yaml:"fixer"`
+	ConfigDir    string              `yaml:"config-dir"`
+	FallbackKind string              `yaml:"fallback-kind"`
+	Remote       ProjectRemoteConfig `yaml:"remote"`
+	Linter       ProjectLinterConfig `yaml:"linter"`
+	Fixer        ProjectFixerConfig  `yaml:"fixer"`
+	Documents    ProjectDocsConfig   `yaml:"documents"`
 }
 
 // ProjectRemoteConfig holds the remote config source settings.
@@ -648,6 +805,12 @@ type ProjectRemoteConfig struct {
 	Version string `yaml:"version"`
 }
 
+// ProjectLinterConfig holds linter default settings.
+type ProjectLinterConfig struct {
+	Strict         *bool `yaml:"strict"`
+	FailOnWarnings *bool `yaml:"fail-on-warnings"`
+}
+
 // ProjectFixerConfig holds fixer default settings.
 type ProjectFixerConfig struct {
 	IndentationLevel        *int  `yaml:"indentation-level"`
@@ -658,6 +821,30 @@ type ProjectFixerConfig struct {
 	PromptIfLineCountChange *bool `yaml:"prompt-if-line-count-change"`
 	UnmatchedToBeginning    *bool `yaml:"unmatched-to-beginning"`
 	Validate                *bool `yaml:"validate"`
+	RenameSuggested         *bool `yaml:"rename-suggested"`
+	CopyMissingEntries      *bool `yaml:"copy-missing-entries"`
+}
+
+// ProjectDocsConfig holds the order policy for the documents of multi-document files.
+type ProjectDocsConfig struct {
+	KindOrder []string `yaml:"kind-order"`
+	SortKey   string   `yaml:"sort-key"`
+}
+
+// documentOrder returns the project's document order policy, or nil if it doesn't have one.
+func documentOrder(projectCfg *ProjectConfig) *documents.Order {
+	if projectCfg == nil || len(projectCfg.Documents.KindOrder) == 0 {
+		return nil
+	}
+	order := &documents.Order{
+		Kinds:   projectCfg.Documents.KindOrder,
+		SortKey: projectCfg.Documents.SortKey,
+	}
+	if err := order.Validate(); err != nil {
+		log.Fatal(err)
+	}
+
+	return order
 }
 
 // resolveConfigDir returns the config directory, preferring CLI flag over project config.
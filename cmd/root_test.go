@@ -567,7 +567,7 @@ func TestParseProjectConfig(t *testing.T) {
 
 	// Test valid config
 	validPath := filepath.Join(tmpDir, "valid.yaml")
-	if err := os.WriteFile(validPath, []byte("remote:\n  url: https://example.com/repo\n  version: v1.0.0\nfixer:\n  indentation-level: 4\n  compact-lists: false\n"), 0644); err != nil {
+	if err := os.WriteFile(validPath, []byte("fallback-kind: Generic\nremote:\n  url: https://example.com/repo\n  version: v1.0.0\nlinter:\n  strict: true\n  fail-on-warnings: true\nfixer:\n  indentation-level: 4\n  compact-lists: false\n"), 0644); err != nil {
 		t.Fatal(err)
 	}
 	cfg, err := parseProjectConfig(validPath)
@@ -580,6 +580,15 @@ func TestParseProjectConfig(t *testing.T) {
 	if cfg.Remote.Version != "v1.0.0" {
 		t.Errorf("expected version v1.0.0, got %s", cfg.Remote.Version)
 	}
+	if cfg.Linter.Strict == nil || *cfg.Linter.Strict != true {
+		t.Errorf("expected strict true, got %v", cfg.Linter.Strict)
+	}
+	if cfg.FallbackKind != "Generic" {
+		t.Errorf("expected fallback-kind Generic, got %s", cfg.FallbackKind)
+	}
+	if cfg.Linter.FailOnWarnings == nil || *cfg.Linter.FailOnWarnings != true {
+		t.Errorf("expected fail-on-warnings true, got %v", cfg.Linter.FailOnWarnings)
+	}
 	if cfg.Fixer.IndentationLevel == nil || *cfg.Fixer.IndentationLevel != 4 {
 		t.Errorf("expected indentation-level 4, got %v", cfg.Fixer.IndentationLevel)
 	}
@@ -602,6 +611,9 @@ func TestParseProjectConfig(t *testing.T) {
 	if cfg.Remote.Version != "v1.0.0" {
 		t.Errorf("expected version v1.0.0, got %s", cfg.Remote.Version)
 	}
+	if cfg.Linter.Strict != nil {
+		t.Errorf("expected nil strict, got %v", cfg.Linter.Strict)
+	}
 	if cfg.Fixer.IndentationLevel != nil {
 		t.Errorf("expected nil indentation-level, got %v", cfg.Fixer.IndentationLevel)
 	}
@@ -651,3 +663,150 @@ func fmtPath(tmpDir, path string) string {
 	}
 	return fmt.Sprintf("%s/%s", tmpDir, path)
 }
+
+func TestFindConfigNode(t *testing.T) {
+	deployment := &compare.Node{}
+	fallback := &compare.Node{}
+	generic := &compare.Node{}
+	configNodes := compare.ConfigNodes{
+		"Deployment":       deployment,
+		fallbackConfigName: fallback,
+		"Generic":          generic,
+	}
+
+	type testCase struct {
+		note                 string
+		fileConfigs          compare.FileConfigs
+		configNodes          compare.ConfigNodes
+		projectCfg           *ProjectConfig
+		expectedNode         *compare.Node
+		expectedName         string
+		expectedUsedFallback bool
+		expectedOK           bool
+	}
+
+	testCases := []testCase{
+		{
+			note:         "matching config",
+			fileConfigs:  compare.FileConfigs{Kind: "Deployment", APIVersion: "apps/v1"},
+			configNodes:  configNodes,
+			expectedNode: deployment,
+			expectedName: "Deployment",
+			expectedOK:   true,
+		},
+		{
+			note:                 "default fallback",
+			fileConfigs:          compare.FileConfigs{Kind: "Certificate", APIVersion: "cert-manager.io/v1"},
+			configNodes:          configNodes,
+			expectedNode:         fallback,
+			expectedName:         fallbackConfigName,
+			expectedUsedFallback: true,
+			expectedOK:           true,
+		},
+		{
+			note:                 "fallback-kind from project config",
+			fileConfigs:          compare.FileConfigs{Kind: "Certificate", APIVersion: "cert-manager.io/v1"},
+			configNodes:          configNodes,
+			projectCfg:           &ProjectConfig{FallbackKind: "Generic"},
+			expectedNode:         generic,
+			expectedName:         "Generic",
+			expectedUsedFallback: true,
+			expectedOK:           true,
+		},
+		{
+			note:                 "no fallback config",
+			fileConfigs:          compare.FileConfigs{Kind: "Certificate", APIVersion: "cert-manager.io/v1"},
+			configNodes:          compare.ConfigNodes{"Deployment": deployment},
+			expectedName:         fallbackConfigName,
+			expectedUsedFallback: true,
+			expectedOK:           false,
+		},
+	}
+
+	for _, tc := range testCases {
+		node, name, usedFallback, ok := findConfigNode(tc.configNodes, tc.fileConfigs, tc.projectCfg)
+		if node != tc.expectedNode || name != tc.expectedName || usedFallback != tc.expectedUsedFallback || ok != tc.expectedOK {
+			t.Errorf("Description: %s: cmd.findConfigNode(...): \n-expected:\n%s %v %v\n+got:\n%s %v %v\n", tc.note, tc.expectedName, tc.expectedUsedFallback, tc.expectedOK, name, usedFallback, ok)
+		}
+	}
+}
+
+func TestResolveExtends(t *testing.T) {
+	parse := func(data string) *compare.Node {
+		n := &yaml.Node{}
+		if err := yaml.Unmarshal([]byte(data), n); err != nil {
+			t.Fatal(err)
+		}
+		node := &compare.Node{Node: n}
+		compare.WalkConvertYamlNodeToMainNode(node)
+		compare.WalkParseLoadConfigComments(node)
+		return node
+	}
+
+	upstream := compare.ConfigNodes{
+		"Deployment": parse("kind: Deployment  # first, required\nspec:\n  replicas: 1\n"),
+	}
+
+	type testCase struct {
+		note         string
+		name         string
+		newNodes     compare.ConfigNodes
+		expectedKeys []string
+		expectedErr  error
+	}
+
+	testCases := []testCase{
+		{
+			note: "extends the config it replaces",
+			name: "Deployment",
+			newNodes: compare.ConfigNodes{
+				"Deployment": parse("# predictable-yaml: kind=Deployment, extends=Deployment\nspec:\n  minReadySeconds: 5\n"),
+			},
+			expectedKeys: []string{"replicas", "minReadySeconds"},
+		},
+		{
+			note: "extends a config in the same layer",
+			name: "StatefulSet",
+			newNodes: compare.ConfigNodes{
+				"Deployment":  parse("# predictable-yaml: kind=Deployment, extends=Deployment\nspec:\n  minReadySeconds: 5\n"),
+				"StatefulSet": parse("# predictable-yaml: kind=StatefulSet, extends=Deployment\nspec:\n  serviceName: TODO\n"),
+			},
+			expectedKeys: []string{"replicas", "minReadySeconds", "serviceName"},
+		},
+		{
+			note: "cycle",
+			name: "A",
+			newNodes: compare.ConfigNodes{
+				"A": parse("# predictable-yaml: kind=A, extends=B\nspec: {}\n"),
+				"B": parse("# predictable-yaml: kind=B, extends=A\nspec: {}\n"),
+			},
+			expectedErr: fmt.Errorf("configuration error: 'extends' cycle at config 'A'"),
+		},
+		{
+			note: "missing",
+			name: "A",
+			newNodes: compare.ConfigNodes{
+				"A": parse("# predictable-yaml: kind=A, extends=Nope\nspec: {}\n"),
+			},
+			expectedErr: fmt.Errorf("configuration error: no config 'Nope' to extend"),
+		},
+	}
+
+	for _, tc := range testCases {
+		got, err := resolveExtends(tc.name, tc.newNodes, compare.ConfigNodes{}, upstream, compare.ConfigNodes{}, map[string]bool{})
+		if fmt.Sprint(err) != fmt.Sprint(tc.expectedErr) {
+			t.Errorf("Description: %s: cmd.resolveExtends(...) error: \n-expected:\n%v\n+got:\n%v\n", tc.note, tc.expectedErr, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		keys := []string{}
+		for _, pair := range compare.GetKeyValuePairs(got.NodeContent[0].NodeContent[3].NodeContent) {
+			keys = append(keys, pair.Key)
+		}
+		if !reflect.DeepEqual(keys, tc.expectedKeys) {
+			t.Errorf("Description: %s: cmd.resolveExtends(...): \n-expected:\n%v\n+got:\n%v\n", tc.note, tc.expectedKeys, keys)
+		}
+	}
+}
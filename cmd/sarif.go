@@ -0,0 +1,268 @@
+/*
+Copyright © 2022 david amick git@davidamick.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/snarlysodboxer/predictable-yaml/pkg/compare"
+	"github.com/snarlysodboxer/predictable-yaml/pkg/documents"
+)
+
+const (
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion = "2.1.0"
+)
+
+// sarifRules describes the rules findings are reported under, in the order they're listed
+var sarifRules = []struct {
+	id          string
+	description string
+}{
+	{compare.RuleOrder, "Keys must be in the order of the config"},
+	{compare.RuleFirst, "Keys marked 'first' must be the first key of their map"},
+	{compare.RuleRequired, "Keys marked 'required' must be present"},
+	{compare.RulePreferred, "Keys marked 'preferred' should be present"},
+	{compare.RuleNullValue, "Maps and sequences must not have null values"},
+	{compare.RuleUnknownKey, "Keys must be in the config"},
+	{compare.RuleEquals, "Keys marked 'equals' must equal the value at their path"},
+	{compare.RuleSubsetOf, "Keys marked 'subset-of' must be a subset of the value at their path"},
+	{compare.RuleOneOf, "Maps must have exactly one key of each 'one-of' group"},
+	{compare.RuleAnyOf, "Maps must have at least one key of each 'any-of' group"},
+	{compare.RuleOmitEmpty, "Empty maps and sequences of keys marked 'omit-empty' must be removed"},
+	{compare.RuleStyle, "Maps and sequences must have the 'style' of their config key"},
+	{compare.RuleBlankBefore, "Keys marked 'blank-before' must have a blank line above them"},
+	{compare.RuleNoBlank, "Values of keys marked 'no-blank' must not have blank lines"},
+	{compare.RuleDirective, "'# predictable-yaml:' comments must have known, well-formed directives"},
+	{compare.RuleDocumentOrder, "Documents must be in the order of the project's policy"},
+	{compare.RuleSchema, "Documents should have a kind that a config is found for"},
+}
+
+// the rules whose findings fixing a document resolves
+var sarifFixableRules = map[string]bool{
+	compare.RuleOrder:       true,
+	compare.RuleFirst:       true,
+	compare.RuleRequired:    true,
+	compare.RuleOmitEmpty:   true,
+	compare.RuleStyle:       true,
+	compare.RuleBlankBefore: true,
+	compare.RuleNoBlank:     true,
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId,omitempty"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+	Fixes     []sarifFix      `json:"fixes,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation  `json:"physicalLocation"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+	EndLine     int `json:"endLine,omitempty"`
+	EndColumn   int `json:"endColumn,omitempty"`
+}
+
+type sarifLogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+type sarifFix struct {
+	Description     sarifMessage          `json:"description"`
+	ArtifactChanges []sarifArtifactChange `json:"artifactChanges"`
+}
+
+type sarifArtifactChange struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Replacements     []sarifReplacement    `json:"replacements"`
+}
+
+type sarifReplacement struct {
+	DeletedRegion   sarifRegion  `json:"deletedRegion"`
+	InsertedContent sarifMessage `json:"insertedContent"`
+}
+
+// fixSuggester is a reporter that suggests the fixed contents of documents with findings
+type fixSuggester interface {
+	suggestFix(ref docRef, doc documents.Document, fixed string)
+}
+
+// sarifReporter writes the findings as a SARIF 2.1.0 log, for code scanning dashboards
+type sarifReporter struct {
+	findingCollector
+	out   io.Writer
+	fixes map[docRef]sarifFix // by file and document
+}
+
+// suggestFix replaces the body of a document with its fixed contents
+func (r *sarifReporter) suggestFix(ref docRef, doc documents.Document, fixed string) {
+	if fixed == doc.Body {
+		return
+	}
+	r.fixes[docRef{file: ref.file, document: ref.document}] = sarifFix{
+		Description: sarifMessage{Text: fmt.Sprintf("Fix '%s' with predictable-yaml", ref.name)},
+		ArtifactChanges: []sarifArtifactChange{{
+			ArtifactLocation: sarifArtifactLocation{URI: sarifURI(ref.file)},
+			Replacements: []sarifReplacement{{
+				DeletedRegion:   bodyRegion(doc),
+				InsertedContent: sarifMessage{Text: fixed},
+			}},
+		}},
+	}
+}
+
+func (r *sarifReporter) finish(summary runSummary) {
+	rules := make([]sarifRule, 0, len(sarifRules))
+	for _, rule := range sarifRules {
+		rules = append(rules, sarifRule{ID: rule.id, ShortDescription: sarifMessage{Text: rule.description}})
+	}
+
+	results := make([]sarifResult, 0, len(r.findings))
+	for _, f := range r.findings {
+		result := sarifResult{
+			RuleID:    f.Rule,
+			Level:     sarifLevel(f.Severity),
+			Message:   sarifMessage{Text: f.Message},
+			Locations: []sarifLocation{sarifFindingLocation(f)},
+		}
+		// the fix of a document resolves all of its fixable findings, so it's given with the first
+		ref := docRef{file: f.File, document: f.Document}
+		if fix, ok := r.fixes[ref]; ok && sarifFixableRules[f.Rule] {
+			result.Fixes = []sarifFix{fix}
+			delete(r.fixes, ref)
+		}
+		results = append(results, result)
+	}
+
+	encoder := json.NewEncoder(r.out)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "predictable-yaml",
+				Version:        Version,
+				InformationURI: "https://github.com/snarlysodboxer/predictable-yaml",
+				Rules:          rules,
+			}},
+			Results: results,
+		}},
+	})
+}
+
+// sarifLevel returns the SARIF level of a finding's severity
+func sarifLevel(severity string) string {
+	switch severity {
+	case severityWarning:
+		return "warning"
+	case severityInfo:
+		return "note"
+	}
+
+	return "error"
+}
+
+// sarifFindingLocation returns the file, line, and column of a finding, and its path as a logical location
+func sarifFindingLocation(f finding) sarifLocation {
+	location := sarifLocation{
+		PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: sarifURI(f.File)}},
+	}
+	if f.Line > 0 {
+		location.PhysicalLocation.Region = &sarifRegion{StartLine: f.Line, StartColumn: f.Column}
+	}
+	if f.Path != "" {
+		location.LogicalLocations = []sarifLogicalLocation{{FullyQualifiedName: f.Path, Kind: "member"}}
+	}
+
+	return location
+}
+
+// sarifURI returns the URI of a file path, relative paths staying relative to the working directory
+func sarifURI(path string) string {
+	path = filepath.ToSlash(path)
+	if filepath.IsAbs(path) || strings.HasPrefix(path, "/") {
+		return (&url.URL{Scheme: "file", Path: path}).String()
+	}
+
+	return (&url.URL{Path: path}).String()
+}
+
+// bodyRegion returns the region of a file that a document's body spans
+func bodyRegion(doc documents.Document) sarifRegion {
+	lines := strings.Split(doc.Body, "\n")
+	last := lines[len(lines)-1]
+
+	return sarifRegion{
+		StartLine:   doc.Line,
+		StartColumn: 1,
+		EndLine:     doc.Line + len(lines) - 1,
+		EndColumn:   len(last) + 1,
+	}
+}
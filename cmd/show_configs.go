@@ -26,6 +26,12 @@ import (
 	"github.com/snarlysodboxer/predictable-yaml/pkg/compare"
 	"github.com/snarlysodboxer/predictable-yaml/pkg/remote"
 	"github.com/spf13/cobra"
+	"go.yaml.in/yaml/v3"
+)
+
+// flags
+var (
+	printConfig string
 )
 
 var showConfigsCmd = &cobra.Command{
@@ -46,6 +52,11 @@ var showConfigsCmd = &cobra.Command{
 			log.Fatal(err)
 		}
 
+		if printConfig != "" {
+			printResolvedConfig(printConfig, workDir, homeDir, filePaths)
+			return
+		}
+
 		// Find config dirs
 		var configDirs []string
 		if configDirFlag != "" {
@@ -91,7 +102,7 @@ var showConfigsCmd = &cobra.Command{
 			// Check for local config files
 			localNodes := loadLocalConfigNodes(dir)
 			if len(localNodes) > 0 {
-				fmt.Printf("  Local overrides: %s\n", strings.Join(configNodeKinds(localNodes), ", "))
+				fmt.Printf("  Local overrides: %s\n", strings.Join(configNodeNames(localNodes), ", "))
 			}
 		}
 
@@ -115,7 +126,7 @@ var showConfigsCmd = &cobra.Command{
 
 				localNodes := loadLocalConfigNodes(dir)
 				if len(localNodes) > 0 {
-					fmt.Printf("  Local overrides: %s\n", strings.Join(configNodeKinds(localNodes), ", "))
+					fmt.Printf("  Local overrides: %s\n", strings.Join(configNodeNames(localNodes), ", "))
 				}
 			}
 		}
@@ -124,8 +135,8 @@ var showConfigsCmd = &cobra.Command{
 		if !hasRemoteConfig && len(configDirs) == 0 {
 			embeddedNodes := loadEmbeddedConfigNodes()
 			if len(embeddedNodes) > 0 {
-				kinds := configNodeKinds(embeddedNodes)
-				fmt.Printf("\nUsing built-in embedded configs (%d schemas): %s\n", len(kinds), strings.Join(kinds, ", "))
+				names := configNodeNames(embeddedNodes)
+				fmt.Printf("\nUsing built-in embedded configs (%d schemas): %s\n", len(names), strings.Join(names, ", "))
 			} else {
 				fmt.Println("\nNo embedded configs available. Build with 'go generate ./internal/embedded/' to embed configs.")
 			}
@@ -133,15 +144,47 @@ var showConfigsCmd = &cobra.Command{
 	},
 }
 
-func configNodeKinds(nodes compare.ConfigNodes) []string {
-	kinds := make([]string, 0, len(nodes))
-	for kind := range nodes {
-		kinds = append(kinds, kind)
+// configNodeNames lists config names: the kind, qualified with its apiVersion pattern when it has one
+func configNodeNames(nodes compare.ConfigNodes) []string {
+	names := make([]string, 0, len(nodes))
+	for name := range nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// printResolvedConfig prints a config as it will be used, with any 'extends' merged in.
+// Configs are resolved for the first given path, or the working directory.
+func printResolvedConfig(name, workDir, homeDir string, filePaths []string) {
+	allFilePaths := []string{}
+	if len(filePaths) > 0 {
+		var err error
+		allFilePaths, err = getAllFilePaths(filePaths)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	projectCfg, projectCfgDir := loadProjectConfig(workDir, homeDir)
+	configDirFlag := resolveConfigDir(projectCfg, projectCfgDir)
+	cfgNodesByPaths := getConfigNodesByPath(configDirFlag, workDir, homeDir, allFilePaths, projectCfg, projectCfgDir)
+	path := workDir
+	if len(allFilePaths) > 0 {
+		path = allFilePaths[0]
+	}
+	configNode, ok := configNodesForPath(cfgNodesByPaths, path)[name]
+	if !ok {
+		log.Fatalf("no config found named '%s'", name)
+	}
+
+	encoder := yaml.NewEncoder(os.Stdout)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(configNode.Node); err != nil {
+		log.Fatal(err)
 	}
-	sort.Strings(kinds)
-	return kinds
 }
 
 func init() {
 	rootCmd.AddCommand(showConfigsCmd)
+	showConfigsCmd.PersistentFlags().StringVar(&printConfig, "print", "", "print the named config as it will be used, with any 'extends' merged in")
 }
@@ -0,0 +1,108 @@
+/*
+Copyright © 2022 david amick git@davidamick.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package compare
+
+import (
+	"fmt"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// ChangeType identifies what happened to a key or sequence item during sorting
+type ChangeType string
+
+const (
+	// KeyMoved is a config key whose position in its map changed
+	KeyMoved ChangeType = "KeyMoved"
+	// KeyAdded is a missing required or preferred key that was added
+	KeyAdded ChangeType = "KeyAdded"
+	// SequenceItemAdded is an item added to an empty required or preferred sequence
+	SequenceItemAdded ChangeType = "SequenceItemAdded"
+	// UnmatchedRelocated is a key not found in the config whose position in its map changed
+	UnmatchedRelocated ChangeType = "UnmatchedRelocated"
+	// KeyRenamed is a likely misspelled key that was renamed to the config key it matches
+	KeyRenamed ChangeType = "KeyRenamed"
+	// StyleChanged is a map or sequence converted to the 'style=' of its config key, given in Value
+	StyleChanged ChangeType = "StyleChanged"
+	// KeyRemoved is a key marked 'omit-empty' whose empty map or sequence was removed
+	KeyRemoved ChangeType = "KeyRemoved"
+	// EntryCopied is a map entry copied from another map to fix an 'equals' or 'subset-of' relation
+	EntryCopied ChangeType = "EntryCopied"
+)
+
+// Change is a single structural change made to a target file during sorting.
+// Changes are recorded in the order they are made, parents before children.
+type Change struct {
+	Type      ChangeType
+	Path      string    // parent path, e.g. ".metadata.labels", or the sequence path for items
+	Key       string    // key name, e.g. "app.kubernetes.io/name", empty for sequence items
+	OldKey    string    // original key name, for renames
+	From      int       // pair index before sorting, -1 for additions
+	To        int       // pair index (or item index) after sorting, -1 for removals
+	ValueKind yaml.Kind // kind of the value node
+	Value     string    // scalar value, empty for maps and sequences, or the new style for style changes
+	Source    string    // for copied entries, the path of the map they were copied from
+	Node      *Node     // the key node, or the item node for sequence items
+	After     *Node     // for moved and added keys, the key they come after once sorted, nil at the top
+	Rule      string    // the rule the change is made for, see the Rule constants
+	Line      int       // line of the key or item in the target file, or of its parent for additions
+	Column    int       // column of the key or item in the target file, or of its parent for additions
+}
+
+func (c Change) String() string {
+	switch c.Type {
+	case KeyMoved, UnmatchedRelocated:
+		return fmt.Sprintf("%s %s.%s (%d -> %d)", c.Type, c.Path, c.Key, c.From, c.To)
+	case SequenceItemAdded:
+		return fmt.Sprintf("%s %s[%d]", c.Type, c.Path, c.To)
+	case KeyRenamed:
+		return fmt.Sprintf("%s %s.%s -> %s", c.Type, c.Path, c.OldKey, c.Key)
+	case StyleChanged:
+		return fmt.Sprintf("%s %s.%s -> %s", c.Type, c.Path, c.Key, c.Value)
+	case EntryCopied:
+		return fmt.Sprintf("%s %s.%s from %s", c.Type, c.Path, c.Key, c.Source)
+	}
+
+	return fmt.Sprintf("%s %s.%s", c.Type, c.Path, c.Key)
+}
+
+// defaultRule returns the rule a change of a type is made for, when it isn't a more specific one
+func defaultRule(changeType ChangeType) string {
+	switch changeType {
+	case KeyAdded, SequenceItemAdded:
+		return RuleRequired
+	case KeyRenamed:
+		return RuleUnknownKey
+	case StyleChanged:
+		return RuleStyle
+	case KeyRemoved:
+		return RuleOmitEmpty
+	}
+
+	return RuleOrder
+}
+
+// recordChange appends a change to the change log, if one was requested
+func (sortConfs SortConfigs) recordChange(change Change) {
+	if sortConfs.Changes == nil {
+		return
+	}
+	if change.Rule == "" {
+		change.Rule = defaultRule(change.Type)
+	}
+	change.Line, change.Column = FilePosition(change.Node)
+	*sortConfs.Changes = append(*sortConfs.Changes, change)
+}
@@ -0,0 +1,149 @@
+/*
+Copyright © 2022 david amick git@davidamick.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package compare
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// ConfigProblem is a problem found by CheckConfig, at a line of the config file
+type ConfigProblem struct {
+	Line int
+	Err  error
+}
+
+// CheckConfig checks a whole config for problems otherwise only found when a target file hits them:
+// unknown or malformed directives, misplaced 'first' keys, sequences with more than one entry,
+// 'equals' and 'subset-of' paths that don't start with '.', unknown styles, 'one-of' and 'any-of' groups with only one key,
+// and dittos that don't resolve in configNodes or form a cycle. Dittos are not checked when configNodes is nil.
+// Problems are sorted by line.
+func CheckConfig(configNode *Node, configNodes ConfigNodes) []ConfigProblem {
+	problems := []ConfigProblem{}
+	if configNode.Kind != yaml.DocumentNode || len(configNode.NodeContent) == 0 {
+		return problems
+	}
+	if configNode.NodeContent[0].Kind != yaml.MappingNode {
+		return append(problems, ConfigProblem{configNode.NodeContent[0].Line, fmt.Errorf("configuration error: config is not a map")})
+	}
+
+	problems = append(problems, CheckConfigDirectives(configNode)...)
+	problems = walkCheckConfig(configNode.NodeContent[0], problems)
+	if configNodes != nil {
+		problems = walkCheckDittos(configNode.NodeContent[0], SortConfigs{ConfigNodes: configNodes}, problems)
+	}
+	sortConfigProblems(problems)
+
+	return problems
+}
+
+// CheckConfigDittos checks that the dittos of a config resolve in configNodes without forming a cycle.
+// Problems are sorted by line.
+func CheckConfigDittos(configNode *Node, configNodes ConfigNodes) []ConfigProblem {
+	problems := []ConfigProblem{}
+	if configNode.Kind != yaml.DocumentNode || len(configNode.NodeContent) == 0 {
+		return problems
+	}
+	problems = walkCheckDittos(configNode.NodeContent[0], SortConfigs{ConfigNodes: configNodes}, problems)
+	sortConfigProblems(problems)
+
+	return problems
+}
+
+// sortConfigProblems sorts problems by line
+func sortConfigProblems(problems []ConfigProblem) {
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].Line < problems[j].Line
+	})
+}
+
+// walkCheckDittos appends the problems with the dittos of a config node and its children
+func walkCheckDittos(node *Node, sortConfs SortConfigs, problems []ConfigProblem) []ConfigProblem {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for _, pair := range GetKeyValuePairs(node.NodeContent) {
+			if pair.KeyNode.Ditto != "" {
+				if _, err := getConfigValueNodeForDitto(pair, sortConfs); err != nil {
+					problems = append(problems, ConfigProblem{pair.KeyNode.Line, err})
+				}
+			}
+			problems = walkCheckDittos(pair.ValueNode, sortConfs, problems)
+		}
+	case yaml.SequenceNode:
+		for _, innerNode := range node.NodeContent {
+			problems = walkCheckDittos(innerNode, sortConfs, problems)
+		}
+	}
+
+	return problems
+}
+
+// walkCheckConfig appends the problems found in a config node and its children
+func walkCheckConfig(node *Node, problems []ConfigProblem) []ConfigProblem {
+	switch node.Kind {
+	case yaml.MappingNode:
+		pairs := GetKeyValuePairs(node.NodeContent)
+		firstKeys := []string{}
+		for index, pair := range pairs {
+			if !pair.KeyNode.MustBeFirst {
+				continue
+			}
+			firstKeys = append(firstKeys, pair.Key)
+			if index != 0 {
+				err := fmt.Errorf("configuration error: key '%s' is marked as 'first' but is not the first key in the map at path '%s'", pair.Key, GetReferencePath(node, 0, ""))
+				problems = append(problems, ConfigProblem{pair.KeyNode.Line, err})
+			}
+		}
+		if len(firstKeys) > 1 {
+			keysStr := "'" + strings.Join(firstKeys, "', '") + "'"
+			err := fmt.Errorf("configuration error: multiple keys marked as 'first' in the same map at path '%s', keys: %s", GetReferencePath(node, 0, ""), keysStr)
+			problems = append(problems, ConfigProblem{node.Line, err})
+		}
+		for _, group := range groupsOf(node) {
+			if len(group.keys) == 1 {
+				err := fmt.Errorf("configuration error: '%s' group '%s' has only one key '%s' in the map at path '%s'", group.directive, group.name, group.keys[0], GetReferencePath(node, 0, ""))
+				problems = append(problems, ConfigProblem{group.line, err})
+			}
+		}
+		for _, pair := range pairs {
+			if style := pair.KeyNode.CollectionStyle; style != "" && style != styleBlock && style != styleFlow {
+				err := fmt.Errorf("configuration error: unknown style '%s', expected '%s' or '%s' at path: %s", style, styleBlock, styleFlow, GetReferencePath(pair.KeyNode, 0, ""))
+				problems = append(problems, ConfigProblem{pair.KeyNode.Line, err})
+			}
+			for _, relation := range relationsOf(pair.KeyNode) {
+				if !startDot.MatchString(relation.path) {
+					err := fmt.Errorf("configuration error: '%s' path '%s' must start with '.' at path: %s", relationDirective(relation), relation.path, GetReferencePath(pair.KeyNode, 0, ""))
+					problems = append(problems, ConfigProblem{pair.KeyNode.Line, err})
+				}
+			}
+			problems = walkCheckConfig(pair.KeyNode, problems)
+			problems = walkCheckConfig(pair.ValueNode, problems)
+		}
+	case yaml.SequenceNode:
+		if len(node.NodeContent) > 1 {
+			err := fmt.Errorf("configuration error: sequence has %d entries at path: %s, only the first is used", len(node.NodeContent), sequencePath(node.NodeContent[0]))
+			problems = append(problems, ConfigProblem{node.NodeContent[1].Line, err})
+		}
+		for _, innerNode := range node.NodeContent {
+			problems = walkCheckConfig(innerNode, problems)
+		}
+	}
+
+	return problems
+}
@@ -0,0 +1,199 @@
+/*
+Copyright © 2022 david amick git@davidamick.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package compare
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"go.yaml.in/yaml/v3"
+)
+
+func TestCheckConfig(t *testing.T) {
+	podYaml := `---
+apiVersion: v1  # first, required
+kind: Pod  # required
+spec:
+  containers:  # required
+  - name: TODO  # first, required
+`
+
+	type testCase struct {
+		note       string
+		configYaml string
+		nilConfigs bool
+		expected   []string
+	}
+
+	testCases := []testCase{
+		{
+			note: "valid",
+			configYaml: `---
+# predictable-yaml: kind=Deployment, apiVersion=apps/*
+apiVersion: apps/v1  # first, required
+kind: Deployment  # required
+spec:
+  template:
+    spec: {}  # ditto=Pod.spec
+  initContainers: []  # ditto=Pod.spec.containers
+  containers: []  # ditto=.spec.initContainers
+`,
+			expected: []string{},
+		},
+		{
+			note: "unknown and malformed directives",
+			configYaml: `---
+# predictable-yaml: kind=Deployment, extend=Pod
+apiVersion: apps/v1  # first, requried
+kind: Deployment  # required, ditto
+`,
+			expected: []string{
+				"2: configuration error: unknown directive 'extend' in '# predictable-yaml:' comment",
+				"3: configuration error: unknown directive 'requried' at path: .apiVersion",
+				"4: configuration error: malformed directive 'ditto', expected 'ditto=<value>' at path: .kind",
+			},
+		},
+		{
+			note: "prose comments aren't directives",
+			configYaml: `---
+apiVersion: apps/v1  # first, required
+kind: Deployment  # TODO
+spec:  # deprecated, use template instead
+  template: {}  # see: https://example.com
+  selector: {}  # requird
+`,
+			expected: []string{
+				"6: configuration error: unknown directive 'requird' at path: .spec.selector",
+			},
+		},
+		{
+			note: "directives followed by prose are checked",
+			configYaml: `---
+kind: Deployment  # first
+metadata:
+  name: TODO  # required (every Deployment needs one)
+  labels: {}  # preferred: used by selectors
+  annotations: {}  # preferd (free form)
+`,
+			expected: []string{
+				"6: configuration error: unknown directive 'preferd' at path: .metadata.annotations",
+			},
+		},
+		{
+			note: "first keys and sequences",
+			configYaml: `---
+kind: Deployment
+spec:  # first
+  containers:
+  - name: TODO  # first
+  - name: TODO
+`,
+			expected: []string{
+				"3: configuration error: key 'spec' is marked as 'first' but is not the first key in the map at path ''",
+				"6: configuration error: sequence has 2 entries at path: .spec.containers, only the first is used",
+			},
+		},
+		{
+			note: "missing kinds and cycles",
+			configYaml: `---
+kind: Deployment
+spec:
+  template: {}  # ditto=ReplicaSet.spec.template
+  volumes: []  # ditto=@volumes
+  selector: {}  # ditto=.spec.selector
+  a: {}  # ditto=.spec.b
+  b: {}  # ditto=.spec.a
+`,
+			expected: []string{
+				"4: configuration error: no config found for schema 'ReplicaSet' specified at path: .spec.template",
+				"5: configuration error: no config fragment found named 'volumes' specified at path: .spec.volumes",
+				"6: configuration error: ditto cycle '.spec.selector' -> '.spec.selector' specified at path: .spec.selector",
+				"7: configuration error: ditto cycle '.spec.a' -> '.spec.b' -> '.spec.a' specified at path: .spec.a",
+				"8: configuration error: ditto cycle '.spec.b' -> '.spec.a' -> '.spec.b' specified at path: .spec.b",
+			},
+		},
+		{
+			note: "relation paths and styles",
+			configYaml: `---
+kind: Deployment
+spec:
+  selector: {}  # subset-of=spec.template.metadata.labels, style=inline
+  template:
+    metadata:
+      labels: {}  # equals=.spec.selector
+`,
+			expected: []string{
+				"4: configuration error: unknown style 'inline', expected 'block' or 'flow' at path: .spec.selector",
+				"4: configuration error: 'subset-of' path 'spec.template.metadata.labels' must start with '.' at path: .spec.selector",
+			},
+		},
+		{
+			note: "groups with only one key",
+			configYaml: `---
+kind: Deployment
+spec:
+  selector: {}  # one-of=source
+  template: {}  # any-of=pod, one-of=pod
+  replicas: 1  # any-of=pod
+`,
+			expected: []string{
+				"4: configuration error: 'one-of' group 'source' has only one key 'selector' in the map at path '.spec'",
+				"5: configuration error: 'one-of' group 'pod' has only one key 'template' in the map at path '.spec'",
+			},
+		},
+		{
+			note: "dittos are not checked without configs",
+			configYaml: `---
+kind: Deployment
+spec:
+  template: {}  # ditto=ReplicaSet.spec.template
+`,
+			nilConfigs: true,
+			expected:   []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		configNodes := ConfigNodes{}
+		for _, y := range []string{podYaml, tc.configYaml} {
+			n := &yaml.Node{}
+			err := yaml.Unmarshal([]byte(y), n)
+			if err != nil {
+				t.Fatalf("Description: %s: compare.CheckConfig(...): failed unmarshaling test data: %v", tc.note, err)
+			}
+			node := &Node{Node: n}
+			WalkConvertYamlNodeToMainNode(node)
+			WalkParseLoadConfigComments(node)
+			configNodes[GetConfigName(node)] = node
+		}
+		configNode := configNodes["apps/*/Deployment"]
+		if configNode == nil {
+			configNode = configNodes["Deployment"]
+		}
+		if tc.nilConfigs {
+			configNodes = nil
+		}
+
+		got := []string{}
+		for _, problem := range CheckConfig(configNode, configNodes) {
+			got = append(got, fmt.Sprintf("%d: %v", problem.Line, problem.Err))
+		}
+		if strings.Join(got, "\n") != strings.Join(tc.expected, "\n") {
+			t.Errorf("Description: %s: compare.CheckConfig(...): \n-expected:\n%v\n+got:\n%v\n", tc.note, strings.Join(tc.expected, "\n"), strings.Join(got, "\n"))
+		}
+	}
+}
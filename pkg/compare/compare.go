@@ -33,7 +33,26 @@ type Node struct {
 	MustBeFirst bool
 	Required    bool
 	Preferred   bool
+	Open        bool
 	Ditto       string
+	Equals      string // path in the target document this key's value must equal
+	SubsetOf    string // path in the target document this key's value must be a subset of
+	BlankBefore bool   // a blank line must be above this key
+	NoBlank     bool   // no blank lines are allowed in this key's value
+	OneOf       string // group of keys of this map, exactly one of which must be present
+	AnyOf       string // group of keys of this map, at least one of which must be present
+	OmitEmpty   bool   // this key is removed when its value is an empty map or sequence, unless it's required
+	// CollectionStyle is 'block' or 'flow', the style of this key's map or sequence value
+	CollectionStyle string
+
+	// lookup caches for config nodes, see configPairs and getConfigValueNodeForDitto
+	pairs     []KeyValuePair
+	pairIndex map[string]int
+	dittoRoot *Node
+	dittoNode *Node
+
+	// line of a target file node before sorting replaced it with the config's, see FilePosition
+	fileLine int
 }
 
 // ConfigNodes is a map of names to Config Nodes
@@ -42,30 +61,25 @@ type ConfigNodes map[string]*Node
 // ValidationErrors allows us to return multiple validation errors
 type ValidationErrors []error
 
-// FileConfigs supports kind and overrides
+// FileConfigs supports kind, apiVersion, and overrides
 type FileConfigs struct {
 	Kind            string // config and target files
+	APIVersion      string // config and target files, may contain wildcards in config files
+	Fragment        string // config files only, referenced by dittos as '@name'
+	Extends         string // config files only, the name of the config this one is merged onto
 	Ignore          bool   // target files only
 	IgnoreRequireds bool   // target files only
 }
 
-// AddedField represents a required field that was added during sorting.
-type AddedField struct {
-	Path string // parent path, e.g. ".metadata.labels"
-	Key  string // key name, e.g. "app.kubernetes.io/name"
-}
-
-func (f AddedField) String() string {
-	return f.Path + "." + f.Key
-}
-
 // SortConfigs represent various configs for a sorting operation
 type SortConfigs struct {
 	ConfigNodes          ConfigNodes
 	FileConfigs          FileConfigs
 	UnmatchedToBeginning bool
 	AddPreferreds        bool
-	AddedFields          *[]AddedField
+	RenameSuggested      bool
+	Changes              *[]Change
+	Warnings             *ValidationErrors
 }
 
 // KeyValuePair represent a scalar key node, and it's related value node
@@ -80,63 +94,74 @@ var (
 	endsWithDot = regexp.MustCompile(`.*\.$`)
 )
 
-// GetFileConfigs parses comments for config info
+// GetFileConfigs parses comments for config info, falling back to top level kind and apiVersion values
 func GetFileConfigs(node *Node) FileConfigs {
+	fileConfigs := getCommentFileConfigs(node)
+
+	// check Kubernetes-esq Kind and apiVersion
+	if fileConfigs.Kind == "" {
+		fileConfigs.Kind = getTopLevelScalar(node, "kind")
+	}
+	if fileConfigs.APIVersion == "" {
+		fileConfigs.APIVersion = getTopLevelScalar(node, "apiVersion")
+	}
+
+	return fileConfigs
+}
+
+// getCommentFileConfigs parses '# predictable-yaml:' comments for config info.
+// Unknown and malformed directives are skipped, see CheckFileConfigs.
+func getCommentFileConfigs(node *Node) FileConfigs {
 	fileConfigs := FileConfigs{}
-	// check config comments
-	if len(node.NodeContent) != 0 {
-		for _, n := range node.NodeContent[0].NodeContent {
-			for _, comment := range []string{n.HeadComment, n.LineComment, n.FootComment} {
-				if comment == "" {
-					continue
-				}
-				commentLines := strings.Split(comment, "\n")
-				for _, commentLine := range commentLines {
-					if !strings.Contains(commentLine, "predictable-yaml:") {
-						continue
-					}
-					commentLine = strings.ReplaceAll(commentLine, "#", "")
-					commentLine = strings.ReplaceAll(commentLine, " ", "")
-					commentLine = strings.Split(commentLine, ":")[1]
-					splitStrings := strings.Split(commentLine, ",")
-					for _, str := range splitStrings {
-						switch {
-						case str == "ignore":
-							fileConfigs.Ignore = true
-						case str == "ignore-requireds":
-							fileConfigs.IgnoreRequireds = true
-						case strings.Contains(str, "kind"):
-							fileConfigs.Kind = strings.Split(str, "=")[1]
-						}
-					}
-				}
+	for _, comment := range getFileConfigComments(node) {
+		directives, _ := parseDirectives(comment.text, fileConfigDirectives)
+		for _, d := range directives {
+			switch d.name {
+			case "ignore":
+				fileConfigs.Ignore = true
+			case "ignore-requireds":
+				fileConfigs.IgnoreRequireds = true
+			case "extends":
+				fileConfigs.Extends = d.value
+			case "fragment":
+				fileConfigs.Fragment = d.value
+			case "apiVersion":
+				fileConfigs.APIVersion = d.value
+			case "kind":
+				fileConfigs.Kind = d.value
 			}
 		}
 	}
 
-	// check Kubernetes-esq Kind
-	if fileConfigs.Kind == "" {
-		if len(node.NodeContent) != 0 {
-			for index, n := range node.NodeContent[0].NodeContent {
-				if n.Value == "kind" {
-					if index+1 <= (len(node.NodeContent[0].NodeContent) - 1) {
-						valueNode := node.NodeContent[0].NodeContent[index+1]
-						if valueNode.Line != n.Line {
-							continue
-						}
-						fileConfigs.Kind = valueNode.Value
-					}
-					break
+	return fileConfigs
+}
+
+// getTopLevelScalar returns the value of a top level key, if it's on the same line as the key
+func getTopLevelScalar(node *Node, key string) string {
+	if len(node.NodeContent) == 0 {
+		return ""
+	}
+	for index, n := range node.NodeContent[0].NodeContent {
+		if n.Value == key {
+			if index+1 <= (len(node.NodeContent[0].NodeContent) - 1) {
+				valueNode := node.NodeContent[0].NodeContent[index+1]
+				if valueNode.Line != n.Line {
+					continue
 				}
+				return valueNode.Value
 			}
+			break
 		}
 	}
 
-	return fileConfigs
+	return ""
 }
 
 // WalkConvertYamlNodeToMainNode converts every *yaml.Node to a *main.Node with our customizations
 func WalkConvertYamlNodeToMainNode(node *Node) {
+	if len(node.Content) != 0 {
+		node.NodeContent = make([]*Node, 0, len(node.Content))
+	}
 	for index, innerNode := range node.Content {
 		n := &Node{
 			Node:       innerNode,
@@ -153,20 +178,36 @@ func WalkConvertYamlNodeToMainNode(node *Node) {
 // WalkParseLoadConfigComments loads the configs from the comments in a config file
 func WalkParseLoadConfigComments(node *Node) {
 	if node.LineComment != "" {
-		comment := strings.ReplaceAll(node.LineComment, "#", "")
-		comment = strings.ReplaceAll(comment, " ", "")
-		splitStrings := strings.Split(comment, ",")
+		directives, _ := parseDirectives(node.LineComment, configDirectives)
 		n := firstScalarOfLine(node)
-		for _, str := range splitStrings {
-			switch {
-			case str == "first":
+		for _, d := range directives {
+			switch d.name {
+			case "first":
 				n.MustBeFirst = true
-			case str == "required":
+			case "required":
 				n.Required = true
-			case str == "preferred":
+			case "preferred":
 				n.Preferred = true
-			case strings.Contains(str, "ditto"):
-				n.Ditto = strings.Split(str, "=")[1]
+			case "open":
+				n.Open = true
+			case "ditto":
+				n.Ditto = d.value
+			case "equals":
+				n.Equals = d.value
+			case "subset-of":
+				n.SubsetOf = d.value
+			case "blank-before":
+				n.BlankBefore = true
+			case "no-blank":
+				n.NoBlank = true
+			case "style":
+				n.CollectionStyle = d.value
+			case "one-of":
+				n.OneOf = d.value
+			case "any-of":
+				n.AnyOf = d.value
+			case "omit-empty":
+				n.OmitEmpty = true
 			}
 		}
 	}
@@ -236,32 +277,31 @@ func WalkFindNullValues(configNode, fileNode *Node, sortConfs SortConfigs, errs
 		if fileNode.Kind != yaml.MappingNode {
 			return errs
 		}
-		configPairs := GetKeyValuePairs(configNode.NodeContent)
+		configPairs, _ := configNode.configPairs()
 		filePairs := GetKeyValuePairs(fileNode.NodeContent)
+		fileIndex := indexKeyValuePairs(filePairs)
 		for _, configPair := range configPairs {
-			for _, filePair := range filePairs {
-				if filePair.Key != configPair.Key {
+			i, ok := fileIndex[configPair.Key]
+			if !ok {
+				continue
+			}
+			filePair := filePairs[i]
+			if filePair.ValueNode.Tag == "!!null" && configPair.ValueNode.Kind != yaml.ScalarNode {
+				errs = append(errs, NewDiagnostic(RuleNullValue, filePair.KeyNode, "validation error: null value at '%s' (line %d) — remove it or set a value", GetReferencePath(filePair.KeyNode, 0, ""), filePair.KeyNode.Line))
+				continue
+			}
+			if configPair.KeyNode.Ditto != "" {
+				cN, err := configNodeForDitto(configPair, filePair, sortConfs)
+				if err != nil {
 					continue
 				}
-				if filePair.ValueNode.Tag == "!!null" && configPair.ValueNode.Kind != yaml.ScalarNode {
-					errs = append(errs, fmt.Errorf("validation error: null value at '%s' — remove it or set a value", GetReferencePath(filePair.KeyNode, 0, "")))
-					break
-				}
-				if configPair.KeyNode.Ditto != "" {
-					cN, err := configNodeForDitto(configPair, filePair, sortConfs)
-					if err != nil {
-						break
-					}
-					if filePair.ValueNode.Tag == "!!null" && cN.Kind != yaml.ScalarNode {
-						errs = append(errs, fmt.Errorf("validation error: null value at '%s' — remove it or set a value", GetReferencePath(filePair.KeyNode, 0, "")))
-						break
-					}
-					errs = WalkFindNullValues(cN, filePair.ValueNode, sortConfs, errs)
-				} else {
-					errs = WalkFindNullValues(configPair.ValueNode, filePair.ValueNode, sortConfs, errs)
+				if filePair.ValueNode.Tag == "!!null" && cN.Kind != yaml.ScalarNode {
+					errs = append(errs, NewDiagnostic(RuleNullValue, filePair.KeyNode, "validation error: null value at '%s' (line %d) — remove it or set a value", GetReferencePath(filePair.KeyNode, 0, ""), filePair.KeyNode.Line))
+					continue
 				}
-
-				break
+				errs = WalkFindNullValues(cN, filePair.ValueNode, sortConfs, errs)
+			} else {
+				errs = WalkFindNullValues(configPair.ValueNode, filePair.ValueNode, sortConfs, errs)
 			}
 		}
 	case yaml.SequenceNode:
@@ -278,9 +318,141 @@ func WalkFindNullValues(configNode, fileNode *Node, sortConfs SortConfigs, errs
 	return errs
 }
 
+// WalkFindUnknownKeys walks the config and file trees together, returning errors for any
+// keys in the file that have no counterpart in the config. Keys under a map whose config
+// key is marked 'open' are allowed. This should be called before WalkAndSort so that the
+// reported line numbers are those of the original file.
+func WalkFindUnknownKeys(configNode, fileNode *Node, sortConfs SortConfigs, errs ValidationErrors) ValidationErrors {
+	return walkFindUnknownKeys(configNode, fileNode, sortConfs, false, errs)
+}
+
+// walkFindUnknownKeys does the work for WalkFindUnknownKeys, open being whether the
+// config key of the current map is marked 'open'
+func walkFindUnknownKeys(configNode, fileNode *Node, sortConfs SortConfigs, open bool, errs ValidationErrors) ValidationErrors {
+	switch configNode.Kind {
+	case yaml.DocumentNode:
+		if fileNode.Kind != yaml.DocumentNode || len(configNode.NodeContent) == 0 || len(fileNode.NodeContent) == 0 {
+			return errs
+		}
+
+		return walkFindUnknownKeys(configNode.NodeContent[0], fileNode.NodeContent[0], sortConfs, open, errs)
+	case yaml.MappingNode:
+		if fileNode.Kind != yaml.MappingNode {
+			return errs
+		}
+		configPairs, configIndex := configNode.configPairs()
+		for _, filePair := range GetKeyValuePairs(fileNode.NodeContent) {
+			i, ok := configIndex[filePair.Key]
+			if !ok {
+				if !open {
+					errs = append(errs, NewDiagnostic(RuleUnknownKey, filePair.KeyNode, "validation error: unknown key at '%s' (line %d) — not found in the config", GetReferencePath(filePair.KeyNode, 0, ""), filePair.KeyNode.Line))
+				}
+				continue
+			}
+			configPair := configPairs[i]
+			cN := configPair.ValueNode
+			if configPair.KeyNode.Ditto != "" {
+				var err error
+				cN, err = configNodeForDitto(configPair, filePair, sortConfs)
+				if err != nil {
+					continue
+				}
+			}
+			errs = walkFindUnknownKeys(cN, filePair.ValueNode, sortConfs, isOpen(configPair.KeyNode, cN), errs)
+		}
+	case yaml.SequenceNode:
+		if fileNode.Kind != yaml.SequenceNode || len(configNode.NodeContent) == 0 {
+			return errs
+		}
+		for _, fNode := range fileNode.NodeContent {
+			errs = walkFindUnknownKeys(configNode.NodeContent[0], fNode, sortConfs, open, errs)
+		}
+	}
+
+	return errs
+}
+
+// walkConfigKeys calls visit for each file key found in the config, with its config key
+func walkConfigKeys(configNode, fileNode *Node, sortConfs SortConfigs, visit func(configKeyNode *Node, filePair KeyValuePair)) {
+	walkConfig(configNode, fileNode, sortConfs, nil, visit)
+}
+
+// walkConfigMaps calls visit for each file map found in the config, with its config map
+func walkConfigMaps(configNode, fileNode *Node, sortConfs SortConfigs, visit func(configMapNode, fileMapNode *Node)) {
+	walkConfig(configNode, fileNode, sortConfs, visit, nil)
+}
+
+// walkConfig walks the config and file trees together, calling visitMap for each file map
+// and visitKey for each file key found in the config. Either may be nil.
+func walkConfig(configNode, fileNode *Node, sortConfs SortConfigs, visitMap func(configMapNode, fileMapNode *Node), visitKey func(configKeyNode *Node, filePair KeyValuePair)) {
+	switch configNode.Kind {
+	case yaml.DocumentNode:
+		if fileNode.Kind != yaml.DocumentNode || len(configNode.NodeContent) == 0 || len(fileNode.NodeContent) == 0 {
+			return
+		}
+		walkConfig(configNode.NodeContent[0], fileNode.NodeContent[0], sortConfs, visitMap, visitKey)
+	case yaml.MappingNode:
+		if fileNode.Kind != yaml.MappingNode {
+			return
+		}
+		if visitMap != nil {
+			visitMap(configNode, fileNode)
+		}
+		configPairs, _ := configNode.configPairs()
+		filePairs := GetKeyValuePairs(fileNode.NodeContent)
+		fileIndex := indexKeyValuePairs(filePairs)
+		for _, configPair := range configPairs {
+			i, ok := fileIndex[configPair.Key]
+			if !ok {
+				continue
+			}
+			filePair := filePairs[i]
+			if visitKey != nil {
+				visitKey(configPair.KeyNode, filePair)
+			}
+			cN := configPair.ValueNode
+			if configPair.KeyNode.Ditto != "" {
+				var err error
+				cN, err = configNodeForDitto(configPair, filePair, sortConfs)
+				if err != nil {
+					continue
+				}
+			}
+			walkConfig(cN, filePair.ValueNode, sortConfs, visitMap, visitKey)
+		}
+	case yaml.SequenceNode:
+		if fileNode.Kind != yaml.SequenceNode || len(configNode.NodeContent) == 0 {
+			return
+		}
+		for _, fNode := range fileNode.NodeContent {
+			walkConfig(configNode.NodeContent[0], fNode, sortConfs, visitMap, visitKey)
+		}
+	}
+}
+
+// isOpen returns whether the file value of a config key may have keys not in the config, which is when
+// the key, or the key its ditto resolves to, is marked 'open'. configValueNode is the resolved value node.
+func isOpen(configKeyNode, configValueNode *Node) bool {
+	if configKeyNode.Open {
+		return true
+	}
+	if configKeyNode.Ditto == "" {
+		return false
+	}
+	keyNode := keyNodeOf(configValueNode)
+
+	return keyNode != nil && keyNode.Open
+}
+
 // WalkAndSort walks the tree and sorts the .Content and .NodeContent.
 // Returns validation errors and whether any changes were made.
 func WalkAndSort(configNode, fileNode *Node, sortConfs SortConfigs, errs ValidationErrors) (ValidationErrors, bool) {
+	return walkAndSort(configNode, fileNode, sortConfs, false, errs)
+}
+
+// walkAndSort does the work for WalkAndSort, open being whether the config key of the current map is
+// marked 'open', whose keys are user data rather than likely misspellings of config keys
+func walkAndSort(configNode, fileNode *Node, sortConfs SortConfigs, open bool, errs ValidationErrors) (ValidationErrors, bool) {
 	changed := false
 	switch configNode.Kind {
 	case yaml.DocumentNode:
@@ -288,40 +460,40 @@ func WalkAndSort(configNode, fileNode *Node, sortConfs SortConfigs, errs Validat
 			return append(errs, fmt.Errorf("program error: expected Document: '%s'", GetReferencePath(fileNode, 0, ""))), false
 		}
 
-		return WalkAndSort(configNode.NodeContent[0], fileNode.NodeContent[0], sortConfs, errs)
+		return walkAndSort(configNode.NodeContent[0], fileNode.NodeContent[0], sortConfs, open, errs)
 	case yaml.MappingNode:
 		if fileNode.Kind != yaml.MappingNode {
 			return append(errs, fmt.Errorf("program error: expected Map: '%s'", GetReferencePath(fileNode, 0, ""))), false
 		}
 
 		// do the sorting
-		if sortNodes(configNode, fileNode, sortConfs) {
+		if sortNodes(configNode, fileNode, sortConfs, open) {
 			changed = true
 		}
 
 		// walk and sort the contents
-		configPairs := GetKeyValuePairs(configNode.NodeContent)
+		configPairs, _ := configNode.configPairs()
 		filePairs := GetKeyValuePairs(fileNode.NodeContent)
+		fileIndex := indexKeyValuePairs(filePairs)
 		for _, configPair := range configPairs {
-			for _, filePair := range filePairs {
-				if filePair.Key != configPair.Key {
+			i, ok := fileIndex[configPair.Key]
+			if !ok {
+				continue
+			}
+			filePair := filePairs[i]
+			cN := configPair.ValueNode
+			if configPair.KeyNode.Ditto != "" {
+				var err error
+				cN, err = configNodeForDitto(configPair, filePair, sortConfs)
+				if err != nil {
+					errs = append(errs, err)
 					continue
 				}
-				var childChanged bool
-				if configPair.KeyNode.Ditto == "" {
-					errs, childChanged = WalkAndSort(configPair.ValueNode, filePair.ValueNode, sortConfs, errs)
-				} else {
-					cN, err := configNodeForDitto(configPair, filePair, sortConfs)
-					if err != nil {
-						errs = append(errs, err)
-						break
-					}
-					errs, childChanged = WalkAndSort(cN, filePair.ValueNode, sortConfs, errs)
-				}
-				if childChanged {
-					changed = true
-				}
-				break
+			}
+			var childChanged bool
+			errs, childChanged = walkAndSort(cN, filePair.ValueNode, sortConfs, isOpen(configPair.KeyNode, cN), errs)
+			if childChanged {
+				changed = true
 			}
 		}
 	case yaml.SequenceNode:
@@ -334,15 +506,9 @@ func WalkAndSort(configNode, fileNode *Node, sortConfs SortConfigs, errs Validat
 		// This avoids adding entries to sequences the user intentionally left empty.
 		parentKeyIsRequired := false
 		parentKeyIsPreferred := false
-		if configNode.ParentNode != nil && configNode.ParentNode.Kind == yaml.MappingNode {
-			for i, n := range configNode.ParentNode.NodeContent {
-				if n.Kind == yaml.ScalarNode && i+1 < len(configNode.ParentNode.NodeContent) &&
-					configNode.ParentNode.NodeContent[i+1] == configNode {
-					parentKeyIsRequired = n.Required
-					parentKeyIsPreferred = n.Preferred
-					break
-				}
-			}
+		if keyNode := keyNodeOf(configNode); keyNode != nil {
+			parentKeyIsRequired = keyNode.Required
+			parentKeyIsPreferred = keyNode.Preferred
 		}
 		shouldPopulate := (parentKeyIsRequired && !sortConfs.FileConfigs.IgnoreRequireds) ||
 			(parentKeyIsPreferred && sortConfs.AddPreferreds && !sortConfs.FileConfigs.IgnoreRequireds)
@@ -368,7 +534,14 @@ func WalkAndSort(configNode, fileNode *Node, sortConfs SortConfigs, errs Validat
 				fileNode.NodeContent = append(fileNode.NodeContent, newNode)
 				fileNode.Content = append(fileNode.Content, newYamlNode)
 				changed = true
-				errs, _ = WalkAndSort(configNode.NodeContent[0], fileNode.NodeContent[0], sortConfs, errs)
+				sortConfs.recordChange(Change{
+					Type:      SequenceItemAdded,
+					Path:      sequencePath(fileNode),
+					From:      -1,
+					ValueKind: yaml.MappingNode,
+					Node:      newNode,
+				})
+				errs, _ = walkAndSort(configNode.NodeContent[0], fileNode.NodeContent[0], sortConfs, open, errs)
 			}
 		} else if shouldPopulate &&
 			len(configNode.NodeContent) > 0 &&
@@ -386,13 +559,21 @@ func WalkAndSort(configNode, fileNode *Node, sortConfs SortConfigs, errs Validat
 			fileNode.NodeContent = append(fileNode.NodeContent, newNode)
 			fileNode.Content = append(fileNode.Content, newYamlNode)
 			changed = true
+			sortConfs.recordChange(Change{
+				Type:      SequenceItemAdded,
+				Path:      sequencePath(fileNode),
+				From:      -1,
+				ValueKind: yaml.ScalarNode,
+				Value:     newYamlNode.Value,
+				Node:      newNode,
+			})
 		} else {
 			if len(configNode.NodeContent) == 0 {
 				return errs, false
 			}
 			for _, fNode := range fileNode.NodeContent {
 				var childChanged bool
-				errs, childChanged = WalkAndSort(configNode.NodeContent[0], fNode, sortConfs, errs)
+				errs, childChanged = walkAndSort(configNode.NodeContent[0], fNode, sortConfs, open, errs)
 				if childChanged {
 					changed = true
 				}
@@ -407,26 +588,70 @@ func WalkAndSort(configNode, fileNode *Node, sortConfs SortConfigs, errs Validat
 	return errs, changed
 }
 
-func sortNodes(configNode, fileNode *Node, sortConfs SortConfigs) bool {
+func sortNodes(configNode, fileNode *Node, sortConfs SortConfigs, open bool) bool {
 	// for each line in the config, put matching file line in new slice
-	newNodeContent := []*Node{}
-	configPairs := GetKeyValuePairs(configNode.NodeContent)
+	newNodeContent := make([]*Node, 0, len(fileNode.NodeContent))
+	configPairs, configIndex := configNode.configPairs()
 	filePairs := GetKeyValuePairs(fileNode.NodeContent)
+	fileIndex := indexKeyValuePairs(filePairs)
+
+	// suggest config keys for likely misspelled unknown keys, renaming them if asked to. keys of open
+	//   maps, like labels, are user data, not misspellings
+	renamed := false
+	suggested := map[string]bool{}
+	for i, filePair := range filePairs {
+		if _, ok := configIndex[filePair.Key]; ok || open {
+			continue
+		}
+		candidates := suggestKeys(filePair.Key, configPairs, fileIndex)
+		switch {
+		case len(candidates) == 1 && sortConfs.RenameSuggested:
+			sortConfs.recordChange(Change{
+				Type:      KeyRenamed,
+				Path:      GetReferencePath(fileNode, 0, ""),
+				Key:       candidates[0],
+				OldKey:    filePair.Key,
+				From:      i,
+				To:        i,
+				ValueKind: filePair.ValueNode.Kind,
+				Value:     filePair.ValueNode.Value,
+				Node:      filePair.KeyNode,
+			})
+			filePair.KeyNode.Value = candidates[0]
+			filePairs[i].Key = candidates[0]
+			fileIndex[candidates[0]] = i
+			renamed = true
+		case len(candidates) != 0 && sortConfs.Warnings != nil:
+			for _, candidate := range candidates {
+				suggested[candidate] = true
+			}
+			*sortConfs.Warnings = append(*sortConfs.Warnings, suggestionWarning(filePair.KeyNode, candidates))
+		}
+	}
+
+	// line of the map, for warnings. read before lines are replaced with config lines below
+	mapLine := 0
+	if len(filePairs) != 0 {
+		mapLine = filePairs[0].KeyNode.Line
+	}
 
 	for _, configPair := range configPairs {
 		// find matching keyValuePair and append it
-		found := false
-		for _, filePair := range filePairs {
-			if filePair.Key == configPair.Key {
-				found = true
-				filePair.KeyNode.Node.Line = configPair.KeyNode.Line
-				filePair.ValueNode.Node.Line = configPair.ValueNode.Line
-				newNodeContent = append(newNodeContent, filePair.KeyNode, filePair.ValueNode)
-				break
-			}
+		i, found := fileIndex[configPair.Key]
+		if found {
+			filePair := filePairs[i]
+			keepFileLine(filePair.KeyNode)
+			keepFileLine(filePair.ValueNode)
+			filePair.KeyNode.Node.Line = configPair.KeyNode.Line
+			filePair.ValueNode.Node.Line = configPair.ValueNode.Line
+			newNodeContent = append(newNodeContent, filePair.KeyNode, filePair.ValueNode)
 		}
 
-		// possibly add missing required and preferred keys/values
+		// possibly add missing required and preferred keys/values, except members of
+		//   'one-of' and 'any-of' groups, which can't all be added
+		if configPair.KeyNode.OneOf != "" || configPair.KeyNode.AnyOf != "" {
+			continue
+		}
 		if (!found && configPair.KeyNode.Required && !sortConfs.FileConfigs.IgnoreRequireds) ||
 			(!found && configPair.KeyNode.Preferred && sortConfs.AddPreferreds && !sortConfs.FileConfigs.IgnoreRequireds) {
 			newValueYamlNode := &yaml.Node{
@@ -435,7 +660,7 @@ func sortNodes(configNode, fileNode *Node, sortConfs SortConfigs) bool {
 			}
 			newValueNode := &Node{
 				Node:       newValueYamlNode,
-				ParentNode: fileNode.ParentNode,
+				ParentNode: fileNode,
 			}
 			newKeyYamlNode := &yaml.Node{
 				Kind:  configPair.KeyNode.Node.Kind,
@@ -443,46 +668,41 @@ func sortNodes(configNode, fileNode *Node, sortConfs SortConfigs) bool {
 			}
 			newKeyNode := &Node{
 				Node:       newKeyYamlNode,
-				ParentNode: fileNode.ParentNode,
+				ParentNode: fileNode,
 			}
 
 			newNodeContent = append(newNodeContent, newKeyNode, newValueNode)
 
-			// track added fields
-			if sortConfs.AddedFields != nil {
-				*sortConfs.AddedFields = append(*sortConfs.AddedFields, AddedField{
-					Path: GetReferencePath(fileNode, 0, ""),
-					Key:  configPair.Key,
-				})
-			}
-
 			// set the style to match the parent. this prevents
 			//   inline representations in many cases.
 			fileNode.Node.Style = fileNode.ParentNode.Style
+		} else if !found && configPair.KeyNode.Preferred && !suggested[configPair.Key] &&
+			!sortConfs.FileConfigs.IgnoreRequireds && sortConfs.Warnings != nil {
+			// a preferred key already offered as a suggestion isn't reported twice
+			*sortConfs.Warnings = append(*sortConfs.Warnings, missingPreferredWarning(fileNode, configPair.Key, mapLine))
 		}
 	}
 
 	// put the remaining nodes at the end or beginning
+	unmatched := []*Node{}
 	for _, filePair := range filePairs {
-		found := false
-		for _, configPair := range configPairs {
-			if filePair.Key == configPair.Key {
-				found = true
-				break
-			}
+		if _, found := configIndex[filePair.Key]; !found {
+			unmatched = append(unmatched, filePair.KeyNode, filePair.ValueNode)
 		}
-
-		if !found {
-			if sortConfs.UnmatchedToBeginning {
-				newNodeContent = append([]*Node{filePair.KeyNode, filePair.ValueNode}, newNodeContent...)
-			} else {
-				newNodeContent = append(newNodeContent, filePair.KeyNode, filePair.ValueNode)
-			}
+	}
+	if sortConfs.UnmatchedToBeginning {
+		// each unmatched pair is placed before the previous one
+		reversed := make([]*Node, 0, len(unmatched)+len(newNodeContent))
+		for i := len(unmatched) - 2; i >= 0; i -= 2 {
+			reversed = append(reversed, unmatched[i], unmatched[i+1])
 		}
+		newNodeContent = append(reversed, newNodeContent...)
+	} else {
+		newNodeContent = append(newNodeContent, unmatched...)
 	}
 
 	// detect if the ordering changed
-	changed := len(newNodeContent) != len(fileNode.NodeContent)
+	changed := renamed || len(newNodeContent) != len(fileNode.NodeContent)
 	if !changed {
 		for i, node := range newNodeContent {
 			if node != fileNode.NodeContent[i] {
@@ -492,10 +712,58 @@ func sortNodes(configNode, fileNode *Node, sortConfs SortConfigs) bool {
 		}
 	}
 
+	// record what was added or moved, in the new order
+	if changed && sortConfs.Changes != nil {
+		oldIndex := make(map[*Node]int, len(filePairs))
+		for i, filePair := range filePairs {
+			oldIndex[filePair.KeyNode] = i
+		}
+		path := GetReferencePath(fileNode, 0, "")
+		newPairs := GetKeyValuePairs(newNodeContent)
+		for to, pair := range newPairs {
+			from, ok := oldIndex[pair.KeyNode]
+			if ok && from == to {
+				continue
+			}
+			changeType, rule := KeyMoved, ""
+			configPairIndex, inConfig := configIndex[pair.Key]
+			switch {
+			case !ok:
+				changeType = KeyAdded
+				from = -1
+				if configKeyNode := configPairs[configPairIndex].KeyNode; configKeyNode.Preferred && !configKeyNode.Required {
+					rule = RulePreferred
+				}
+			case !inConfig:
+				changeType = UnmatchedRelocated
+			case to == 0 && configPairs[configPairIndex].KeyNode.MustBeFirst:
+				rule = RuleFirst
+			}
+			var after *Node
+			if to > 0 {
+				after = newPairs[to-1].KeyNode
+			}
+			sortConfs.recordChange(Change{
+				Type:      changeType,
+				Rule:      rule,
+				Path:      path,
+				Key:       pair.Key,
+				From:      from,
+				To:        to,
+				ValueKind: pair.ValueNode.Kind,
+				Value:     pair.ValueNode.Value,
+				Node:      pair.KeyNode,
+				After:     after,
+			})
+		}
+	}
+
 	fileNode.NodeContent = newNodeContent
 
-	newContent := []*yaml.Node{}
-	for _, node := range newNodeContent {
+	newContent := make([]*yaml.Node, 0, len(newNodeContent))
+	for index, node := range newNodeContent {
+		// keep indexes current so reference paths resolve after reordering
+		node.Index = index
 		newContent = append(newContent, node.Node)
 	}
 	fileNode.Content = newContent
@@ -503,25 +771,80 @@ func sortNodes(configNode, fileNode *Node, sortConfs SortConfigs) bool {
 	return changed
 }
 
+// keepFileLine remembers the line of a target file node, before it's replaced with the config's
+func keepFileLine(node *Node) {
+	if node.fileLine == 0 {
+		node.fileLine = node.Line
+	}
+}
+
+// sequencePath returns the reference path of a sequence node, without an item index
+func sequencePath(node *Node) string {
+	return strings.TrimSuffix(GetReferencePath(node, 0, ""), "[0]")
+}
+
 func getConfigValueNodeForDitto(configPair KeyValuePair, sortConfs SortConfigs) (*Node, error) {
+	return resolveDitto(configPair, sortConfs, []*Node{})
+}
+
+// resolveDitto finds the config value node for a ditto, following dittos to keys that are themselves dittos.
+// followed holds the key nodes followed so far, to detect cycles.
+func resolveDitto(configPair KeyValuePair, sortConfs SortConfigs, followed []*Node) (*Node, error) {
+	for i, keyNode := range followed {
+		if keyNode != configPair.KeyNode {
+			continue
+		}
+		cycle := []string{GetReferencePath(keyNode, 0, "")}
+		for _, n := range followed[i:] {
+			cycle = append(cycle, n.Ditto)
+		}
+		filePath := GetReferencePath(followed[0], 0, "")
+		return nil, fmt.Errorf("configuration error: ditto cycle '%s' specified at path: %s", strings.Join(cycle, "' -> '"), filePath)
+	}
+	followed = append(followed, configPair.KeyNode)
+
 	rootNode := &Node{}
 	dittoPath := configPair.KeyNode.Ditto
 	if startDot.MatchString(configPair.KeyNode.Ditto) {
 		// is local path
 		rootNode = walkToRootNode(configPair.KeyNode)
 	} else {
-		// is path in another config
-		dittoKind := strings.Split(configPair.KeyNode.Ditto, `.`)[0]
-		dittoPath = fmt.Sprintf(".%s", strings.Join(strings.Split(configPair.KeyNode.Ditto, `.`)[1:], `.`))
+		// is path in another config, named by kind or qualified by apiVersion, or in a fragment named '@name'
+		dittoKind := ""
 		ok := false
-		rootNode, ok = sortConfs.ConfigNodes[dittoKind]
+		rootNode, dittoKind, dittoPath, ok = sortConfs.ConfigNodes.findDitto(configPair.KeyNode.Ditto)
 		if !ok {
 			filePath := GetReferencePath(configPair.KeyNode, 0, "")
+			if strings.HasPrefix(dittoKind, fragmentPrefix) {
+				return nil, fmt.Errorf("configuration error: no config fragment found named '%s' specified at path: %s", strings.TrimPrefix(dittoKind, fragmentPrefix), filePath)
+			}
 			err := fmt.Errorf("configuration error: no config found for schema '%s' specified at path: %s", dittoKind, filePath)
 			return nil, err
 		}
 	}
 
+	// the result only depends on the root config, so reuse it across target files
+	if configPair.KeyNode.dittoNode != nil && configPair.KeyNode.dittoRoot == rootNode {
+		return configPair.KeyNode.dittoNode, nil
+	}
+	valueNode, err := walkToDittoValueNode(configPair, rootNode, dittoPath)
+	if err != nil {
+		return nil, err
+	}
+	if keyNode := keyNodeOf(valueNode); keyNode != nil && keyNode.Ditto != "" {
+		valueNode, err = resolveDitto(KeyValuePair{Key: keyNode.Value, KeyNode: keyNode, ValueNode: valueNode}, sortConfs, followed)
+		if err != nil {
+			return nil, err
+		}
+	}
+	configPair.KeyNode.dittoRoot = rootNode
+	configPair.KeyNode.dittoNode = valueNode
+
+	return valueNode, nil
+}
+
+// walkToDittoValueNode finds the config value node for dittoPath under rootNode.
+func walkToDittoValueNode(configPair KeyValuePair, rootNode *Node, dittoPath string) (*Node, error) {
 	cN, err := walkToNodeForPath(rootNode, dittoPath, 0)
 	if err != nil {
 		return nil, err
@@ -549,6 +872,47 @@ func getConfigValueNodeForDitto(configPair KeyValuePair, sortConfs SortConfigs)
 	return valueNode, nil
 }
 
+// configPairs returns the KeyValuePairs of a config mapping node and an index
+// of key to pair position, building them on first use. Config nodes are never
+// reordered, so both are reused for every target file.
+func (n *Node) configPairs() ([]KeyValuePair, map[string]int) {
+	if n.pairIndex == nil {
+		n.pairs = GetKeyValuePairs(n.NodeContent)
+		n.pairIndex = indexKeyValuePairs(n.pairs)
+	}
+
+	return n.pairs, n.pairIndex
+}
+
+// indexKeyValuePairs maps each key to the position of its first occurrence in pairs
+func indexKeyValuePairs(pairs []KeyValuePair) map[string]int {
+	index := make(map[string]int, len(pairs))
+	for i, pair := range pairs {
+		if _, ok := index[pair.Key]; !ok {
+			index[pair.Key] = i
+		}
+	}
+
+	return index
+}
+
+// keyNodeOf returns the key node for a mapping value node, or nil
+func keyNodeOf(node *Node) *Node {
+	parent := node.ParentNode
+	if parent == nil || parent.Kind != yaml.MappingNode {
+		return nil
+	}
+	if node.Index < 1 || node.Index >= len(parent.NodeContent) || parent.NodeContent[node.Index] != node {
+		return nil
+	}
+	keyNode := parent.NodeContent[node.Index-1]
+	if keyNode.Kind != yaml.ScalarNode {
+		return nil
+	}
+
+	return keyNode
+}
+
 // GetKeyValuePairs builds a list of KeyValuePairs
 func GetKeyValuePairs(nodeContent []*Node) []KeyValuePair {
 	if !(len(nodeContent)%2 == 0) {
@@ -658,14 +1022,11 @@ func firstScalarOfLine(node *Node) *Node {
 	if node.ParentNode == nil {
 		panic(fmt.Sprintf("internal error: expected node to have parentNode, value: %q, line: %d", node.Value, node.Line))
 	}
-	for index, innerNode := range node.ParentNode.NodeContent {
-		if innerNode == node {
-			if index-1 >= 0 {
-				n := node.ParentNode.NodeContent[index-1]
-				if n.Kind == yaml.ScalarNode && n.Line == node.Line {
-					return n
-				}
-			}
+	siblings := node.ParentNode.NodeContent
+	if node.Index-1 >= 0 && node.Index < len(siblings) && siblings[node.Index] == node {
+		n := siblings[node.Index-1]
+		if n.Kind == yaml.ScalarNode && n.Line == node.Line {
+			return n
 		}
 	}
 
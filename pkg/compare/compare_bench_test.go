@@ -0,0 +1,147 @@
+/*
+Copyright © 2022 david amick git@davidamick.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package compare
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// largeMappingYamls builds a ConfigMap-like config and a target file with keyCount
+// keys under .data. The target lists the keys in reverse order, with every
+// tenth key missing from the config so unmatched keys are exercised too.
+func largeMappingYamls(keyCount int) (string, string) {
+	var config, file strings.Builder
+	config.WriteString("apiVersion: v1  # first, required\nkind: ConfigMap  # required\nmetadata:\n  name: example  # first, required\ndata:  # required\n")
+	for i := 0; i < keyCount; i++ {
+		if i%10 == 0 {
+			continue
+		}
+		fmt.Fprintf(&config, "  key-%05d: value\n", i)
+	}
+	file.WriteString("kind: ConfigMap\napiVersion: v1\ndata:\n")
+	for i := keyCount - 1; i >= 0; i-- {
+		fmt.Fprintf(&file, "  key-%05d: value-%d\n", i, i)
+	}
+	file.WriteString("metadata:\n  name: example\n")
+
+	return config.String(), file.String()
+}
+
+// largeSchemaYamls builds a CRD-schema-like config and target file: depth levels of
+// nested maps, each with width keys, the target file in reverse order.
+func largeSchemaYamls(width, depth int) (string, string) {
+	var config, file strings.Builder
+	config.WriteString("kind: Schema  # required\nspec:\n")
+	file.WriteString("spec:\n")
+	var writeLevel func(builder *strings.Builder, level int, reverse bool)
+	writeLevel = func(builder *strings.Builder, level int, reverse bool) {
+		indent := strings.Repeat("  ", level+1)
+		for i := 0; i < width; i++ {
+			index := i
+			if reverse {
+				index = width - 1 - i
+			}
+			if level+1 < depth && index == width/2 {
+				fmt.Fprintf(builder, "%sfield-%04d:\n", indent, index)
+				writeLevel(builder, level+1, reverse)
+				continue
+			}
+			fmt.Fprintf(builder, "%sfield-%04d: value\n", indent, index)
+		}
+	}
+	writeLevel(&config, 0, false)
+	writeLevel(&file, 0, true)
+	file.WriteString("kind: Schema\n")
+
+	return config.String(), file.String()
+}
+
+func parseBenchConfig(b *testing.B, configYaml string) (ConfigNodes, *Node) {
+	b.Helper()
+	cN := &yaml.Node{}
+	if err := yaml.Unmarshal([]byte(configYaml), cN); err != nil {
+		b.Fatalf("failed unmarshaling config bench data: %v", err)
+	}
+	configNode := &Node{Node: cN}
+	WalkConvertYamlNodeToMainNode(configNode)
+	WalkParseLoadConfigComments(configNode)
+	fileConfigs := GetFileConfigs(configNode)
+	if fileConfigs.Kind == "" {
+		b.Fatalf("failed getting kind for config bench data")
+	}
+
+	return ConfigNodes{fileConfigs.Kind: configNode}, configNode
+}
+
+func benchmarkWalkAndSort(b *testing.B, configYaml, fileYaml string) {
+	configNodes, configNode := parseBenchConfig(b, configYaml)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		fN := &yaml.Node{}
+		if err := yaml.Unmarshal([]byte(fileYaml), fN); err != nil {
+			b.Fatalf("failed unmarshaling file bench data: %v", err)
+		}
+		fileNode := &Node{Node: fN}
+		WalkConvertYamlNodeToMainNode(fileNode)
+		sortConfs := SortConfigs{ConfigNodes: configNodes, FileConfigs: GetFileConfigs(fileNode)}
+		b.StartTimer()
+
+		errs := WalkFindNullValues(configNode, fileNode, sortConfs, ValidationErrors{})
+		errs, _ = WalkAndSort(configNode, fileNode, sortConfs, errs)
+		if len(errs) != 0 {
+			b.Fatalf("unexpected errors:\n%s", GetValidationErrorStrings(errs))
+		}
+	}
+}
+
+func BenchmarkWalkAndSortLargeMapping(b *testing.B) {
+	for _, keyCount := range []int{100, 1000, 5000} {
+		configYaml, fileYaml := largeMappingYamls(keyCount)
+		b.Run(fmt.Sprintf("keys=%d", keyCount), func(b *testing.B) {
+			benchmarkWalkAndSort(b, configYaml, fileYaml)
+		})
+	}
+}
+
+func BenchmarkWalkAndSortLargeSchema(b *testing.B) {
+	for _, width := range []int{50, 500} {
+		configYaml, fileYaml := largeSchemaYamls(width, 4)
+		b.Run(fmt.Sprintf("width=%d", width), func(b *testing.B) {
+			benchmarkWalkAndSort(b, configYaml, fileYaml)
+		})
+	}
+}
+
+func BenchmarkWalkParseLoadConfigComments(b *testing.B) {
+	configYaml, _ := largeMappingYamls(5000)
+	cN := &yaml.Node{}
+	if err := yaml.Unmarshal([]byte(configYaml), cN); err != nil {
+		b.Fatalf("failed unmarshaling config bench data: %v", err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		configNode := &Node{Node: cN}
+		WalkConvertYamlNodeToMainNode(configNode)
+		WalkParseLoadConfigComments(configNode)
+	}
+}
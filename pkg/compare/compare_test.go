@@ -18,6 +18,7 @@ package compare
 import (
 	"bytes"
 	"fmt"
+	"reflect"
 	"testing"
 
 	// "github.com/kylelemons/godebug/diff"
@@ -227,6 +228,7 @@ func TestGetFileConfigs(t *testing.T) {
 		note                   string
 		yaml                   string
 		expectedKind           string
+		expectedAPIVersion     string
 		expectedIgnore         bool
 		expectedIgnoreRequired bool
 	}
@@ -286,6 +288,7 @@ kind: Deployment
 spec:
   asdf: fdsa`,
 			expectedKind:           "Deployment",
+			expectedAPIVersion:     "TODO",
 			expectedIgnoreRequired: true,
 			expectedIgnore:         false,
 		},
@@ -300,9 +303,42 @@ kind: Deployment
 spec:
   asdf: fdsa`,
 			expectedKind:           "Deployment",
+			expectedAPIVersion:     "TODO",
 			expectedIgnoreRequired: true,
 			expectedIgnore:         false,
 		},
+		{
+			note: "comment apiVersion with wildcard",
+			yaml: `---
+# predictable-yaml: kind=Gateway, apiVersion=gateway.networking.k8s.io/*
+apiVersion: gateway.networking.k8s.io/v1
+kind: Gateway`,
+			expectedKind:       "Gateway",
+			expectedAPIVersion: "gateway.networking.k8s.io/*",
+		},
+		{
+			note: "quoted kind with colon and prose",
+			yaml: `---
+# predictable-yaml: kind="my:schema", ignore  # generated, don't sort
+kind: Deployment`,
+			expectedKind:   "my:schema",
+			expectedIgnore: true,
+		},
+		{
+			note: "unknown directive is skipped",
+			yaml: `---
+# predictable-yaml: ignore-requried, kind=generic
+kind: Deployment`,
+			expectedKind: "generic",
+		},
+		{
+			note: "regular apiVersion",
+			yaml: `---
+apiVersion: networking.istio.io/v1beta1
+kind: Gateway`,
+			expectedKind:       "Gateway",
+			expectedAPIVersion: "networking.istio.io/v1beta1",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -320,6 +356,9 @@ spec:
 		if got.Kind != tc.expectedKind {
 			t.Errorf("Description: %s: compare.GetFileConfigs(...): \n-expected:\n%#v\n+got:\n%#v\n", tc.note, tc.expectedKind, got)
 		}
+		if got.APIVersion != tc.expectedAPIVersion {
+			t.Errorf("Description: %s: compare.GetFileConfigs(...): \n-expected:\n%#v\n+got:\n%#v\n", tc.note, tc.expectedAPIVersion, got.APIVersion)
+		}
 		if got.Ignore != tc.expectedIgnore {
 			t.Errorf("Description: %s: compare.GetFileConfigs(...): \n-expected:\n%#v\n+got:\n%#v\n", tc.note, tc.expectedIgnoreRequired, got.Ignore)
 		}
@@ -417,6 +456,7 @@ func TestWalkParseLoadConfigComments(t *testing.T) {
 		first     bool
 		required  bool
 		preferred bool
+		open      bool
 		ditto     string
 	}
 
@@ -433,6 +473,7 @@ spec:  # first
         - asdf
         args:
         - asdf
+        env: []  # open
 `
 
 	testCases := []testCase{
@@ -490,6 +531,16 @@ spec:  # first
 			preferred: true,
 			ditto:     "",
 		},
+		{
+			note:      ".spec.template.spec.containers[0].env: open",
+			yaml:      testYamlContainers,
+			path:      ".spec.template.spec.containers[0].env",
+			first:     false,
+			required:  false,
+			preferred: false,
+			open:      true,
+			ditto:     "",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -526,6 +577,9 @@ spec:  # first
 		if childNode.Preferred != tc.preferred {
 			t.Errorf("Description: %s: compare.WalkParseLoadConfigComments(...): -expected, +got:\n-%#v\n+%#v\n", tc.note, tc.preferred, childNode.Preferred)
 		}
+		if childNode.Open != tc.open {
+			t.Errorf("Description: %s: compare.WalkParseLoadConfigComments(...): -expected, +got:\n-%#v\n+%#v\n", tc.note, tc.open, childNode.Open)
+		}
 		if childNode.Ditto != tc.ditto {
 			t.Errorf("Description: %s: compare.WalkParseLoadConfigComments(...): -expected, +got:\n-%#v\n+%#v\n", tc.note, tc.ditto, childNode.Ditto)
 		}
@@ -1253,6 +1307,137 @@ kind: Gateway
 spec:
   gatewayClassName: envoy-ingress
   listeners: []
+`,
+		},
+		{
+			note:         "multiple unmatched keys to beginning",
+			toBeginning:  true,
+			expectedErrs: ValidationErrors{},
+			configYamls: []string{
+				`---
+kind: ConfigMap  # first, required
+data:
+  one: TODO
+  two: TODO`},
+			fileYaml: `---
+data:
+  two: b
+  alpha: x
+  one: a
+  beta: y
+kind: ConfigMap`,
+			expectedYaml: `kind: ConfigMap
+data:
+  beta: y
+  alpha: x
+  one: a
+  two: b
+`,
+		},
+		{
+			note:         "cross-schema ditto to a config qualified by an apiVersion pattern",
+			expectedErrs: ValidationErrors{},
+			configYamls: []string{
+				`---
+# predictable-yaml: kind=Deployment, apiVersion=apps/*
+kind: Deployment  # first
+spec:
+  replicas: 1  # first
+  selector: {}`,
+				`---
+kind: Rollout  # first
+spec: {}  # ditto=apps/v1/Deployment.spec`},
+			fileYaml: `---
+kind: Rollout
+spec:
+  selector:
+    app: example
+  replicas: 3`,
+			expectedYaml: `kind: Rollout
+spec:
+  replicas: 3
+  selector:
+    app: example
+`,
+		},
+		{
+			note:         "cross-schema ditto to a key with a slash",
+			expectedErrs: ValidationErrors{},
+			configYamls: []string{
+				`---
+kind: Settings  # first
+spec:
+  team/limits:
+    cpu: 1  # first
+    memory: 1`,
+				`---
+kind: Rollout  # first
+spec:
+  limits: {}  # ditto=Settings.spec.team/limits`},
+			fileYaml: `---
+kind: Rollout
+spec:
+  limits:
+    memory: 2
+    cpu: 2`,
+			expectedYaml: `kind: Rollout
+spec:
+  limits:
+    cpu: 2
+    memory: 2
+`,
+		},
+		{
+			note:         "dittos to fragments",
+			expectedErrs: ValidationErrors{},
+			configYamls: []string{
+				`---
+# predictable-yaml: fragment=probe
+periodSeconds: 10  # first
+httpGet:
+  port: http  # first, required
+  path: /`,
+				`---
+# predictable-yaml: fragment=container
+name: TODO  # first, required
+image: TODO
+livenessProbe: {}  # ditto=@probe
+startupProbe: {}  # ditto=@probe
+readinessProbe:
+  httpGet: {}  # ditto=@probe.httpGet`,
+				`---
+kind: Pod  # first
+spec:
+  containers: []  # ditto=@container.`},
+			fileYaml: `---
+kind: Pod
+spec:
+  containers:
+  - image: example
+    name: cool-app
+    livenessProbe:
+      httpGet:
+        path: /
+        port: http
+      periodSeconds: 10
+    readinessProbe:
+      httpGet:
+        path: /
+        port: http`,
+			expectedYaml: `kind: Pod
+spec:
+  containers:
+    - name: cool-app
+      image: example
+      livenessProbe:
+        periodSeconds: 10
+        httpGet:
+          port: http
+          path: /
+      readinessProbe:
+        httpGet:
+          port: http
+          path: /
 `,
 		},
 	}
@@ -1270,12 +1455,12 @@ spec:
 			configNode := &Node{Node: cN}
 			WalkConvertYamlNodeToMainNode(configNode)
 			WalkParseLoadConfigComments(configNode)
-			fileConfigs := GetFileConfigs(configNode)
-			if fileConfigs.Kind == "" {
+			configName := GetConfigName(configNode)
+			if configName == "" {
 				t.Fatalf("Description: %s: compare.WalkAndSort(...): failed getting kind for config test data!", tc.note)
 				continue
 			}
-			configNodes[fileConfigs.Kind] = configNode
+			configNodes[configName] = configNode
 		}
 
 		fN := &yaml.Node{}
@@ -1292,7 +1477,7 @@ spec:
 		}
 
 		// do it
-		sortConfs := SortConfigs{configNodes, fileConfigs, tc.toBeginning, tc.addPreferreds, nil}
+		sortConfs := SortConfigs{configNodes, fileConfigs, tc.toBeginning, tc.addPreferreds, false, nil, nil}
 		gotErrs, _ := WalkAndSort(configNodes[fileConfigs.Kind], fileNode, sortConfs, ValidationErrors{})
 		expected := GetValidationErrorStrings(tc.expectedErrs)
 		got := GetValidationErrorStrings(gotErrs)
@@ -1339,7 +1524,7 @@ func TestWalkFindNullValues(t *testing.T) {
 		{
 			note: "null value where map expected",
 			expectedErrs: ValidationErrors{
-				fmt.Errorf("validation error: null value at '.spec.template.spec.containers[0].livenessProbe' — remove it or set a value"),
+				fmt.Errorf("validation error: null value at '.spec.template.spec.containers[0].livenessProbe' (line 8) — remove it or set a value"),
 			},
 			configYamls: []string{`---
 kind: Deployment # first
@@ -1363,7 +1548,7 @@ spec:
 		{
 			note: "implicit null value where map expected",
 			expectedErrs: ValidationErrors{
-				fmt.Errorf("validation error: null value at '.spec.template.spec.containers[0].livenessProbe' — remove it or set a value"),
+				fmt.Errorf("validation error: null value at '.spec.template.spec.containers[0].livenessProbe' (line 8) — remove it or set a value"),
 			},
 			configYamls: []string{`---
 kind: Deployment # first
@@ -1387,7 +1572,7 @@ spec:
 		{
 			note: "tilde null value where map expected",
 			expectedErrs: ValidationErrors{
-				fmt.Errorf("validation error: null value at '.spec.template.spec.containers[0].livenessProbe' — remove it or set a value"),
+				fmt.Errorf("validation error: null value at '.spec.template.spec.containers[0].livenessProbe' (line 8) — remove it or set a value"),
 			},
 			configYamls: []string{`---
 kind: Deployment # first
@@ -1455,7 +1640,7 @@ spec:
 		{
 			note: "null value in trailing-dot ditto sequence",
 			expectedErrs: ValidationErrors{
-				fmt.Errorf("validation error: null value at '.spec.template.testThis[0].containers[0].livenessProbe' — remove it or set a value"),
+				fmt.Errorf("validation error: null value at '.spec.template.testThis[0].containers[0].livenessProbe' (line 14) — remove it or set a value"),
 			},
 			configYamls: []string{`---
 kind: Deployment # first
@@ -1530,3 +1715,364 @@ spec:
 		}
 	}
 }
+
+func TestWalkFindUnknownKeys(t *testing.T) {
+	type testCase struct {
+		note         string
+		expectedErrs ValidationErrors
+		configYamls  []string
+		fileYaml     string
+	}
+
+	testCases := []testCase{
+		{
+			note:         "all keys known",
+			expectedErrs: ValidationErrors{},
+			configYamls: []string{`---
+kind: Deployment  # first, required
+spec:
+  replicas: 1
+  imagePullPolicy: IfNotPresent`},
+			fileYaml: `---
+kind: Deployment
+spec:
+  imagePullPolicy: Always
+  replicas: 2`,
+		},
+		{
+			note: "misspelled keys",
+			expectedErrs: ValidationErrors{
+				fmt.Errorf("validation error: unknown key at '.spec.replica' (line 4) — not found in the config"),
+				fmt.Errorf("validation error: unknown key at '.spec.imagePullPolicyy' (line 5) — not found in the config"),
+			},
+			configYamls: []string{`---
+kind: Deployment  # first, required
+spec:
+  replicas: 1
+  imagePullPolicy: IfNotPresent`},
+			fileYaml: `---
+kind: Deployment
+spec:
+  replica: 2
+  imagePullPolicyy: Always`,
+		},
+		{
+			note: "open maps allow any keys",
+			expectedErrs: ValidationErrors{
+				fmt.Errorf("validation error: unknown key at '.metadata.name.extra' (line 10) — not found in the config"),
+			},
+			configYamls: []string{`---
+kind: Deployment  # first, required
+metadata:
+  name:
+    value: TODO
+  labels:  # open
+    app: TODO  # first, required
+  annotations: {}  # open`},
+			fileYaml: `---
+kind: Deployment
+metadata:
+  labels:
+    app: cool-app
+    team: cool-team
+  annotations:
+    example.com/thing: "true"
+  name:
+    extra: asdf`,
+		},
+		{
+			note: "sequences and dittos",
+			expectedErrs: ValidationErrors{
+				fmt.Errorf("validation error: unknown key at '.spec.containers[1].imag' (line 7) — not found in the config"),
+				fmt.Errorf("validation error: unknown key at '.spec.initContainers[0].nam' (line 9) — not found in the config"),
+			},
+			configYamls: []string{`---
+kind: Pod  # first, required
+spec:
+  initContainers: []  # ditto=.spec.containers
+  containers:
+  - name: TODO  # first, required
+    image: TODO
+    env: {}  # open`},
+			fileYaml: `---
+kind: Pod
+spec:
+  containers:
+  - name: one
+  - name: two
+    imag: asdf
+  initContainers:
+  - nam: three
+    env:
+      ANYTHING: goes`,
+		},
+	}
+
+	for _, tc := range testCases {
+		configNodes := ConfigNodes{}
+		for _, cYaml := range tc.configYamls {
+			cN := &yaml.Node{}
+			err := yaml.Unmarshal([]byte(cYaml), cN)
+			if err != nil {
+				t.Errorf("Description: %s: compare.WalkFindUnknownKeys(...): failed unmarshaling config test data!", tc.note)
+				continue
+			}
+			configNode := &Node{Node: cN}
+			WalkConvertYamlNodeToMainNode(configNode)
+			WalkParseLoadConfigComments(configNode)
+			fileConfigs := GetFileConfigs(configNode)
+			if fileConfigs.Kind == "" {
+				t.Errorf("Description: %s: compare.WalkFindUnknownKeys(...): failed getting kind for config test data!", tc.note)
+			}
+			configNodes[fileConfigs.Kind] = configNode
+		}
+
+		fN := &yaml.Node{}
+		err := yaml.Unmarshal([]byte(tc.fileYaml), fN)
+		if err != nil {
+			t.Errorf("Description: %s: compare.WalkFindUnknownKeys(...): failed unmarshaling file test data: %v", tc.note, err)
+			continue
+		}
+		fileNode := &Node{Node: fN}
+		WalkConvertYamlNodeToMainNode(fileNode)
+		fileConfigs := GetFileConfigs(fileNode)
+
+		sortConfigs := SortConfigs{
+			ConfigNodes: configNodes,
+			FileConfigs: fileConfigs,
+		}
+		gotErrs := WalkFindUnknownKeys(configNodes[fileConfigs.Kind], fileNode, sortConfigs, ValidationErrors{})
+		expected := GetValidationErrorStrings(tc.expectedErrs)
+		got := GetValidationErrorStrings(gotErrs)
+		if got != expected {
+			t.Errorf("Description: %s: compare.WalkFindUnknownKeys(...): \n-expected:\n%v\n+got:\n%v\n", tc.note, expected, got)
+		}
+	}
+}
+
+func TestWalkAndSortWarnings(t *testing.T) {
+	type testCase struct {
+		note             string
+		renameSuggested  bool
+		expectedWarnings ValidationErrors
+		expectedChanges  []string
+		configYaml       string
+		fileYaml         string
+		expectedYaml     string
+	}
+
+	configYaml := `---
+kind: Deployment  # first, required
+spec:
+  replicas: 1
+  readinessProbe: {}
+  imagePullPolicy: IfNotPresent
+  image: TODO
+  name: TODO
+  names: []`
+
+	testCases := []testCase{
+		{
+			note: "warn on likely misspellings",
+			expectedWarnings: ValidationErrors{
+				fmt.Errorf("warning: unknown key at '.spec.replica' (line 4) — did you mean 'replicas'?"),
+				fmt.Errorf("warning: unknown key at '.spec.READINESSPROBE' (line 5) — did you mean 'readinessProbe'?"),
+			},
+			expectedChanges: []string{},
+			configYaml:      configYaml,
+			fileYaml: `---
+kind: Deployment
+spec:
+  replica: 2
+  READINESSPROBE: {}
+  somethingElse: true`,
+			expectedYaml: `kind: Deployment
+spec:
+    replica: 2
+    READINESSPROBE: {}
+    somethingElse: true
+`,
+		},
+		{
+			note:            "rename when there is exactly one candidate",
+			renameSuggested: true,
+			expectedWarnings: ValidationErrors{
+				fmt.Errorf("warning: unknown key at '.spec.namess' (line 6) — did you mean 'name' or 'names'?"),
+			},
+			expectedChanges: []string{
+				"KeyRenamed .spec.imagePullPolicyy -> imagePullPolicy",
+				"KeyMoved .spec.replicas (1 -> 0)",
+				"KeyMoved .spec.imagePullPolicy (0 -> 1)",
+			},
+			configYaml: configYaml,
+			fileYaml: `---
+kind: Deployment
+spec:
+  imagePullPolicyy: Always
+  replicas: 2
+  namess: asdf`,
+			expectedYaml: `kind: Deployment
+spec:
+    replicas: 2
+    imagePullPolicy: Always
+    namess: asdf
+`,
+		},
+		{
+			note: "missing preferred keys, unless suggested",
+			expectedWarnings: ValidationErrors{
+				fmt.Errorf("warning: missing preferred key 'replicas' at '.spec' (line 4)"),
+				fmt.Errorf("warning: unknown key at '.spec.template.nam' (line 6) — did you mean 'name'?"),
+			},
+			expectedChanges: []string{},
+			configYaml: `---
+kind: Deployment  # first, required
+spec:
+  replicas: 1  # preferred
+  template:
+    name: TODO  # preferred
+    image: TODO  # preferred`,
+			fileYaml: `---
+kind: Deployment
+spec:
+  template:
+    image: asdf
+    nam: asdf`,
+			expectedYaml: `kind: Deployment
+spec:
+    template:
+        image: asdf
+        nam: asdf
+`,
+		},
+		{
+			note:             "keys of open maps are not misspellings, including through ditto",
+			renameSuggested:  true,
+			expectedWarnings: ValidationErrors{},
+			expectedChanges:  []string{},
+			configYaml: `---
+kind: Deployment  # first, required
+metadata:
+  labels:  # open
+    app: TODO  # first
+spec:
+  selector: {}  # ditto=.metadata.labels`,
+			fileYaml: `---
+kind: Deployment
+metadata:
+  labels:
+    ap: x
+    apps: y
+spec:
+  selector:
+    apps: y`,
+			expectedYaml: `kind: Deployment
+metadata:
+    labels:
+        ap: x
+        apps: y
+spec:
+    selector:
+        apps: y
+`,
+		},
+		{
+			note:             "keys already in the file are not candidates",
+			renameSuggested:  true,
+			expectedWarnings: ValidationErrors{},
+			expectedChanges:  []string{},
+			configYaml:       configYaml,
+			fileYaml: `---
+kind: Deployment
+spec:
+  replicas: 2
+  replicass: 3`,
+			expectedYaml: `kind: Deployment
+spec:
+    replicas: 2
+    replicass: 3
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		cN := &yaml.Node{}
+		err := yaml.Unmarshal([]byte(tc.configYaml), cN)
+		if err != nil {
+			t.Errorf("Description: %s: compare.WalkAndSort(...): failed unmarshaling config test data: %v", tc.note, err)
+			continue
+		}
+		configNode := &Node{Node: cN}
+		WalkConvertYamlNodeToMainNode(configNode)
+		WalkParseLoadConfigComments(configNode)
+		configNodes := ConfigNodes{"Deployment": configNode}
+
+		fN := &yaml.Node{}
+		err = yaml.Unmarshal([]byte(tc.fileYaml), fN)
+		if err != nil {
+			t.Errorf("Description: %s: compare.WalkAndSort(...): failed unmarshaling file test data: %v", tc.note, err)
+			continue
+		}
+		fileNode := &Node{Node: fN}
+		WalkConvertYamlNodeToMainNode(fileNode)
+
+		changes := []Change{}
+		warnings := ValidationErrors{}
+		sortConfigs := SortConfigs{
+			ConfigNodes:     configNodes,
+			FileConfigs:     GetFileConfigs(fileNode),
+			RenameSuggested: tc.renameSuggested,
+			Changes:         &changes,
+			Warnings:        &warnings,
+		}
+		errs, _ := WalkAndSort(configNode, fileNode, sortConfigs, ValidationErrors{})
+		if len(errs) != 0 {
+			t.Errorf("Description: %s: compare.WalkAndSort(...): unexpected errors: %v", tc.note, GetValidationErrorStrings(errs))
+			continue
+		}
+
+		expected := GetValidationErrorStrings(tc.expectedWarnings)
+		got := GetValidationErrorStrings(warnings)
+		if got != expected {
+			t.Errorf("Description: %s: compare.WalkAndSort(...) warnings: \n-expected:\n%v\n+got:\n%v\n", tc.note, expected, got)
+		}
+
+		gotChanges := []string{}
+		for _, change := range changes {
+			gotChanges = append(gotChanges, change.String())
+		}
+		if !reflect.DeepEqual(gotChanges, tc.expectedChanges) {
+			t.Errorf("Description: %s: compare.WalkAndSort(...) changes: \n-expected:\n%v\n+got:\n%v\n", tc.note, tc.expectedChanges, gotChanges)
+		}
+
+		gotBytes, err := yaml.Marshal(fileNode.Node)
+		if err != nil {
+			t.Errorf("Description: %s: compare.WalkAndSort(...): failed marshaling: %v", tc.note, err)
+			continue
+		}
+		if string(gotBytes) != tc.expectedYaml {
+			t.Errorf("Description: %s: compare.WalkAndSort(...): \n-expected:\n%v\n+got:\n%v\n", tc.note, tc.expectedYaml, string(gotBytes))
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	testCases := []struct {
+		a, b     string
+		expected int
+	}{
+		{"", "", 0},
+		{"replicas", "replicas", 0},
+		{"replica", "replicas", 1},
+		{"imagePullPolicyy", "imagePullPolicy", 1},
+		{"kitten", "sitting", 3},
+		{"nme", "name", 1},
+	}
+
+	for _, tc := range testCases {
+		got := editDistance(tc.a, tc.b)
+		if got != tc.expected {
+			t.Errorf("Description: compare.editDistance(%q, %q): \n-expected:\n%v\n+got:\n%v\n", tc.a, tc.b, tc.expected, got)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+/*
+Copyright © 2022 david amick git@davidamick.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package compare
+
+import (
+	"fmt"
+)
+
+// the rules that validation errors, warnings and changes are reported under
+const (
+	RuleOrder         = "order"
+	RuleFirst         = "first"
+	RuleRequired      = "required"
+	RulePreferred     = "preferred"
+	RuleNullValue     = "null-value"
+	RuleUnknownKey    = "unknown-key"
+	RuleEquals        = "equals"
+	RuleSubsetOf      = "subset-of"
+	RuleOneOf         = "one-of"
+	RuleAnyOf         = "any-of"
+	RuleOmitEmpty     = "omit-empty"
+	RuleStyle         = "style"
+	RuleBlankBefore   = "blank-before"
+	RuleNoBlank       = "no-blank"
+	RuleDirective     = "directive"
+	RuleDocumentOrder = "document-order"
+	RuleSchema        = "schema"
+)
+
+// Diagnostic is a validation error or warning about a place in a target file.
+// Its Error is the message, so it reads the same as any other error.
+type Diagnostic struct {
+	Rule    string // one of the Rule constants
+	Path    string // reference path, e.g. ".spec.replicas"
+	Line    int    // line in the target file, 0 if unknown
+	Column  int    // column in the target file, 0 if unknown
+	Message string
+}
+
+func (d *Diagnostic) Error() string {
+	return d.Message
+}
+
+// NewDiagnostic returns a Diagnostic at the file position of a node, with a formatted message
+func NewDiagnostic(rule string, node *Node, format string, args ...any) *Diagnostic {
+	line, column := FilePosition(node)
+
+	return &Diagnostic{
+		Rule:    rule,
+		Path:    GetReferencePath(node, 0, ""),
+		Line:    line,
+		Column:  column,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// FilePosition returns the line and column of a node in its target file. Sorting replaces the
+// lines of keys with those of the config, so the original line is used. Nodes added by sorting
+// have no position, so the position of the nearest parent that has one is used.
+func FilePosition(node *Node) (int, int) {
+	for ; node != nil; node = node.ParentNode {
+		if node.fileLine != 0 {
+			return node.fileLine, node.Column
+		}
+		if node.Line != 0 {
+			return node.Line, node.Column
+		}
+	}
+
+	return 0, 0
+}
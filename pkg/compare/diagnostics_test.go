@@ -0,0 +1,77 @@
+/*
+Copyright © 2022 david amick git@davidamick.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package compare
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestChangeRulesAndPositions(t *testing.T) {
+	configYaml := `---
+kind: Pod  # first, required
+metadata:
+  name: TODO  # first
+  namespace: TODO  # required
+spec:
+  containers:
+  - name: TODO  # first
+    image: TODO  # required
+`
+	fileYaml := `---
+metadata:
+  labels: {app: example}
+  name: example
+kind: Pod
+spec:
+  containers:
+  - image: example
+    name: example
+`
+	configNode, fileNode, sortConfigs := parseConfigAndFileTestNodes(t, configYaml, fileYaml)
+	sortConfigs.ConfigNodes = ConfigNodes{"Pod": configNode}
+	changes := []Change{}
+	sortConfigs.Changes = &changes
+
+	errs, _ := WalkAndSort(configNode, fileNode, sortConfigs, ValidationErrors{})
+	if len(errs) != 0 {
+		t.Fatalf("compare.WalkAndSort(...): unexpected errors: %v", errs)
+	}
+
+	// positions are those of the original file, additions take their map's position,
+	// and moved and added keys know the key they come after
+	expected := strings.Join([]string{
+		"KeyMoved .kind (1 -> 0) first at 5:1 after -",
+		"KeyMoved .metadata (0 -> 1) order at 2:1 after kind",
+		"KeyMoved .metadata.name (1 -> 0) first at 4:3 after -",
+		"KeyAdded .metadata.namespace required at 3:3 after name",
+		"UnmatchedRelocated .metadata.labels (0 -> 2) order at 3:3 after namespace",
+		"KeyMoved .spec.containers[0].name (1 -> 0) first at 9:5 after -",
+		"KeyMoved .spec.containers[0].image (0 -> 1) order at 8:5 after name",
+	}, "\n")
+	got := []string{}
+	for _, change := range changes {
+		after := "-"
+		if change.After != nil {
+			after = change.After.Value
+		}
+		got = append(got, fmt.Sprintf("%s %s at %d:%d after %s", change, change.Rule, change.Line, change.Column, after))
+	}
+	if strings.Join(got, "\n") != expected {
+		t.Errorf("compare.WalkAndSort(...): change rules and positions: \n-expected:\n%v\n+got:\n%v\n", expected, strings.Join(got, "\n"))
+	}
+}
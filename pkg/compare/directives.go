@@ -0,0 +1,356 @@
+/*
+Copyright © 2022 david amick git@davidamick.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package compare
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// fileConfigPrefix starts the directive comments of target and config files
+const fileConfigPrefix = "predictable-yaml:"
+
+// directiveName matches the name of a directive, a comment starting with anything else is prose
+var directiveName = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9-]*$`)
+
+// leadingDirectiveName matches the word a directive entry followed by prose starts with
+var leadingDirectiveName = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9-]*`)
+
+// configDirectives are the directives of config line comments, and whether each takes a value
+var configDirectives = map[string]bool{
+	"first":        false,
+	"required":     false,
+	"preferred":    false,
+	"open":         false,
+	"delete":       false,
+	"ditto":        true,
+	"equals":       true,
+	"subset-of":    true,
+	"blank-before": false,
+	"no-blank":     false,
+	"style":        true,
+	"one-of":       true,
+	"any-of":       true,
+	"omit-empty":   false,
+}
+
+// fileConfigDirectives are the directives of '# predictable-yaml:' comments, and whether each takes a value
+var fileConfigDirectives = map[string]bool{
+	"kind":             true,
+	"apiVersion":       true,
+	"fragment":         true,
+	"extends":          true,
+	"ignore":           false,
+	"ignore-requireds": false,
+}
+
+// directive is one entry of a directive comment, like 'first' or 'ditto=.spec'
+type directive struct {
+	name     string
+	value    string
+	hasValue bool
+}
+
+// parseDirectives tokenizes a directive comment: a comma separated list of names and name=value pairs.
+// Values may be quoted with " or ' to hold spaces, commas, '#', or '='. Text after a further '#' is prose,
+// and so is the whole comment when its first entry isn't a directive name, like '# the image to run'.
+// An entry starting with a name like one of known, followed by prose, like 'required (every Service needs one)',
+// is that directive, and the rest of the comment is prose.
+// Entries that parse are returned even when others are malformed, with the error of the first malformed one.
+func parseDirectives(comment string, known map[string]bool) ([]directive, error) {
+	entries, quoteErr := splitDirectiveEntries(comment)
+
+	directives := []directive{}
+	var firstErr error
+	for index, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, value, hasValue := cutUnquoted(entry, '=')
+		name = strings.TrimSpace(name)
+		if word := leadingDirectiveName.FindString(entry); !directiveName.MatchString(name) && isLikeDirective(word, known) {
+			directives = append(directives, directive{name: word})
+			break
+		}
+		var err error
+		switch {
+		case !directiveName.MatchString(name) && index == 0:
+			// prose
+			return nil, nil
+		case index == len(entries)-1 && quoteErr != nil:
+			err = quoteErr
+		case !directiveName.MatchString(name):
+			err = fmt.Errorf("malformed directive '%s'", entry)
+		case hasValue:
+			value, err = unquoteDirectiveValue(strings.TrimSpace(value))
+			if err != nil {
+				err = fmt.Errorf("malformed directive '%s', %w", entry, err)
+			}
+		}
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		directives = append(directives, directive{name: name, value: value, hasValue: hasValue})
+	}
+
+	return directives, firstErr
+}
+
+// splitDirectiveEntries splits a directive comment into its entries on commas outside quotes, stopping at prose.
+// An unterminated quote is an error of the last entry.
+func splitDirectiveEntries(comment string) ([]string, error) {
+	text := strings.TrimLeft(strings.TrimSpace(comment), "#")
+
+	entries := []string{}
+	var entry strings.Builder
+	var quote rune
+	escaped := false
+loop:
+	for _, r := range text {
+		switch {
+		case escaped:
+			escaped = false
+		case quote == '"' && r == '\\':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == ',':
+			entries = append(entries, entry.String())
+			entry.Reset()
+			continue
+		case r == '#':
+			break loop
+		}
+		entry.WriteRune(r)
+	}
+	entries = append(entries, entry.String())
+	if quote != 0 {
+		return entries, fmt.Errorf("malformed directive '%s', unterminated quote", strings.TrimSpace(entry.String()))
+	}
+
+	return entries, nil
+}
+
+// isDirectiveList reports whether a line comment is meant as directives rather than prose like '# TODO' or
+// '# deprecated', which is when an entry has a value, or starts with a name like a known directive
+func isDirectiveList(comment string, known map[string]bool) bool {
+	entries, _ := splitDirectiveEntries(comment)
+	for index, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		name, _, hasValue := cutUnquoted(entry, '=')
+		name = strings.TrimSpace(name)
+		if !directiveName.MatchString(name) {
+			if isLikeDirective(leadingDirectiveName.FindString(entry), known) {
+				return true
+			}
+			if index == 0 {
+				return false
+			}
+			continue
+		}
+		if hasValue || isLikeDirective(name, known) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isLikeDirective reports whether a name is one of known, or likely a misspelling of one
+func isLikeDirective(name string, known map[string]bool) bool {
+	if name == "" {
+		return false
+	}
+	if known[name] {
+		return true
+	}
+	for knownName := range known {
+		if editDistance(name, knownName) <= maxEditDistance(name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// cutUnquoted slices str around the first sep outside quotes
+func cutUnquoted(str string, sep rune) (string, string, bool) {
+	var quote rune
+	for i, r := range str {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == sep:
+			return str[:i], str[i+1:], true
+		}
+	}
+
+	return str, "", false
+}
+
+// unquoteDirectiveValue strips the quotes from a quoted value, unquoted values must not contain spaces or quotes
+func unquoteDirectiveValue(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, `"`):
+		unquoted, err := strconv.Unquote(value)
+		if err != nil {
+			return "", fmt.Errorf("invalid quoted value")
+		}
+		return unquoted, nil
+	case strings.HasPrefix(value, "'"):
+		if len(value) < 2 || !strings.HasSuffix(value, "'") || strings.Contains(value[1:len(value)-1], "'") {
+			return "", fmt.Errorf("invalid quoted value")
+		}
+		return value[1 : len(value)-1], nil
+	case strings.ContainsAny(value, `"'`):
+		return "", fmt.Errorf("quotes must surround the whole value")
+	case strings.IndexFunc(value, unicode.IsSpace) != -1:
+		return "", fmt.Errorf("quote values containing spaces")
+	}
+
+	return value, nil
+}
+
+// checkDirectives returns errors for directives that are unknown to known, or used with or without a value wrongly
+func checkDirectives(directives []directive, known map[string]bool) []error {
+	errs := []error{}
+	for _, d := range directives {
+		takesValue, ok := known[d.name]
+		switch {
+		case !ok:
+			errs = append(errs, fmt.Errorf("unknown directive '%s'", d.name))
+		case takesValue && (!d.hasValue || d.value == ""):
+			errs = append(errs, fmt.Errorf("malformed directive '%s', expected '%s=<value>'", d.name, d.name))
+		case !takesValue && d.hasValue:
+			errs = append(errs, fmt.Errorf("malformed directive '%s=%s', '%s' takes no value", d.name, d.value, d.name))
+		}
+	}
+
+	return errs
+}
+
+// formatDirectiveValue quotes a directive value when it couldn't be parsed unquoted
+func formatDirectiveValue(value string) string {
+	if value == "" || strings.ContainsAny(value, "\"'#,= \t") {
+		return strconv.Quote(value)
+	}
+
+	return value
+}
+
+// fileConfigComment is the directive text of a '# predictable-yaml:' comment line, and its line
+type fileConfigComment struct {
+	line int
+	text string
+}
+
+// getFileConfigComments returns the '# predictable-yaml:' comment lines on top level nodes
+func getFileConfigComments(node *Node) []fileConfigComment {
+	comments := []fileConfigComment{}
+	if len(node.NodeContent) == 0 {
+		return comments
+	}
+	for _, n := range node.NodeContent[0].NodeContent {
+		for commentIndex, comment := range []string{n.HeadComment, n.LineComment, n.FootComment} {
+			if comment == "" {
+				continue
+			}
+			commentLines := strings.Split(comment, "\n")
+			for lineIndex, commentLine := range commentLines {
+				text := strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(commentLine), "#"))
+				if !strings.HasPrefix(text, fileConfigPrefix) {
+					continue
+				}
+				// head comments end on the line above the node, foot comments start on the line below
+				line := n.Line
+				switch commentIndex {
+				case 0:
+					line = n.Line - len(commentLines) + lineIndex
+				case 2:
+					line = n.Line + 1 + lineIndex
+				}
+				comments = append(comments, fileConfigComment{line, strings.TrimPrefix(text, fileConfigPrefix)})
+			}
+		}
+	}
+
+	return comments
+}
+
+// CheckFileConfigs returns problems with a file's '# predictable-yaml:' comments, like unknown or malformed directives
+func CheckFileConfigs(node *Node) []ConfigProblem {
+	problems := []ConfigProblem{}
+	for _, comment := range getFileConfigComments(node) {
+		directives, err := parseDirectives(comment.text, fileConfigDirectives)
+		if err == nil && directives == nil {
+			err = fmt.Errorf("malformed directive '%s'", strings.TrimSpace(comment.text))
+		}
+		if err != nil {
+			problems = append(problems, ConfigProblem{comment.line, fmt.Errorf("%w in '# %s' comment", err, fileConfigPrefix)})
+		}
+		for _, err := range checkDirectives(directives, fileConfigDirectives) {
+			problems = append(problems, ConfigProblem{comment.line, fmt.Errorf("%w in '# %s' comment", err, fileConfigPrefix)})
+		}
+	}
+
+	return problems
+}
+
+// CheckConfigDirectives returns problems with a config's directives, like unknown or malformed directives,
+// in its line comments and its '# predictable-yaml:' comments
+func CheckConfigDirectives(configNode *Node) []ConfigProblem {
+	problems := []ConfigProblem{}
+	for _, problem := range CheckFileConfigs(configNode) {
+		problems = append(problems, ConfigProblem{problem.Line, fmt.Errorf("configuration error: %w", problem.Err)})
+	}
+
+	return walkCheckConfigDirectives(configNode, problems)
+}
+
+// walkCheckConfigDirectives appends the problems with the line comment directives of a config node and its children
+func walkCheckConfigDirectives(node *Node, problems []ConfigProblem) []ConfigProblem {
+	if node.LineComment != "" && !strings.HasPrefix(strings.TrimSpace(strings.TrimLeft(node.LineComment, "#")), fileConfigPrefix) &&
+		isDirectiveList(node.LineComment, configDirectives) {
+		path := GetReferencePath(firstScalarOfLine(node), 0, "")
+		directives, err := parseDirectives(node.LineComment, configDirectives)
+		if err != nil {
+			problems = append(problems, ConfigProblem{node.Line, fmt.Errorf("configuration error: %w at path: %s", err, path)})
+		}
+		for _, err := range checkDirectives(directives, configDirectives) {
+			problems = append(problems, ConfigProblem{node.Line, fmt.Errorf("configuration error: %w at path: %s", err, path)})
+		}
+	}
+	for _, innerNode := range node.NodeContent {
+		problems = walkCheckConfigDirectives(innerNode, problems)
+	}
+
+	return problems
+}
@@ -0,0 +1,259 @@
+/*
+Copyright © 2022 david amick git@davidamick.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package compare
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.yaml.in/yaml/v3"
+)
+
+func TestParseDirectives(t *testing.T) {
+	type testCase struct {
+		note        string
+		comment     string
+		expected    []directive
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			note:     "names",
+			comment:  "# first, required",
+			expected: []directive{{name: "first"}, {name: "required"}},
+		},
+		{
+			note:     "spaces around separators",
+			comment:  "#first ,required,  ditto = .spec.template",
+			expected: []directive{{name: "first"}, {name: "required"}, {name: "ditto", value: ".spec.template", hasValue: true}},
+		},
+		{
+			note:     "quoted values",
+			comment:  `# kind="my:schema, v2", extends='a=b # c'`,
+			expected: []directive{{name: "kind", value: "my:schema, v2", hasValue: true}, {name: "extends", value: "a=b # c", hasValue: true}},
+		},
+		{
+			note:     "escaped quote",
+			comment:  `# kind="say \"hi\""`,
+			expected: []directive{{name: "kind", value: `say "hi"`, hasValue: true}},
+		},
+		{
+			note:     "prose after a further '#'",
+			comment:  "# required  # the container name, used in logs",
+			expected: []directive{{name: "required"}},
+		},
+		{
+			note:     "prose only",
+			comment:  "# the image to run, pinned by digest",
+			expected: nil,
+		},
+		{
+			note:     "prose only, with punctuation",
+			comment:  "# see: https://example.com",
+			expected: nil,
+		},
+		{
+			note:     "empty",
+			comment:  "#",
+			expected: []directive{},
+		},
+		{
+			note:     "unknown names still parse",
+			comment:  "# requried",
+			expected: []directive{{name: "requried"}},
+		},
+		{
+			note:        "unterminated quote",
+			comment:     `# ditto=".spec`,
+			expected:    []directive{},
+			expectedErr: fmt.Errorf(`malformed directive 'ditto=".spec', unterminated quote`),
+		},
+		{
+			note:        "spaces in unquoted value",
+			comment:     "# kind=my schema",
+			expected:    []directive{},
+			expectedErr: fmt.Errorf("malformed directive 'kind=my schema', quote values containing spaces"),
+		},
+		{
+			note:        "prose after a directive",
+			comment:     "# required, because it is",
+			expected:    []directive{{name: "required"}},
+			expectedErr: fmt.Errorf("malformed directive 'because it is'"),
+		},
+		{
+			note:     "prose right after a first directive",
+			comment:  "# required (every Service needs one)",
+			expected: []directive{{name: "required"}},
+		},
+		{
+			note:     "prose right after a first directive, with punctuation",
+			comment:  "# preferred: used by selectors, and by services",
+			expected: []directive{{name: "preferred"}},
+		},
+		{
+			note:     "prose right after a later directive",
+			comment:  "# first, required (every Service needs one)",
+			expected: []directive{{name: "first"}, {name: "required"}},
+		},
+		{
+			note:     "misspelled directive followed by prose",
+			comment:  "# requird (every Service needs one)",
+			expected: []directive{{name: "requird"}},
+		},
+		{
+			note:        "entries that parse are kept",
+			comment:     "# required, equals=a b, first",
+			expected:    []directive{{name: "required"}, {name: "first"}},
+			expectedErr: fmt.Errorf("malformed directive 'equals=a b', quote values containing spaces"),
+		},
+		{
+			note:        "entries before an unterminated quote are kept",
+			comment:     `# required, ditto=".spec`,
+			expected:    []directive{{name: "required"}},
+			expectedErr: fmt.Errorf(`malformed directive 'ditto=".spec', unterminated quote`),
+		},
+	}
+
+	for _, tc := range testCases {
+		got, err := parseDirectives(tc.comment, configDirectives)
+		if fmt.Sprint(err) != fmt.Sprint(tc.expectedErr) {
+			t.Errorf("Description: %s: compare.parseDirectives(%q) error: \n-expected:\n%v\n+got:\n%v\n", tc.note, tc.comment, tc.expectedErr, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("Description: %s: compare.parseDirectives(%q): \n-expected:\n%#v\n+got:\n%#v\n", tc.note, tc.comment, tc.expected, got)
+		}
+	}
+}
+
+func TestIsDirectiveList(t *testing.T) {
+	testCases := []struct {
+		comment  string
+		expected bool
+	}{
+		{"# first, required", true},
+		{"# requried", true},
+		{"# ditto=.spec", true},
+		{"# unknwn=value", true},
+		{`# ditto=".spec`, true},
+		{"# required (every Service needs one)", true},
+		{"# preferred: used by selectors", true},
+		{"# requird (every Service needs one)", true},
+		{"# TODO", false},
+		{"# deprecated", false},
+		{"# deprecated, use template instead", false},
+		{"# the image to run", false},
+		{"# see: https://example.com", false},
+		{"#", false},
+	}
+
+	for _, tc := range testCases {
+		got := isDirectiveList(tc.comment, configDirectives)
+		if got != tc.expected {
+			t.Errorf("Description: compare.isDirectiveList(%q): \n-expected:\n%v\n+got:\n%v\n", tc.comment, tc.expected, got)
+		}
+	}
+}
+
+func TestWalkParseLoadConfigCommentsMalformed(t *testing.T) {
+	n := &yaml.Node{}
+	err := yaml.Unmarshal([]byte("---\nkind: Deployment  # first, required, equals=a b\n"), n)
+	if err != nil {
+		t.Fatalf("Description: compare.WalkParseLoadConfigComments(...): failed unmarshaling test data: %v", err)
+	}
+	node := &Node{Node: n}
+	WalkConvertYamlNodeToMainNode(node)
+	WalkParseLoadConfigComments(node)
+
+	keyNode := node.NodeContent[0].NodeContent[0]
+	if !keyNode.MustBeFirst || !keyNode.Required || keyNode.Equals != "" {
+		t.Errorf("Description: compare.WalkParseLoadConfigComments(...): expected the entries that parse to be loaded, got first: %v, required: %v, equals: %q", keyNode.MustBeFirst, keyNode.Required, keyNode.Equals)
+	}
+}
+
+func TestCheckFileConfigs(t *testing.T) {
+	type testCase struct {
+		note     string
+		yaml     string
+		expected []string
+	}
+
+	testCases := []testCase{
+		{
+			note: "valid",
+			yaml: `---
+# generated by a tool
+# predictable-yaml: kind="my:schema", ignore-requireds  # for now
+kind: Deployment`,
+			expected: []string{},
+		},
+		{
+			note: "unknown and malformed",
+			yaml: `---
+# predictable-yaml: ignor, kind
+kind: Deployment  # predictable-yaml: ignore=true
+spec: {}
+# predictable-yaml: this file is special`,
+			expected: []string{
+				"2: unknown directive 'ignor' in '# predictable-yaml:' comment",
+				"2: malformed directive 'kind', expected 'kind=<value>' in '# predictable-yaml:' comment",
+				"3: malformed directive 'ignore=true', 'ignore' takes no value in '# predictable-yaml:' comment",
+				"5: malformed directive 'this file is special' in '# predictable-yaml:' comment",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		n := &yaml.Node{}
+		err := yaml.Unmarshal([]byte(tc.yaml), n)
+		if err != nil {
+			t.Fatalf("Description: %s: compare.CheckFileConfigs(...): failed unmarshaling test data: %v", tc.note, err)
+		}
+		node := &Node{Node: n}
+		WalkConvertYamlNodeToMainNode(node)
+
+		got := []string{}
+		for _, problem := range CheckFileConfigs(node) {
+			got = append(got, fmt.Sprintf("%d: %v", problem.Line, problem.Err))
+		}
+		if strings.Join(got, "\n") != strings.Join(tc.expected, "\n") {
+			t.Errorf("Description: %s: compare.CheckFileConfigs(...): \n-expected:\n%v\n+got:\n%v\n", tc.note, strings.Join(tc.expected, "\n"), strings.Join(got, "\n"))
+		}
+	}
+}
+
+func TestWalkParseLoadConfigCommentsProse(t *testing.T) {
+	n := &yaml.Node{}
+	err := yaml.Unmarshal([]byte("---\nmetadata:\n  name: TODO  # required (every Service needs one)\n  labels: {}  # preferred: used by selectors\n"), n)
+	if err != nil {
+		t.Fatalf("Description: compare.WalkParseLoadConfigComments(...): failed unmarshaling test data: %v", err)
+	}
+	node := &Node{Node: n}
+	WalkConvertYamlNodeToMainNode(node)
+	WalkParseLoadConfigComments(node)
+
+	metadata := GetKeyValuePairs(node.NodeContent[0].NodeContent)[0].ValueNode
+	pairs := GetKeyValuePairs(metadata.NodeContent)
+	if !pairs[0].KeyNode.Required {
+		t.Errorf("Description: compare.WalkParseLoadConfigComments(...): expected 'name' to be required")
+	}
+	if !pairs[1].KeyNode.Preferred {
+		t.Errorf("Description: compare.WalkParseLoadConfigComments(...): expected 'labels' to be preferred")
+	}
+}
@@ -0,0 +1,94 @@
+/*
+Copyright © 2022 david amick git@davidamick.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package compare
+
+import (
+	"go.yaml.in/yaml/v3"
+)
+
+// WalkFindEmptyValues walks the config and file trees together, returning errors for empty maps and sequences
+// whose config key is marked 'omit-empty' and not 'required'. This should be called before WalkAndSort,
+// so reported line numbers are those of the original file.
+func WalkFindEmptyValues(configNode, fileNode *Node, sortConfs SortConfigs, errs ValidationErrors) ValidationErrors {
+	walkConfigKeys(configNode, fileNode, sortConfs, func(configKeyNode *Node, filePair KeyValuePair) {
+		if shouldOmit(configKeyNode, filePair.ValueNode) {
+			errs = append(errs, NewDiagnostic(RuleOmitEmpty, filePair.KeyNode, "validation error: empty value at '%s' (line %d) — remove it", GetReferencePath(filePair.KeyNode, 0, ""), filePair.KeyNode.Line))
+		}
+	})
+
+	return errs
+}
+
+// WalkRemoveEmptyValues walks the config and file trees together, removing empty maps and sequences
+// whose config key is marked 'omit-empty' and not 'required'. Maps left empty by removing their keys are
+// removed too, when they are marked the same. Returns whether any keys were removed.
+func WalkRemoveEmptyValues(configNode, fileNode *Node, sortConfs SortConfigs) bool {
+	// parents are visited before their children, so remove from the last visited up
+	candidates := []KeyValuePair{}
+	configKeyNodes := []*Node{}
+	walkConfigKeys(configNode, fileNode, sortConfs, func(configKeyNode *Node, filePair KeyValuePair) {
+		if configKeyNode.OmitEmpty && !configKeyNode.Required {
+			candidates = append(candidates, filePair)
+			configKeyNodes = append(configKeyNodes, configKeyNode)
+		}
+	})
+
+	removals := []Change{}
+	for i := len(candidates) - 1; i >= 0; i-- {
+		filePair := candidates[i]
+		if !shouldOmit(configKeyNodes[i], filePair.ValueNode) {
+			continue
+		}
+		removals = append(removals, Change{
+			Type:      KeyRemoved,
+			Path:      GetReferencePath(filePair.KeyNode.ParentNode, 0, ""),
+			Key:       filePair.Key,
+			From:      filePair.KeyNode.Index / 2,
+			To:        -1,
+			ValueKind: filePair.ValueNode.Kind,
+			Node:      filePair.KeyNode,
+		})
+		removePair(filePair.KeyNode)
+	}
+
+	// record removals parents first, like other changes
+	for i := len(removals) - 1; i >= 0; i-- {
+		sortConfs.recordChange(removals[i])
+	}
+
+	return len(removals) != 0
+}
+
+// shouldOmit reports whether a value is an empty map or sequence of a key marked 'omit-empty' and not 'required'
+func shouldOmit(configKeyNode, valueNode *Node) bool {
+	if !configKeyNode.OmitEmpty || configKeyNode.Required {
+		return false
+	}
+
+	return (valueNode.Kind == yaml.MappingNode || valueNode.Kind == yaml.SequenceNode) && len(valueNode.NodeContent) == 0
+}
+
+// removePair removes a key and its value from their map
+func removePair(keyNode *Node) {
+	mapNode := keyNode.ParentNode
+	index := keyNode.Index
+	mapNode.NodeContent = append(mapNode.NodeContent[:index:index], mapNode.NodeContent[index+2:]...)
+	mapNode.Content = append(mapNode.Content[:index:index], mapNode.Content[index+2:]...)
+	for i, node := range mapNode.NodeContent[index:] {
+		// keep indexes current so reference paths resolve
+		node.Index = index + i
+	}
+}
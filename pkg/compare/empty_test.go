@@ -0,0 +1,152 @@
+/*
+Copyright © 2022 david amick git@davidamick.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package compare
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"go.yaml.in/yaml/v3"
+)
+
+func TestWalkRemoveEmptyValues(t *testing.T) {
+	configYaml := `---
+kind: Pod  # first, required
+metadata:
+  name: TODO  # first
+  annotations: {}  # open, omit-empty
+spec:  # omit-empty
+  volumes: []  # omit-empty
+  resources: {}  # open, required, omit-empty
+  containers:
+  - name: TODO  # first
+    envFrom: []  # omit-empty
+`
+
+	type testCase struct {
+		note            string
+		fileYaml        string
+		expectedErrs    ValidationErrors
+		expectedYaml    string
+		expectedChanges []string
+	}
+
+	testCases := []testCase{
+		{
+			note: "no empty values",
+			fileYaml: `---
+kind: Pod
+metadata:
+  name: example
+  annotations: {a: b}
+spec:
+  resources: {}
+  containers:
+  - name: example
+`,
+			expectedErrs:    ValidationErrors{},
+			expectedChanges: []string{},
+		},
+		{
+			note: "empty values removed",
+			fileYaml: `---
+kind: Pod
+metadata:
+  name: example
+  annotations: {}
+spec:
+  containers:
+  - name: example
+    envFrom: []
+  volumes: []
+`,
+			expectedErrs: ValidationErrors{
+				fmt.Errorf("validation error: empty value at '.metadata.annotations' (line 5) — remove it"),
+				fmt.Errorf("validation error: empty value at '.spec.volumes' (line 10) — remove it"),
+				fmt.Errorf("validation error: empty value at '.spec.containers[0].envFrom' (line 9) — remove it"),
+			},
+			expectedYaml: `kind: Pod
+metadata:
+  name: example
+spec:
+  containers:
+    - name: example
+`,
+			expectedChanges: []string{
+				"KeyRemoved .metadata.annotations",
+				"KeyRemoved .spec.volumes",
+				"KeyRemoved .spec.containers[0].envFrom",
+			},
+		},
+		{
+			note: "maps left empty are removed too",
+			fileYaml: `---
+kind: Pod
+spec:
+  volumes: []
+`,
+			expectedErrs: ValidationErrors{
+				fmt.Errorf("validation error: empty value at '.spec.volumes' (line 4) — remove it"),
+			},
+			expectedYaml: `kind: Pod
+`,
+			expectedChanges: []string{
+				"KeyRemoved .spec",
+				"KeyRemoved .spec.volumes",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		configNode, fileNode, sortConfigs := parseConfigAndFileTestNodes(t, configYaml, tc.fileYaml)
+		sortConfigs.ConfigNodes = ConfigNodes{"Pod": configNode}
+
+		gotErrs := WalkFindEmptyValues(configNode, fileNode, sortConfigs, ValidationErrors{})
+		expected := GetValidationErrorStrings(tc.expectedErrs)
+		got := GetValidationErrorStrings(gotErrs)
+		if got != expected {
+			t.Errorf("Description: %s: compare.WalkFindEmptyValues(...): \n-expected:\n%v\n+got:\n%v\n", tc.note, expected, got)
+		}
+
+		changes := []Change{}
+		sortConfigs.Changes = &changes
+		removed := WalkRemoveEmptyValues(configNode, fileNode, sortConfigs)
+		if removed != (len(tc.expectedChanges) != 0) {
+			t.Errorf("Description: %s: compare.WalkRemoveEmptyValues(...): expected removed to be %v", tc.note, !removed)
+		}
+		gotChanges := []string{}
+		for _, change := range changes {
+			gotChanges = append(gotChanges, change.String())
+		}
+		if strings.Join(gotChanges, "\n") != strings.Join(tc.expectedChanges, "\n") {
+			t.Errorf("Description: %s: compare.WalkRemoveEmptyValues(...): changes: \n-expected:\n%v\n+got:\n%v\n", tc.note, strings.Join(tc.expectedChanges, "\n"), strings.Join(gotChanges, "\n"))
+		}
+		if tc.expectedYaml == "" {
+			continue
+		}
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(fileNode.Node); err != nil {
+			t.Fatalf("failed encoding: %v", err)
+		}
+		if buf.String() != tc.expectedYaml {
+			t.Errorf("Description: %s: compare.WalkRemoveEmptyValues(...): \n-expected:\n%v\n+got:\n%v\n", tc.note, tc.expectedYaml, buf.String())
+		}
+	}
+}
@@ -0,0 +1,85 @@
+/*
+Copyright © 2022 david amick git@davidamick.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package compare
+
+import (
+	"fmt"
+	"strings"
+)
+
+// keyGroup is the keys of a config map that share a 'one-of' or 'any-of' group
+type keyGroup struct {
+	directive string // 'one-of' or 'any-of'
+	name      string
+	keys      []string
+	line      int // line of the first key
+}
+
+// groupsOf returns the 'one-of' and 'any-of' groups of a config map, in the order of their first keys
+func groupsOf(configMapNode *Node) []*keyGroup {
+	groups := []*keyGroup{}
+	byName := map[string]*keyGroup{}
+	configPairs, _ := configMapNode.configPairs()
+	for _, pair := range configPairs {
+		for _, g := range []keyGroup{{directive: "one-of", name: pair.KeyNode.OneOf}, {directive: "any-of", name: pair.KeyNode.AnyOf}} {
+			if g.name == "" {
+				continue
+			}
+			id := g.directive + "=" + g.name
+			group, ok := byName[id]
+			if !ok {
+				group = &keyGroup{directive: g.directive, name: g.name, line: pair.KeyNode.Line}
+				byName[id] = group
+				groups = append(groups, group)
+			}
+			group.keys = append(group.keys, pair.Key)
+		}
+	}
+
+	return groups
+}
+
+// WalkFindGroupErrors walks the config and file trees together, returning errors for maps that don't have
+// exactly one of the keys of each 'one-of' group, or at least one of the keys of each 'any-of' group.
+// Missing keys aren't reported for files marked 'ignore-requireds'. This should be called before WalkAndSort,
+// so reported line numbers are those of the original file.
+func WalkFindGroupErrors(configNode, fileNode *Node, sortConfs SortConfigs, errs ValidationErrors) ValidationErrors {
+	walkConfigMaps(configNode, fileNode, sortConfs, func(configMapNode, fileMapNode *Node) {
+		filePairs := GetKeyValuePairs(fileMapNode.NodeContent)
+		fileIndex := indexKeyValuePairs(filePairs)
+		path := GetReferencePath(fileMapNode, 0, "")
+		for _, group := range groupsOf(configMapNode) {
+			found := []string{}
+			for _, key := range group.keys {
+				if i, ok := fileIndex[key]; ok {
+					found = append(found, fmt.Sprintf("'%s' (line %d)", key, filePairs[i].KeyNode.Line))
+				}
+			}
+			keys := "'" + strings.Join(group.keys, "', '") + "'"
+			switch {
+			case len(found) == 0 && sortConfs.FileConfigs.IgnoreRequireds:
+			case len(found) == 0 && group.directive == "one-of":
+				errs = append(errs, NewDiagnostic(RuleOneOf, fileMapNode, "validation error: missing one of %s at '%s' (line %d)", keys, path, fileMapNode.Line))
+			case len(found) == 0:
+				errs = append(errs, NewDiagnostic(RuleAnyOf, fileMapNode, "validation error: missing at least one of %s at '%s' (line %d)", keys, path, fileMapNode.Line))
+			case len(found) > 1 && group.directive == "one-of":
+				errs = append(errs, NewDiagnostic(RuleOneOf, fileMapNode, "validation error: only one of %s is allowed at '%s', found %s", keys, path, strings.Join(found, ", ")))
+			}
+		}
+	})
+
+	return errs
+}
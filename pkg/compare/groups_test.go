@@ -0,0 +1,150 @@
+/*
+Copyright © 2022 david amick git@davidamick.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package compare
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"go.yaml.in/yaml/v3"
+)
+
+func TestWalkFindGroupErrors(t *testing.T) {
+	configYaml := `---
+kind: Pod  # first, required
+spec:
+  volumes:
+  - name: TODO  # first, required
+    configMap: {}  # open, required, one-of=source
+    secret: {}  # open, required, one-of=source
+    emptyDir: {}  # open, one-of=source
+  steps:
+  - uses: TODO  # any-of=action
+    run: TODO  # any-of=action
+    with: {}  # open
+`
+
+	type testCase struct {
+		note         string
+		fileYaml     string
+		expectedErrs ValidationErrors
+		expectedYaml string
+	}
+
+	testCases := []testCase{
+		{
+			note: "groups satisfied",
+			fileYaml: `---
+kind: Pod
+spec:
+  volumes:
+  - name: config
+    configMap:
+      name: config
+  - name: scratch
+    emptyDir: {}
+  steps:
+  - uses: actions/checkout@v4
+  - uses: actions/setup-go@v5
+    run: go build
+`,
+			expectedErrs: ValidationErrors{},
+		},
+		{
+			note: "groups violated, grouped keys not added",
+			fileYaml: `---
+kind: Pod
+spec:
+  volumes:
+  - name: none
+  - name: both
+    secret:
+      secretName: both
+    configMap:
+      name: both
+  steps:
+  - with:
+      go-version: 1.22
+`,
+			expectedErrs: ValidationErrors{
+				fmt.Errorf("validation error: missing one of 'configMap', 'secret', 'emptyDir' at '.spec.volumes[0]' (line 5)"),
+				fmt.Errorf("validation error: only one of 'configMap', 'secret', 'emptyDir' is allowed at '.spec.volumes[1]', found 'configMap' (line 9), 'secret' (line 7)"),
+				fmt.Errorf("validation error: missing at least one of 'uses', 'run' at '.spec.steps[0]' (line 12)"),
+			},
+			expectedYaml: `kind: Pod
+spec:
+  volumes:
+    - name: none
+    - name: both
+      configMap:
+        name: both
+      secret:
+        secretName: both
+  steps:
+    - with:
+        go-version: 1.22
+`,
+		},
+		{
+			note: "missing keys not reported with ignore-requireds",
+			fileYaml: `---
+# predictable-yaml: ignore-requireds
+kind: Pod
+spec:
+  volumes:
+  - name: none
+  - name: both
+    configMap:
+      name: both
+    secret:
+      secretName: both
+`,
+			expectedErrs: ValidationErrors{
+				fmt.Errorf("validation error: only one of 'configMap', 'secret', 'emptyDir' is allowed at '.spec.volumes[1]', found 'configMap' (line 8), 'secret' (line 10)"),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		configNode, fileNode, sortConfigs := parseConfigAndFileTestNodes(t, configYaml, tc.fileYaml)
+		sortConfigs.ConfigNodes = ConfigNodes{"Pod": configNode}
+
+		gotErrs := WalkFindGroupErrors(configNode, fileNode, sortConfigs, ValidationErrors{})
+		expected := GetValidationErrorStrings(tc.expectedErrs)
+		got := GetValidationErrorStrings(gotErrs)
+		if got != expected {
+			t.Errorf("Description: %s: compare.WalkFindGroupErrors(...): \n-expected:\n%v\n+got:\n%v\n", tc.note, expected, got)
+		}
+		if tc.expectedYaml == "" {
+			continue
+		}
+
+		errs, _ := WalkAndSort(configNode, fileNode, sortConfigs, ValidationErrors{})
+		if len(errs) != 0 {
+			t.Fatalf("Description: %s: compare.WalkAndSort(...): unexpected errors: %v", tc.note, errs)
+		}
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(fileNode.Node); err != nil {
+			t.Fatalf("failed encoding: %v", err)
+		}
+		if buf.String() != tc.expectedYaml {
+			t.Errorf("Description: %s: compare.WalkAndSort(...): \n-expected:\n%v\n+got:\n%v\n", tc.note, tc.expectedYaml, buf.String())
+		}
+	}
+}
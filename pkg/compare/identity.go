@@ -0,0 +1,172 @@
+/*
+Copyright © 2022 david amick git@davidamick.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package compare
+
+import (
+	"path"
+	"strings"
+)
+
+// fragmentPrefix marks config fragment names, in ConfigNodes and in dittos
+const fragmentPrefix = "@"
+
+// ConfigName returns the name a config is stored under in ConfigNodes.
+// Configs with an apiVersion are qualified with it, e.g. 'gateway.networking.k8s.io/*/Gateway',
+// others are named by kind alone and match any apiVersion.
+// Fragments are named '@name' and are never matched against target files.
+func (fileConfigs FileConfigs) ConfigName() string {
+	if fileConfigs.Fragment != "" {
+		return fragmentPrefix + fileConfigs.Fragment
+	}
+	if fileConfigs.Kind == "" || fileConfigs.APIVersion == "" {
+		return fileConfigs.Kind
+	}
+
+	return fileConfigs.APIVersion + "/" + fileConfigs.Kind
+}
+
+// SchemaName describes a target file's schema for messages, e.g. 'apps/v1 Deployment'
+func (fileConfigs FileConfigs) SchemaName() string {
+	if fileConfigs.APIVersion == "" {
+		return fileConfigs.Kind
+	}
+
+	return fileConfigs.APIVersion + " " + fileConfigs.Kind
+}
+
+// GetConfigName returns the name a config file is stored under in ConfigNodes, or "" if it has no kind or fragment name.
+// A config only has an apiVersion when one is given in its '# predictable-yaml:' comment,
+// a top level apiVersion value in a config file is an example value like any other.
+func GetConfigName(node *Node) string {
+	fileConfigs := getCommentFileConfigs(node)
+	if fileConfigs.Kind == "" && fileConfigs.Fragment == "" {
+		fileConfigs.Kind = getTopLevelScalar(node, "kind")
+	}
+
+	return fileConfigs.ConfigName()
+}
+
+// splitConfigName splits a config name into its apiVersion pattern and kind
+func splitConfigName(name string) (string, string) {
+	index := strings.LastIndex(name, "/")
+	if index == -1 {
+		return "", name
+	}
+
+	return name[:index], name[index+1:]
+}
+
+// splitDittoName splits a cross-schema ditto like 'apps/v1/Deployment.spec.template'
+// into the config name and the path within it. A qualified name is 'version/Kind' or 'group/version/Kind',
+// whose version has no dots, so slashes of keys in the path, like 'Pod.metadata.annotations.example.com/foo',
+// stay in the path.
+func splitDittoName(ditto string) (string, string) {
+	nameEnd := 0
+	segments := strings.SplitN(ditto, "/", 3)
+	switch {
+	case strings.HasPrefix(ditto, fragmentPrefix):
+	case len(segments) == 3 && !strings.Contains(segments[1], "."):
+		nameEnd = len(segments[0]) + len(segments[1]) + 2
+	case len(segments) >= 2 && !strings.Contains(segments[0], "."):
+		nameEnd = len(segments[0]) + 1
+	}
+	dotIndex := strings.Index(ditto[nameEnd:], ".")
+	if dotIndex == -1 {
+		return ditto, "."
+	}
+	dotIndex += nameEnd
+
+	return ditto[:dotIndex], ditto[dotIndex:]
+}
+
+// findDitto returns the config a cross-schema ditto refers to, its name, and the path within it.
+// The longest config name the ditto starts with wins, so no path is mistaken for part of a name,
+// otherwise the name split from the ditto is matched like a target file's kind and apiVersion.
+func (configNodes ConfigNodes) findDitto(ditto string) (*Node, string, string, bool) {
+	bestName := ""
+	for name := range configNodes {
+		if len(name) > len(bestName) && (ditto == name || strings.HasPrefix(ditto, name+".")) {
+			bestName = name
+		}
+	}
+	if bestName != "" {
+		path := strings.TrimPrefix(ditto, bestName)
+		if path == "" {
+			path = "."
+		}
+		return configNodes[bestName], bestName, path, true
+	}
+
+	name, path := splitDittoName(ditto)
+	if strings.HasPrefix(name, fragmentPrefix) {
+		return nil, name, path, false
+	}
+	// an apiVersion may be matched by a config's pattern, like 'apps/v1/Deployment' by 'apps/*/Deployment'
+	apiVersion, kind := splitConfigName(name)
+	node, _, ok := configNodes.Find(FileConfigs{Kind: kind, APIVersion: apiVersion})
+
+	return node, name, path, ok
+}
+
+// apiVersionSpecificity ranks how specifically an apiVersion pattern matches an apiVersion.
+// An exact match outranks any wildcard match, wildcard matches are ranked by their literal characters,
+// and -1 means no match.
+func apiVersionSpecificity(pattern, apiVersion string) int {
+	switch {
+	case pattern == apiVersion:
+		return 1 << 16
+	case pattern == "*":
+		return 0
+	}
+	if matched, err := path.Match(pattern, apiVersion); err != nil || !matched {
+		return -1
+	}
+
+	return len(pattern) - strings.Count(pattern, "*")
+}
+
+// Find returns the most specific config for a target file's kind and apiVersion, and its name.
+// Configs qualified with a matching apiVersion win over configs named by kind alone,
+// and the first name in order wins between equally specific patterns.
+func (configNodes ConfigNodes) Find(fileConfigs FileConfigs) (*Node, string, bool) {
+	bestName := ""
+	bestSpecificity := -2
+	for name := range configNodes {
+		if strings.HasPrefix(name, fragmentPrefix) {
+			continue
+		}
+		apiVersion, kind := splitConfigName(name)
+		if kind != fileConfigs.Kind {
+			continue
+		}
+		specificity := -1 // kind alone
+		if apiVersion != "" {
+			specificity = apiVersionSpecificity(apiVersion, fileConfigs.APIVersion)
+			if specificity == -1 || fileConfigs.APIVersion == "" {
+				continue
+			}
+		}
+		if specificity > bestSpecificity || (specificity == bestSpecificity && name < bestName) {
+			bestName = name
+			bestSpecificity = specificity
+		}
+	}
+	if bestName == "" {
+		return nil, "", false
+	}
+
+	return configNodes[bestName], bestName, true
+}
@@ -0,0 +1,228 @@
+/*
+Copyright © 2022 david amick git@davidamick.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package compare
+
+import (
+	"testing"
+
+	"go.yaml.in/yaml/v3"
+)
+
+func TestGetConfigName(t *testing.T) {
+	type testCase struct {
+		note     string
+		yaml     string
+		expected string
+	}
+
+	testCases := []testCase{
+		{
+			note: "kind alone, apiVersion value is only an example",
+			yaml: `---
+apiVersion: apps/v1
+kind: Deployment  # first, required`,
+			expected: "Deployment",
+		},
+		{
+			note: "qualified by comment",
+			yaml: `---
+# predictable-yaml: kind=Gateway, apiVersion=gateway.networking.k8s.io/*
+apiVersion: gateway.networking.k8s.io/v1
+kind: Gateway`,
+			expected: "gateway.networking.k8s.io/*/Gateway",
+		},
+		{
+			note: "fragment",
+			yaml: `---
+# predictable-yaml: fragment=probe
+httpGet:
+  port: http  # first, required`,
+			expected: "@probe",
+		},
+		{
+			note: "no kind",
+			yaml: `---
+apiVersion: v1`,
+			expected: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		n := &yaml.Node{}
+		err := yaml.Unmarshal([]byte(tc.yaml), n)
+		if err != nil {
+			t.Fatalf("Description: %s: compare.GetConfigName(...): failed unmarshaling test data: %v", tc.note, err)
+		}
+		node := &Node{Node: n}
+		WalkConvertYamlNodeToMainNode(node)
+
+		got := GetConfigName(node)
+		if got != tc.expected {
+			t.Errorf("Description: %s: compare.GetConfigName(...): \n-expected:\n%v\n+got:\n%v\n", tc.note, tc.expected, got)
+		}
+	}
+}
+
+func TestConfigNodesFind(t *testing.T) {
+	configNodes := ConfigNodes{
+		"Gateway":                              &Node{},
+		"networking.istio.io/*/Gateway":        &Node{},
+		"gateway.networking.k8s.io/*/Gateway":  &Node{},
+		"gateway.networking.k8s.io/v1/Gateway": &Node{},
+		"*/Certificate":                        &Node{},
+		"apps/v1/Deployment":                   &Node{},
+		"a*/v1/Widget":                         &Node{},
+		"*b/v1/Widget":                         &Node{},
+		"@Probe":                               &Node{},
+	}
+
+	type testCase struct {
+		note         string
+		fileConfigs  FileConfigs
+		expectedName string
+	}
+
+	testCases := []testCase{
+		{
+			note:         "exact apiVersion wins",
+			fileConfigs:  FileConfigs{Kind: "Gateway", APIVersion: "gateway.networking.k8s.io/v1"},
+			expectedName: "gateway.networking.k8s.io/v1/Gateway",
+		},
+		{
+			note:         "group wildcard wins over kind alone",
+			fileConfigs:  FileConfigs{Kind: "Gateway", APIVersion: "gateway.networking.k8s.io/v1beta1"},
+			expectedName: "gateway.networking.k8s.io/*/Gateway",
+		},
+		{
+			note:         "other group",
+			fileConfigs:  FileConfigs{Kind: "Gateway", APIVersion: "networking.istio.io/v1beta1"},
+			expectedName: "networking.istio.io/*/Gateway",
+		},
+		{
+			note:         "kind alone matches anything else",
+			fileConfigs:  FileConfigs{Kind: "Gateway", APIVersion: "example.com/v1"},
+			expectedName: "Gateway",
+		},
+		{
+			note:         "kind alone matches no apiVersion",
+			fileConfigs:  FileConfigs{Kind: "Gateway"},
+			expectedName: "Gateway",
+		},
+		{
+			note:         "star matches any apiVersion",
+			fileConfigs:  FileConfigs{Kind: "Certificate", APIVersion: "cert-manager.io/v1"},
+			expectedName: "*/Certificate",
+		},
+		{
+			note:         "first name wins between equally specific patterns",
+			fileConfigs:  FileConfigs{Kind: "Widget", APIVersion: "ab/v1"},
+			expectedName: "*b/v1/Widget",
+		},
+		{
+			note:         "no match for other apiVersion",
+			fileConfigs:  FileConfigs{Kind: "Deployment", APIVersion: "apps/v1beta2"},
+			expectedName: "",
+		},
+		{
+			note:         "fragments are not matched",
+			fileConfigs:  FileConfigs{Kind: "@Probe"},
+			expectedName: "",
+		},
+		{
+			note:         "no match for other kind",
+			fileConfigs:  FileConfigs{Kind: "Service", APIVersion: "v1"},
+			expectedName: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		node, name, ok := configNodes.Find(tc.fileConfigs)
+		if name != tc.expectedName {
+			t.Errorf("Description: %s: compare.ConfigNodes.Find(...): \n-expected:\n%v\n+got:\n%v\n", tc.note, tc.expectedName, name)
+		}
+		if ok != (tc.expectedName != "") || (ok && node != configNodes[tc.expectedName]) {
+			t.Errorf("Description: %s: compare.ConfigNodes.Find(...): returned the wrong node for '%s'", tc.note, name)
+		}
+	}
+}
+
+func TestSplitDittoName(t *testing.T) {
+	type testCase struct {
+		ditto        string
+		expectedName string
+		expectedPath string
+	}
+
+	testCases := []testCase{
+		{"Deployment.spec.template", "Deployment", ".spec.template"},
+		{"Pod.spec.containers.", "Pod", ".spec.containers."},
+		{"Pod", "Pod", "."},
+		{"@probe", "@probe", "."},
+		{"@probe.httpGet", "@probe", ".httpGet"},
+		{"apps/v1/Deployment.spec.template", "apps/v1/Deployment", ".spec.template"},
+		{"gateway.networking.k8s.io/*/Gateway.spec.listeners", "gateway.networking.k8s.io/*/Gateway", ".spec.listeners"},
+		{"v1/ConfigMap.data", "v1/ConfigMap", ".data"},
+		{"ConfigMap.data.a/b", "ConfigMap", ".data.a/b"},
+		{"Pod.metadata.annotations.example.com/foo", "Pod", ".metadata.annotations.example.com/foo"},
+		{"apps/v1/Deployment.metadata.annotations.example.com/foo", "apps/v1/Deployment", ".metadata.annotations.example.com/foo"},
+		{"@probe.a/b/c", "@probe", ".a/b/c"},
+	}
+
+	for _, tc := range testCases {
+		name, path := splitDittoName(tc.ditto)
+		if name != tc.expectedName || path != tc.expectedPath {
+			t.Errorf("Description: compare.splitDittoName(%q): \n-expected:\n%v %v\n+got:\n%v %v\n", tc.ditto, tc.expectedName, tc.expectedPath, name, path)
+		}
+	}
+}
+
+func TestConfigNodesFindDitto(t *testing.T) {
+	configNodes := ConfigNodes{
+		"Pod":                                 &Node{},
+		"apps/*/Deployment":                   &Node{},
+		"gateway.networking.k8s.io/*/Gateway": &Node{},
+		"@probe":                              &Node{},
+	}
+
+	type testCase struct {
+		ditto        string
+		expectedName string
+		expectedPath string
+		expectedNode string
+	}
+
+	testCases := []testCase{
+		{"Pod.spec", "Pod", ".spec", "Pod"},
+		{"Pod", "Pod", ".", "Pod"},
+		{"Pod.metadata.annotations.example.com/foo/bar", "Pod", ".metadata.annotations.example.com/foo/bar", "Pod"},
+		{"apps/*/Deployment.spec.template", "apps/*/Deployment", ".spec.template", "apps/*/Deployment"},
+		{"apps/v1/Deployment.spec.template", "apps/v1/Deployment", ".spec.template", "apps/*/Deployment"},
+		{"gateway.networking.k8s.io/v1/Gateway.metadata.annotations.a/b", "gateway.networking.k8s.io/v1/Gateway", ".metadata.annotations.a/b", "gateway.networking.k8s.io/*/Gateway"},
+		{"@probe.httpGet", "@probe", ".httpGet", "@probe"},
+		{"@missing.httpGet", "@missing", ".httpGet", ""},
+		{"Service.metadata.annotations.a/b", "Service", ".metadata.annotations.a/b", ""},
+	}
+
+	for _, tc := range testCases {
+		node, name, path, ok := configNodes.findDitto(tc.ditto)
+		if name != tc.expectedName || path != tc.expectedPath {
+			t.Errorf("Description: compare.ConfigNodes.findDitto(%q): \n-expected:\n%v %v\n+got:\n%v %v\n", tc.ditto, tc.expectedName, tc.expectedPath, name, path)
+		}
+		if ok != (tc.expectedNode != "") || (ok && node != configNodes[tc.expectedNode]) {
+			t.Errorf("Description: compare.ConfigNodes.findDitto(%q): expected the config '%s'", tc.ditto, tc.expectedNode)
+		}
+	}
+}
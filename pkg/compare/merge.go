@@ -0,0 +1,179 @@
+/*
+Copyright © 2022 david amick git@davidamick.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package compare
+
+import (
+	"fmt"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// MergeConfig returns a new config with patch merged onto base, for configs marked 'extends=<name>'.
+// Keys in the patch override inherited keys in place, or are inserted after the patch key preceding them.
+// Maps, and the first entry of sequences, are merged recursively, other values are replaced.
+// A pair's directives are inherited unless the patch pair has its own, and keys marked '# delete' are removed.
+// Neither base nor patch is modified.
+func MergeConfig(base, patch *Node) (*Node, error) {
+	if base.Kind != yaml.DocumentNode || len(base.Content) == 0 || base.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("configuration error: extended config is not a map")
+	}
+	if patch.Kind != yaml.DocumentNode || len(patch.Content) == 0 || patch.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("configuration error: extending config is not a map")
+	}
+
+	merged := copyYamlNode(base.Node)
+	err := mergeYamlMappings(merged.Content[0], patch.Content[0], "")
+	if err != nil {
+		return nil, err
+	}
+
+	mergedNode := &Node{Node: merged}
+	WalkConvertYamlNodeToMainNode(mergedNode)
+	WalkParseLoadConfigComments(mergedNode)
+	err = WalkAndValidateConfig(mergedNode)
+	if err != nil {
+		return nil, err
+	}
+
+	return mergedNode, nil
+}
+
+// mergeYamlMappings merges the pairs of patch into base, modifying base
+func mergeYamlMappings(base, patch *yaml.Node, path string) error {
+	// position of the last patch key found in base, new keys are inserted after it
+	insertAt := -1
+	for i := 0; i+1 < len(patch.Content); i += 2 {
+		patchKey, patchValue := patch.Content[i], patch.Content[i+1]
+		keyPath := fmt.Sprintf("%s.%s", path, patchKey.Value)
+		baseIndex := yamlMappingIndex(base, patchKey.Value)
+
+		if hasDirective(patchKey, patchValue, "delete") {
+			if baseIndex == -1 {
+				return fmt.Errorf("configuration error: key marked 'delete' is not in the extended config at path: %s", keyPath)
+			}
+			base.Content = append(base.Content[:baseIndex], base.Content[baseIndex+2:]...)
+			if insertAt >= baseIndex {
+				insertAt -= 2
+			}
+			continue
+		}
+
+		if baseIndex == -1 {
+			// insert after the preceding patch key, or before the next one found in base
+			index := insertAt + 2
+			if insertAt == -1 {
+				index = nextBaseIndex(base, patch, i)
+			}
+			newKey, newValue := copyYamlNode(patchKey), copyYamlNode(patchValue)
+			if strings.Contains(newKey.HeadComment, fileConfigPrefix) {
+				newKey.HeadComment = ""
+			}
+			base.Content = append(base.Content[:index], append([]*yaml.Node{newKey, newValue}, base.Content[index:]...)...)
+			insertAt = index
+			continue
+		}
+
+		baseKey, baseValue := base.Content[baseIndex], base.Content[baseIndex+1]
+		if patchKey.LineComment != "" || patchValue.LineComment != "" {
+			baseKey.LineComment = patchKey.LineComment
+			baseValue.LineComment = patchValue.LineComment
+		}
+		switch {
+		case baseValue.Kind == yaml.MappingNode && patchValue.Kind == yaml.MappingNode:
+			err := mergeYamlMappings(baseValue, patchValue, keyPath)
+			if err != nil {
+				return err
+			}
+		case baseValue.Kind == yaml.SequenceNode && patchValue.Kind == yaml.SequenceNode:
+			if len(patchValue.Content) == 0 {
+				break
+			}
+			if len(baseValue.Content) != 0 && baseValue.Content[0].Kind == yaml.MappingNode && patchValue.Content[0].Kind == yaml.MappingNode {
+				err := mergeYamlMappings(baseValue.Content[0], patchValue.Content[0], keyPath+"[0]")
+				if err != nil {
+					return err
+				}
+				break
+			}
+			base.Content[baseIndex+1] = withLineComment(copyYamlNode(patchValue), baseValue.LineComment)
+		default:
+			base.Content[baseIndex+1] = withLineComment(copyYamlNode(patchValue), baseValue.LineComment)
+		}
+		insertAt = baseIndex
+	}
+
+	return nil
+}
+
+// nextBaseIndex returns where to insert patch key i when no preceding patch key is in base:
+// before the next patch key that is in base, or at the end.
+func nextBaseIndex(base, patch *yaml.Node, i int) int {
+	for j := i + 2; j+1 < len(patch.Content); j += 2 {
+		if index := yamlMappingIndex(base, patch.Content[j].Value); index != -1 {
+			return index
+		}
+	}
+
+	return len(base.Content)
+}
+
+// yamlMappingIndex returns the index of key in a mapping node's Content, or -1
+func yamlMappingIndex(mapping *yaml.Node, key string) int {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// hasDirective reports whether a config pair's line comment contains a directive
+func hasDirective(key, value *yaml.Node, directive string) bool {
+	for _, comment := range []string{key.LineComment, value.LineComment} {
+		directives, _ := parseDirectives(comment, configDirectives)
+		for _, d := range directives {
+			if d.name == directive {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// withLineComment sets a replaced value's line comment, unless it has its own
+func withLineComment(node *yaml.Node, lineComment string) *yaml.Node {
+	if node.LineComment == "" {
+		node.LineComment = lineComment
+	}
+
+	return node
+}
+
+// copyYamlNode returns a deep copy of a yaml node
+func copyYamlNode(node *yaml.Node) *yaml.Node {
+	newNode := *node
+	if node.Content != nil {
+		newNode.Content = make([]*yaml.Node, 0, len(node.Content))
+		for _, child := range node.Content {
+			newNode.Content = append(newNode.Content, copyYamlNode(child))
+		}
+	}
+
+	return &newNode
+}
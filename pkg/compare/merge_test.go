@@ -0,0 +1,172 @@
+/*
+Copyright © 2022 david amick git@davidamick.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package compare
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"go.yaml.in/yaml/v3"
+)
+
+func TestMergeConfig(t *testing.T) {
+	type testCase struct {
+		note         string
+		baseYaml     string
+		patchYaml    string
+		expectedYaml string
+		expectedErr  error
+	}
+
+	baseYaml := `---
+apiVersion: apps/v1  # first, required
+kind: Deployment  # required
+metadata:  # required
+  name: TODO  # first, required
+  labels: {}  # open
+  annotations: {}  # open
+spec:  # required
+  replicas: 1  # first
+  template:  # required
+    spec:
+      containers:  # required
+      - name: TODO  # first, required
+        image: TODO  # required
+`
+
+	testCases := []testCase{
+		{
+			note:     "override, insert, and delete",
+			baseYaml: baseYaml,
+			patchYaml: `---
+# predictable-yaml: kind=Deployment, extends=Deployment
+metadata:
+  labels: {}  # required, open
+  annotations: {}  # delete
+spec:
+  replicas: 3
+  minReadySeconds: 5  # required
+  template:
+    spec:
+      containers:
+      - image: TODO  # preferred
+        command: []
+      priorityClassName: TODO`,
+			expectedYaml: `apiVersion: apps/v1 # first, required
+kind: Deployment # required
+metadata: # required
+  name: TODO # first, required
+  labels: {} # required, open
+spec: # required
+  replicas: 3 # first
+  minReadySeconds: 5 # required
+  template: # required
+    spec:
+      containers: # required
+        - name: TODO # first, required
+          image: TODO # preferred
+          command: []
+      priorityClassName: TODO
+`,
+		},
+		{
+			note:     "insert before the next inherited key",
+			baseYaml: baseYaml,
+			patchYaml: `---
+# predictable-yaml: kind=Deployment, extends=Deployment
+status: {}
+replicas: 1
+spec: {}
+`,
+			expectedYaml: `apiVersion: apps/v1 # first, required
+kind: Deployment # required
+metadata: # required
+  name: TODO # first, required
+  labels: {} # open
+  annotations: {} # open
+status: {}
+replicas: 1
+spec: # required
+  replicas: 1 # first
+  template: # required
+    spec:
+      containers: # required
+        - name: TODO # first, required
+          image: TODO # required
+`,
+		},
+		{
+			note:     "delete missing key",
+			baseYaml: baseYaml,
+			patchYaml: `---
+# predictable-yaml: kind=Deployment, extends=Deployment
+metadata:
+  finalizers: []  # delete`,
+			expectedErr: fmt.Errorf("configuration error: key marked 'delete' is not in the extended config at path: .metadata.finalizers"),
+		},
+		{
+			note:     "merged config is validated",
+			baseYaml: baseYaml,
+			patchYaml: `---
+# predictable-yaml: kind=Deployment, extends=Deployment
+metadata:
+  namespace: TODO  # first`,
+			expectedErr: fmt.Errorf("configuration error: multiple keys marked as 'first' in the same map at path '.metadata', keys: 'name', 'namespace'"),
+		},
+	}
+
+	for _, tc := range testCases {
+		nodes := []*Node{}
+		for _, y := range []string{tc.baseYaml, tc.patchYaml} {
+			n := &yaml.Node{}
+			err := yaml.Unmarshal([]byte(y), n)
+			if err != nil {
+				t.Fatalf("Description: %s: compare.MergeConfig(...): failed unmarshaling test data: %v", tc.note, err)
+			}
+			node := &Node{Node: n}
+			WalkConvertYamlNodeToMainNode(node)
+			WalkParseLoadConfigComments(node)
+			nodes = append(nodes, node)
+		}
+		baseBefore, _ := yaml.Marshal(nodes[0].Node)
+
+		merged, err := MergeConfig(nodes[0], nodes[1])
+		if fmt.Sprint(err) != fmt.Sprint(tc.expectedErr) {
+			t.Errorf("Description: %s: compare.MergeConfig(...) error: \n-expected:\n%v\n+got:\n%v\n", tc.note, tc.expectedErr, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		var buffer bytes.Buffer
+		encoder := yaml.NewEncoder(&buffer)
+		encoder.SetIndent(2)
+		err = encoder.Encode(merged.Node)
+		if err != nil {
+			t.Fatalf("Description: %s: compare.MergeConfig(...): failed encoding: %v", tc.note, err)
+		}
+		if buffer.String() != tc.expectedYaml {
+			t.Errorf("Description: %s: compare.MergeConfig(...): \n-expected:\n%v\n+got:\n%v\n", tc.note, tc.expectedYaml, buffer.String())
+		}
+
+		baseAfter, _ := yaml.Marshal(nodes[0].Node)
+		if !bytes.Equal(baseBefore, baseAfter) {
+			t.Errorf("Description: %s: compare.MergeConfig(...): modified the base config:\n%s", tc.note, baseAfter)
+		}
+	}
+}
@@ -0,0 +1,299 @@
+/*
+Copyright © 2022 david amick git@davidamick.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package compare
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// WalkFindRelationErrors walks the config and file trees together, returning errors for keys marked
+// 'equals=<path>' whose value doesn't equal the value at path in the target document, and for keys marked
+// 'subset-of=<path>' whose entries aren't all in the value at path. This should be called before WalkAndSort,
+// so reported line numbers are those of the original file.
+func WalkFindRelationErrors(configNode, fileNode *Node, sortConfs SortConfigs, errs ValidationErrors) ValidationErrors {
+	walkConfigKeys(configNode, fileNode, sortConfs, func(configKeyNode *Node, filePair KeyValuePair) {
+		for _, relation := range relationsOf(configKeyNode) {
+			if err := checkRelation(filePair, relation); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	})
+
+	return errs
+}
+
+// WalkCopyMissingEntries walks the config and file trees together, copying map entries to fix the relations
+// found by WalkFindRelationErrors: for 'equals', entries at the path that are missing at the key are copied
+// to the key, and for 'subset-of', entries at the key that are missing at the path are copied to the path.
+// Entries whose values differ are left alone. Returns whether any entries were copied.
+func WalkCopyMissingEntries(configNode, fileNode *Node, sortConfs SortConfigs) bool {
+	changed := false
+	walkConfigKeys(configNode, fileNode, sortConfs, func(configKeyNode *Node, filePair KeyValuePair) {
+		for _, relation := range relationsOf(configKeyNode) {
+			source := FindValueNode(walkToRootNode(filePair.ValueNode), relation.path)
+			if source == nil {
+				continue
+			}
+			from, to := source, filePair.ValueNode
+			if relation.subset {
+				from, to = filePair.ValueNode, source
+			}
+			if copyMissingEntries(from, to, relationDirective(relation), sortConfs) {
+				changed = true
+			}
+		}
+	})
+
+	return changed
+}
+
+// relation is an 'equals' or 'subset-of' directive of a config key
+type relation struct {
+	path   string
+	subset bool
+}
+
+// relationsOf returns the relations of a config key node
+func relationsOf(keyNode *Node) []relation {
+	relations := []relation{}
+	if keyNode.Equals != "" {
+		relations = append(relations, relation{path: keyNode.Equals})
+	}
+	if keyNode.SubsetOf != "" {
+		relations = append(relations, relation{path: keyNode.SubsetOf, subset: true})
+	}
+
+	return relations
+}
+
+// relationDirective returns the name of a relation's directive
+func relationDirective(relation relation) string {
+	if relation.subset {
+		return "subset-of"
+	}
+
+	return "equals"
+}
+
+// checkRelation returns an error naming both paths when a file key's value doesn't satisfy a relation
+func checkRelation(filePair KeyValuePair, relation relation) error {
+	path := GetReferencePath(filePair.KeyNode, 0, "")
+	verb, rule := "equal", RuleEquals
+	if relation.subset {
+		verb, rule = "be a subset of", RuleSubsetOf
+	}
+
+	source := FindValueNode(walkToRootNode(filePair.ValueNode), relation.path)
+	if source == nil {
+		return NewDiagnostic(rule, filePair.KeyNode, "validation error: '%s' (line %d) must %s '%s', which is missing", path, filePair.KeyNode.Line, verb, relation.path)
+	}
+	differences := relationDifferences(filePair.ValueNode, source, path, relation.path, relation.subset)
+	if len(differences) == 0 {
+		return nil
+	}
+	sourceLine := source.Line
+	if keyNode := keyNodeOf(source); keyNode != nil {
+		sourceLine = keyNode.Line
+	}
+
+	return NewDiagnostic(rule, filePair.KeyNode, "validation error: '%s' (line %d) must %s '%s' (line %d): %s", path, filePair.KeyNode.Line, verb, relation.path, sourceLine, strings.Join(differences, ", "))
+}
+
+// relationDifferences describes how a value differs from the source value it must equal, or be a subset of.
+// Maps are described entry by entry, other values as a whole.
+func relationDifferences(node, source *Node, path, sourcePath string, subset bool) []string {
+	differences := []string{}
+	if node.Kind != yaml.MappingNode || source.Kind != yaml.MappingNode {
+		switch {
+		case subset && !nodeIsSubset(node, source):
+			differences = append(differences, "values differ")
+		case !subset && !nodesEqual(node, source):
+			differences = append(differences, "values differ")
+		}
+		return differences
+	}
+
+	sourcePairs := GetKeyValuePairs(source.NodeContent)
+	sourceIndex := indexKeyValuePairs(sourcePairs)
+	pairs := GetKeyValuePairs(node.NodeContent)
+	index := indexKeyValuePairs(pairs)
+	for _, pair := range pairs {
+		i, ok := sourceIndex[pair.Key]
+		switch {
+		case !ok:
+			differences = append(differences, fmt.Sprintf("'%s' is missing at '%s'", pair.Key, sourcePath))
+		case !nodesEqual(pair.ValueNode, sourcePairs[i].ValueNode):
+			differences = append(differences, fmt.Sprintf("'%s' differs", pair.Key))
+		}
+	}
+	if !subset {
+		for _, sourcePair := range sourcePairs {
+			if _, ok := index[sourcePair.Key]; !ok {
+				differences = append(differences, fmt.Sprintf("'%s' is missing at '%s'", sourcePair.Key, path))
+			}
+		}
+	}
+
+	return differences
+}
+
+// nodesEqual reports whether two nodes hold the same data, ignoring key order, styles, and comments
+func nodesEqual(a, b *Node) bool {
+	if a.Kind != b.Kind {
+		return false
+	}
+	switch a.Kind {
+	case yaml.MappingNode:
+		return len(a.NodeContent) == len(b.NodeContent) && nodeIsSubset(a, b)
+	case yaml.SequenceNode:
+		if len(a.NodeContent) != len(b.NodeContent) {
+			return false
+		}
+		for i := range a.NodeContent {
+			if !nodesEqual(a.NodeContent[i], b.NodeContent[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return a.Value == b.Value && a.ShortTag() == b.ShortTag()
+}
+
+// nodeIsSubset reports whether every map entry or sequence item of a is in b, other nodes must be equal
+func nodeIsSubset(a, b *Node) bool {
+	if a.Kind != b.Kind {
+		return false
+	}
+	switch a.Kind {
+	case yaml.MappingNode:
+		bPairs := GetKeyValuePairs(b.NodeContent)
+		bIndex := indexKeyValuePairs(bPairs)
+		for _, pair := range GetKeyValuePairs(a.NodeContent) {
+			i, ok := bIndex[pair.Key]
+			if !ok || !nodesEqual(pair.ValueNode, bPairs[i].ValueNode) {
+				return false
+			}
+		}
+		return true
+	case yaml.SequenceNode:
+	items:
+		for _, item := range a.NodeContent {
+			for _, bItem := range b.NodeContent {
+				if nodesEqual(item, bItem) {
+					continue items
+				}
+			}
+			return false
+		}
+		return true
+	}
+
+	return nodesEqual(a, b)
+}
+
+// FindValueNode returns the value node of a document at a path like '.spec.template.metadata.labels' or '.spec.containers[0]',
+// or nil when the target document doesn't have it
+func FindValueNode(documentNode *Node, path string) *Node {
+	if documentNode.Kind != yaml.DocumentNode || len(documentNode.NodeContent) == 0 || !startDot.MatchString(path) {
+		return nil
+	}
+	node := documentNode.NodeContent[0]
+	p := strings.ReplaceAll(strings.ReplaceAll(path, "[", "."), "]", "")
+	for _, segment := range strings.Split(p, ".")[1:] {
+		switch node.Kind {
+		case yaml.MappingNode:
+			pairs := GetKeyValuePairs(node.NodeContent)
+			i, ok := indexKeyValuePairs(pairs)[segment]
+			if !ok {
+				return nil
+			}
+			node = pairs[i].ValueNode
+		case yaml.SequenceNode:
+			i, err := strconv.Atoi(segment)
+			if err != nil || i < 0 || i >= len(node.NodeContent) {
+				return nil
+			}
+			node = node.NodeContent[i]
+		default:
+			return nil
+		}
+	}
+
+	return node
+}
+
+// copyMissingEntries copies the entries of the from map that are missing in the to map, recording each as copied
+// for the rule of the relation
+func copyMissingEntries(from, to *Node, rule string, sortConfs SortConfigs) bool {
+	if from.Kind != yaml.MappingNode || to.Kind != yaml.MappingNode {
+		return false
+	}
+	toIndex := indexKeyValuePairs(GetKeyValuePairs(to.NodeContent))
+	changed := false
+	for _, pair := range GetKeyValuePairs(from.NodeContent) {
+		if _, ok := toIndex[pair.Key]; ok {
+			continue
+		}
+		keyNode := copyNode(pair.KeyNode, to)
+		to.NodeContent = append(to.NodeContent, keyNode)
+		valueNode := copyNode(pair.ValueNode, to)
+		to.NodeContent = append(to.NodeContent, valueNode)
+		to.Content = append(to.Content, keyNode.Node, valueNode.Node)
+		changed = true
+		sortConfs.recordChange(Change{
+			Type:      EntryCopied,
+			Path:      GetReferencePath(to, 0, ""),
+			Key:       pair.Key,
+			From:      -1,
+			To:        keyNode.Index / 2,
+			ValueKind: valueNode.Kind,
+			Value:     valueNode.Value,
+			Source:    GetReferencePath(from, 0, ""),
+			Node:      keyNode,
+			Rule:      rule,
+		})
+	}
+
+	return changed
+}
+
+// copyNode deep copies a target file node without its comments or anchors, to be appended to parent
+func copyNode(node, parent *Node) *Node {
+	newYamlNode := &yaml.Node{
+		Kind:  node.Kind,
+		Style: node.Style,
+		Tag:   node.Tag,
+		Value: node.Value,
+		Alias: node.Alias,
+	}
+	newNode := &Node{
+		Node:       newYamlNode,
+		ParentNode: parent,
+		Index:      len(parent.NodeContent),
+	}
+	for _, innerNode := range node.NodeContent {
+		n := copyNode(innerNode, newNode)
+		newNode.NodeContent = append(newNode.NodeContent, n)
+		newYamlNode.Content = append(newYamlNode.Content, n.Node)
+	}
+
+	return newNode
+}
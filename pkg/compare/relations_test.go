@@ -0,0 +1,216 @@
+/*
+Copyright © 2022 david amick git@davidamick.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package compare
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"go.yaml.in/yaml/v3"
+)
+
+const relationsConfigYaml = `---
+kind: Deployment  # first, required
+spec:
+  selector:
+    matchLabels: {}  # open, subset-of=.spec.template.metadata.labels
+  template:
+    metadata:
+      labels: {}  # open
+      annotations: {}  # open, equals=.metadata.annotations
+    spec:
+      containers:
+      - name: TODO  # equals=.metadata.name
+`
+
+// parseConfigAndFileTestNodes parses a config and target file for tests of the walkers using walkConfigKeys
+func parseConfigAndFileTestNodes(t *testing.T, configYaml, fileYaml string) (*Node, *Node, SortConfigs) {
+	cN := &yaml.Node{}
+	if err := yaml.Unmarshal([]byte(configYaml), cN); err != nil {
+		t.Fatalf("failed unmarshaling config test data: %v", err)
+	}
+	configNode := &Node{Node: cN}
+	WalkConvertYamlNodeToMainNode(configNode)
+	WalkParseLoadConfigComments(configNode)
+
+	fN := &yaml.Node{}
+	if err := yaml.Unmarshal([]byte(fileYaml), fN); err != nil {
+		t.Fatalf("failed unmarshaling file test data: %v", err)
+	}
+	fileNode := &Node{Node: fN}
+	WalkConvertYamlNodeToMainNode(fileNode)
+
+	return configNode, fileNode, SortConfigs{ConfigNodes: ConfigNodes{"Deployment": configNode}, FileConfigs: GetFileConfigs(fileNode)}
+}
+
+func TestWalkFindRelationErrors(t *testing.T) {
+	type testCase struct {
+		note         string
+		expectedErrs ValidationErrors
+		fileYaml     string
+	}
+
+	testCases := []testCase{
+		{
+			note:         "relations hold",
+			expectedErrs: ValidationErrors{},
+			fileYaml: `---
+kind: Deployment
+metadata:
+  name: example
+  annotations:
+    team: cool-team
+spec:
+  selector:
+    matchLabels:
+      app: example
+  template:
+    metadata:
+      labels:
+        version: v1
+        app: example
+      annotations: {team: cool-team}
+    spec:
+      containers:
+      - name: example`,
+		},
+		{
+			note: "relations broken",
+			expectedErrs: ValidationErrors{
+				fmt.Errorf("validation error: '.spec.selector.matchLabels' (line 9) must be a subset of '.spec.template.metadata.labels' (line 14): 'app' differs, 'tier' is missing at '.spec.template.metadata.labels'"),
+				fmt.Errorf("validation error: '.spec.template.metadata.annotations' (line 16) must equal '.metadata.annotations' (line 5): 'owner' is missing at '.metadata.annotations', 'team' is missing at '.spec.template.metadata.annotations'"),
+				fmt.Errorf("validation error: '.spec.template.spec.containers[1].name' (line 21) must equal '.metadata.name' (line 4): values differ"),
+			},
+			fileYaml: `---
+kind: Deployment
+metadata:
+  name: example
+  annotations:
+    team: cool-team
+spec:
+  selector:
+    matchLabels:
+      app: example
+      tier: web
+  template:
+    metadata:
+      labels:
+        app: other
+      annotations:
+        owner: someone
+    spec:
+      containers:
+      - name: example
+      - name: sidecar`,
+		},
+		{
+			note: "missing paths",
+			expectedErrs: ValidationErrors{
+				fmt.Errorf("validation error: '.spec.selector.matchLabels' (line 5) must be a subset of '.spec.template.metadata.labels', which is missing"),
+			},
+			fileYaml: `---
+kind: Deployment
+spec:
+  selector:
+    matchLabels:
+      app: example`,
+		},
+	}
+
+	for _, tc := range testCases {
+		configNode, fileNode, sortConfigs := parseConfigAndFileTestNodes(t, relationsConfigYaml, tc.fileYaml)
+		gotErrs := WalkFindRelationErrors(configNode, fileNode, sortConfigs, ValidationErrors{})
+		expected := GetValidationErrorStrings(tc.expectedErrs)
+		got := GetValidationErrorStrings(gotErrs)
+		if got != expected {
+			t.Errorf("Description: %s: compare.WalkFindRelationErrors(...): \n-expected:\n%v\n+got:\n%v\n", tc.note, expected, got)
+		}
+	}
+}
+
+func TestWalkCopyMissingEntries(t *testing.T) {
+	fileYaml := `---
+kind: Deployment
+metadata:
+  name: example
+  annotations:
+    team: cool-team
+spec:
+  selector:
+    matchLabels:
+      app: example
+      tier: web  # the tier
+  template:
+    metadata:
+      labels:
+        app: other
+      annotations:
+        owner: someone
+`
+	expectedYaml := `kind: Deployment
+metadata:
+  name: example
+  annotations:
+    team: cool-team
+spec:
+  selector:
+    matchLabels:
+      app: example
+      tier: web # the tier
+  template:
+    metadata:
+      labels:
+        app: other
+        tier: web
+      annotations:
+        owner: someone
+        team: cool-team
+`
+	expectedChanges := []string{
+		"EntryCopied .spec.template.metadata.labels.tier from .spec.selector.matchLabels (subset-of)",
+		"EntryCopied .spec.template.metadata.annotations.team from .metadata.annotations (equals)",
+	}
+
+	configNode, fileNode, sortConfigs := parseConfigAndFileTestNodes(t, relationsConfigYaml, fileYaml)
+	changes := []Change{}
+	sortConfigs.Changes = &changes
+	if !WalkCopyMissingEntries(configNode, fileNode, sortConfigs) {
+		t.Errorf("Description: compare.WalkCopyMissingEntries(...): expected changes, got none")
+	}
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(fileNode.Node); err != nil {
+		t.Fatalf("failed encoding: %v", err)
+	}
+	if buf.String() != expectedYaml {
+		t.Errorf("Description: compare.WalkCopyMissingEntries(...): \n-expected:\n%v\n+got:\n%v\n", expectedYaml, buf.String())
+	}
+	gotChanges := []string{}
+	for _, change := range changes {
+		gotChanges = append(gotChanges, fmt.Sprintf("%s (%s)", change, change.Rule))
+	}
+	if strings.Join(gotChanges, "\n") != strings.Join(expectedChanges, "\n") {
+		t.Errorf("Description: compare.WalkCopyMissingEntries(...): changes: \n-expected:\n%v\n+got:\n%v\n", strings.Join(expectedChanges, "\n"), strings.Join(gotChanges, "\n"))
+	}
+
+	// differing values are left alone
+	if errs := WalkFindRelationErrors(configNode, fileNode, sortConfigs, ValidationErrors{}); len(errs) != 2 {
+		t.Errorf("Description: compare.WalkCopyMissingEntries(...): expected 2 remaining relation errors, got:\n%v", GetValidationErrorStrings(errs))
+	}
+}
@@ -0,0 +1,506 @@
+/*
+Copyright © 2022 david amick git@davidamick.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package compare
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// SchemaFileSuffix marks config files written in the structured schema format
+const SchemaFileSuffix = ".pyschema.yaml"
+
+// schemaPlaceholder is the example value for scalars without one
+const schemaPlaceholder = "TODO"
+
+const (
+	schemaTypeMap      = "map"
+	schemaTypeSequence = "sequence"
+	schemaTypeScalar   = "scalar"
+)
+
+// Schema is a config in the structured schema format.
+// Rather than directives in line comments, it states the order and directives of each map explicitly,
+// so comments in it are free to be prose.
+type Schema struct {
+	Kind       string `yaml:"kind,omitempty"`
+	APIVersion string `yaml:"apiVersion,omitempty"`
+	Fragment   string `yaml:"fragment,omitempty"`
+	Extends    string `yaml:"extends,omitempty"`
+
+	SchemaNode `yaml:",inline"`
+}
+
+// SchemaNode describes a map, sequence, or scalar in a Schema.
+// The type is inferred when not given: 'order' or 'children' make a map, 'items' a sequence, otherwise a scalar,
+// and a bare 'ditto' or 'open' is an empty map.
+type SchemaNode struct {
+	Type        string                 `yaml:"type,omitempty"`
+	Value       string                 `yaml:"value,omitempty"`
+	Open        bool                   `yaml:"open,omitempty"`
+	Ditto       string                 `yaml:"ditto,omitempty"`
+	Equals      string                 `yaml:"equals,omitempty"`
+	SubsetOf    string                 `yaml:"subset-of,omitempty"`
+	BlankBefore bool                   `yaml:"blank-before,omitempty"`
+	NoBlank     bool                   `yaml:"no-blank,omitempty"`
+	Style       string                 `yaml:"style,omitempty"`
+	OmitEmpty   bool                   `yaml:"omit-empty,omitempty"`
+	Delete      bool                   `yaml:"delete,omitempty"`
+	Order       []string               `yaml:"order,omitempty"`
+	First       string                 `yaml:"first,omitempty"`
+	Required    []string               `yaml:"required,omitempty"`
+	Preferred   []string               `yaml:"preferred,omitempty"`
+	OneOf       map[string][]string    `yaml:"one-of,omitempty"`
+	AnyOf       map[string][]string    `yaml:"any-of,omitempty"`
+	Children    map[string]*SchemaNode `yaml:"children,omitempty"`
+	Items       *SchemaNode            `yaml:"items,omitempty"`
+}
+
+// IsSchemaFile reports whether a config file name is in the structured schema format
+func IsSchemaFile(fileName string) bool {
+	return strings.HasSuffix(fileName, SchemaFileSuffix) || strings.HasSuffix(fileName, ".pyschema.yml")
+}
+
+// TrimSchemaFileSuffix strips the schema format's suffix, or the YAML extension, from a config file name
+func TrimSchemaFileSuffix(fileName string) string {
+	for _, suffix := range []string{SchemaFileSuffix, ".pyschema.yml", ".yaml", ".yml"} {
+		if strings.HasSuffix(fileName, suffix) {
+			return strings.TrimSuffix(fileName, suffix)
+		}
+	}
+
+	return fileName
+}
+
+// ParseSchema reads a config in the structured schema format and returns it as a config in the comment format,
+// ready for WalkConvertYamlNodeToMainNode and WalkParseLoadConfigComments.
+func ParseSchema(data []byte) (*yaml.Node, error) {
+	schema := &Schema{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	err := decoder.Decode(schema)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshaling schema: %w", err)
+	}
+
+	configYaml, err := schema.ToConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	// round trip through text so nodes get the line numbers directives are attributed by
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	err = encoder.Encode(configYaml)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding schema: %w", err)
+	}
+	node := &yaml.Node{}
+	err = yaml.Unmarshal(buffer.Bytes(), node)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshaling converted schema: %w", err)
+	}
+
+	return node, nil
+}
+
+// ToConfig converts a Schema to a config document in the comment format
+func (schema *Schema) ToConfig() (*yaml.Node, error) {
+	if schema.inferType() != schemaTypeMap {
+		return nil, fmt.Errorf("configuration error: schema must describe a map")
+	}
+	if schema.Open || schema.Ditto != "" {
+		return nil, fmt.Errorf("configuration error: 'open' and 'ditto' are not supported at the top level of a schema")
+	}
+	mapping, err := schema.SchemaNode.toYamlNode("")
+	if err != nil {
+		return nil, err
+	}
+
+	identity := []string{}
+	for _, pair := range [][2]string{
+		{"kind", schema.Kind},
+		{"apiVersion", schema.APIVersion},
+		{"fragment", schema.Fragment},
+		{"extends", schema.Extends},
+	} {
+		if pair[1] != "" {
+			identity = append(identity, pair[0]+"="+formatDirectiveValue(pair[1]))
+		}
+	}
+	if len(identity) != 0 && len(mapping.Content) != 0 {
+		mapping.Content[0].HeadComment = "# predictable-yaml: " + strings.Join(identity, ", ")
+	}
+
+	return &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{mapping}}, nil
+}
+
+// inferType returns the node's type, inferring it when not given
+func (schemaNode *SchemaNode) inferType() string {
+	switch {
+	case schemaNode == nil:
+		return schemaTypeScalar
+	case schemaNode.Type != "":
+		return schemaNode.Type
+	case len(schemaNode.Order) != 0 || len(schemaNode.Children) != 0:
+		return schemaTypeMap
+	case schemaNode.Items != nil:
+		return schemaTypeSequence
+	case schemaNode.Value == "" && (schemaNode.Ditto != "" || schemaNode.Open):
+		return schemaTypeMap
+	}
+
+	return schemaTypeScalar
+}
+
+// toYamlNode converts a SchemaNode to a config value node
+func (schemaNode *SchemaNode) toYamlNode(path string) (*yaml.Node, error) {
+	displayPath := path
+	if displayPath == "" {
+		displayPath = "."
+	}
+
+	switch schemaNode.inferType() {
+	case schemaTypeScalar:
+		value := schemaPlaceholder
+		if schemaNode != nil && schemaNode.Value != "" {
+			value = schemaNode.Value
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: value}, nil
+	case schemaTypeSequence:
+		sequence := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		if schemaNode.Items == nil {
+			sequence.Style = yaml.FlowStyle
+			return sequence, nil
+		}
+		if schemaNode.Items.Open || schemaNode.Items.Ditto != "" {
+			return nil, fmt.Errorf("configuration error: 'open' and 'ditto' belong on the sequence, not its 'items', at path: %s", displayPath)
+		}
+		if schemaNode.Items.Equals != "" || schemaNode.Items.SubsetOf != "" {
+			return nil, fmt.Errorf("configuration error: 'equals' and 'subset-of' belong on the sequence, not its 'items', at path: %s", displayPath)
+		}
+		if schemaNode.Items.Style != "" || schemaNode.Items.OmitEmpty {
+			return nil, fmt.Errorf("configuration error: 'style' and 'omit-empty' belong on the sequence, not its 'items', at path: %s", displayPath)
+		}
+		if schemaNode.Items.Delete {
+			return nil, fmt.Errorf("configuration error: 'delete' belongs on the sequence, not its 'items', at path: %s", displayPath)
+		}
+		if schemaNode.Items.BlankBefore || schemaNode.Items.NoBlank {
+			return nil, fmt.Errorf("configuration error: 'blank-before' and 'no-blank' belong on the sequence, or on the first key of its 'items', at path: %s", displayPath)
+		}
+		item, err := schemaNode.Items.toYamlNode(path + "[0]")
+		if err != nil {
+			return nil, err
+		}
+		sequence.Content = []*yaml.Node{item}
+		return sequence, nil
+	case schemaTypeMap:
+	default:
+		return nil, fmt.Errorf("configuration error: unknown type '%s' at path: %s, expected one of 'map', 'sequence', 'scalar'", schemaNode.Type, displayPath)
+	}
+
+	mapping := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	inOrder := map[string]bool{}
+	for _, key := range schemaNode.Order {
+		if inOrder[key] {
+			return nil, fmt.Errorf("configuration error: key '%s' is listed twice in 'order' at path: %s", key, displayPath)
+		}
+		inOrder[key] = true
+	}
+	checkListed := func(field string, keys ...string) error {
+		for _, key := range keys {
+			if !inOrder[key] {
+				return fmt.Errorf("configuration error: key '%s' in '%s' is not listed in 'order' at path: %s", key, field, displayPath)
+			}
+		}
+		return nil
+	}
+	if schemaNode.First != "" {
+		if err := checkListed("first", schemaNode.First); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkListed("required", schemaNode.Required...); err != nil {
+		return nil, err
+	}
+	if err := checkListed("preferred", schemaNode.Preferred...); err != nil {
+		return nil, err
+	}
+	for key := range schemaNode.Children {
+		if err := checkListed("children", key); err != nil {
+			return nil, err
+		}
+	}
+	oneOfGroups, err := groupsByKey("one-of", schemaNode.OneOf, checkListed, displayPath)
+	if err != nil {
+		return nil, err
+	}
+	anyOfGroups, err := groupsByKey("any-of", schemaNode.AnyOf, checkListed, displayPath)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range schemaNode.Order {
+		child := schemaNode.Children[key]
+		valueNode, err := child.toYamlNode(path + "." + key)
+		if err != nil {
+			return nil, err
+		}
+
+		directives := []string{}
+		if key == schemaNode.First {
+			directives = append(directives, "first")
+		}
+		if slices.Contains(schemaNode.Required, key) {
+			directives = append(directives, "required")
+		}
+		if slices.Contains(schemaNode.Preferred, key) {
+			directives = append(directives, "preferred")
+		}
+		if child != nil && child.Open {
+			directives = append(directives, "open")
+		}
+		if child != nil && child.Ditto != "" {
+			directives = append(directives, "ditto="+formatDirectiveValue(child.Ditto))
+		}
+		if child != nil && child.Equals != "" {
+			directives = append(directives, "equals="+formatDirectiveValue(child.Equals))
+		}
+		if child != nil && child.SubsetOf != "" {
+			directives = append(directives, "subset-of="+formatDirectiveValue(child.SubsetOf))
+		}
+		if child != nil && child.BlankBefore {
+			directives = append(directives, "blank-before")
+		}
+		if child != nil && child.NoBlank {
+			directives = append(directives, "no-blank")
+		}
+		if child != nil && child.Style != "" {
+			directives = append(directives, "style="+formatDirectiveValue(child.Style))
+		}
+		if child != nil && child.OmitEmpty {
+			directives = append(directives, "omit-empty")
+		}
+		if child != nil && child.Delete {
+			directives = append(directives, "delete")
+		}
+		if group, ok := oneOfGroups[key]; ok {
+			directives = append(directives, "one-of="+formatDirectiveValue(group))
+		}
+		if group, ok := anyOfGroups[key]; ok {
+			directives = append(directives, "any-of="+formatDirectiveValue(group))
+		}
+		keyNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}
+		if len(directives) != 0 {
+			// the encoder only keeps comments for flow values when they're on the value
+			if valueNode.Style == yaml.FlowStyle {
+				valueNode.LineComment = "# " + strings.Join(directives, ", ")
+			} else {
+				keyNode.LineComment = "# " + strings.Join(directives, ", ")
+			}
+		}
+		mapping.Content = append(mapping.Content, keyNode, valueNode)
+	}
+	if len(mapping.Content) == 0 {
+		mapping.Style = yaml.FlowStyle
+	}
+
+	return mapping, nil
+}
+
+// MarshalSchema encodes a Schema, with key lists in flow style
+func MarshalSchema(schema *Schema) ([]byte, error) {
+	node := &yaml.Node{}
+	err := node.Encode(schema)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding schema: %w", err)
+	}
+	flowKeyLists(node)
+
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	err = encoder.Encode(node)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding schema: %w", err)
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// flowKeyLists sets sequences of scalars, like 'order' and 'required', to flow style
+func flowKeyLists(node *yaml.Node) {
+	if node.Kind == yaml.SequenceNode {
+		node.Style = yaml.FlowStyle
+	}
+	for _, child := range node.Content {
+		flowKeyLists(child)
+	}
+}
+
+// ConfigToSchema converts a config in the comment format to the structured schema format.
+// The config's comments must already be loaded with WalkParseLoadConfigComments.
+func ConfigToSchema(configNode *Node) (*Schema, error) {
+	if configNode.Kind != yaml.DocumentNode || len(configNode.NodeContent) == 0 || configNode.NodeContent[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("configuration error: config is not a map")
+	}
+	fileConfigs := getCommentFileConfigs(configNode)
+	schema := &Schema{
+		Kind:       fileConfigs.Kind,
+		APIVersion: fileConfigs.APIVersion,
+		Fragment:   fileConfigs.Fragment,
+		Extends:    fileConfigs.Extends,
+	}
+	if schema.Kind == "" && schema.Fragment == "" {
+		schema.Kind = getTopLevelScalar(configNode, "kind")
+	}
+	schema.SchemaNode = *nodeToSchemaNode(configNode.NodeContent[0])
+
+	return schema, nil
+}
+
+// nodeToSchemaNode converts a config value node to a SchemaNode, or nil for a plain placeholder scalar
+func nodeToSchemaNode(node *Node) *SchemaNode {
+	schemaNode := &SchemaNode{}
+	switch node.Kind {
+	case yaml.MappingNode:
+		if len(node.NodeContent) == 0 {
+			schemaNode.Type = schemaTypeMap
+		}
+		for _, pair := range GetKeyValuePairs(node.NodeContent) {
+			schemaNode.Order = append(schemaNode.Order, pair.Key)
+			if pair.KeyNode.MustBeFirst {
+				schemaNode.First = pair.Key
+			}
+			if pair.KeyNode.Required {
+				schemaNode.Required = append(schemaNode.Required, pair.Key)
+			}
+			if pair.KeyNode.Preferred {
+				schemaNode.Preferred = append(schemaNode.Preferred, pair.Key)
+			}
+			if pair.KeyNode.OneOf != "" {
+				if schemaNode.OneOf == nil {
+					schemaNode.OneOf = map[string][]string{}
+				}
+				schemaNode.OneOf[pair.KeyNode.OneOf] = append(schemaNode.OneOf[pair.KeyNode.OneOf], pair.Key)
+			}
+			if pair.KeyNode.AnyOf != "" {
+				if schemaNode.AnyOf == nil {
+					schemaNode.AnyOf = map[string][]string{}
+				}
+				schemaNode.AnyOf[pair.KeyNode.AnyOf] = append(schemaNode.AnyOf[pair.KeyNode.AnyOf], pair.Key)
+			}
+			child := nodeToSchemaNode(pair.ValueNode)
+			if pair.KeyNode.Open || pair.KeyNode.Ditto != "" {
+				if child == nil {
+					child = &SchemaNode{Type: schemaTypeScalar}
+				}
+				child.Open = pair.KeyNode.Open
+				child.Ditto = pair.KeyNode.Ditto
+				if child.Type == schemaTypeMap && len(child.Order) == 0 {
+					// a bare 'ditto' or 'open' is inferred as an empty map
+					child.Type = ""
+				}
+			}
+			if pair.KeyNode.Equals != "" || pair.KeyNode.SubsetOf != "" {
+				if child == nil {
+					child = &SchemaNode{Type: schemaTypeScalar}
+				}
+				child.Equals = pair.KeyNode.Equals
+				child.SubsetOf = pair.KeyNode.SubsetOf
+			}
+			if pair.KeyNode.BlankBefore || pair.KeyNode.NoBlank {
+				if child == nil {
+					child = &SchemaNode{Type: schemaTypeScalar}
+				}
+				child.BlankBefore = pair.KeyNode.BlankBefore
+				child.NoBlank = pair.KeyNode.NoBlank
+			}
+			if pair.KeyNode.CollectionStyle != "" {
+				if child == nil {
+					child = &SchemaNode{Type: schemaTypeScalar}
+				}
+				child.Style = pair.KeyNode.CollectionStyle
+			}
+			if pair.KeyNode.OmitEmpty {
+				if child == nil {
+					child = &SchemaNode{Type: schemaTypeScalar}
+				}
+				child.OmitEmpty = true
+			}
+			// 'delete' only matters when merging extended configs, so it's read from the comments
+			if hasDirective(pair.KeyNode.Node, pair.ValueNode.Node, "delete") {
+				if child == nil {
+					child = &SchemaNode{Type: schemaTypeScalar}
+				}
+				child.Delete = true
+			}
+			if child != nil {
+				if schemaNode.Children == nil {
+					schemaNode.Children = map[string]*SchemaNode{}
+				}
+				schemaNode.Children[pair.Key] = child
+			}
+		}
+	case yaml.SequenceNode:
+		if len(node.NodeContent) == 0 {
+			schemaNode.Type = schemaTypeSequence
+			break
+		}
+		schemaNode.Items = nodeToSchemaNode(node.NodeContent[0])
+		if schemaNode.Items == nil {
+			schemaNode.Items = &SchemaNode{Value: schemaPlaceholder}
+		}
+	default:
+		if node.Value == schemaPlaceholder || node.Value == "" {
+			return nil
+		}
+		schemaNode.Value = node.Value
+	}
+
+	return schemaNode
+}
+
+// groupsByKey returns the group name of each key of a schema map's 'one-of' or 'any-of' groups,
+// which must be listed in 'order' and be in only one group
+func groupsByKey(field string, groups map[string][]string, checkListed func(field string, keys ...string) error, displayPath string) (map[string]string, error) {
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	byKey := map[string]string{}
+	for _, name := range names {
+		keys := groups[name]
+		if err := checkListed(field, keys...); err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			if other, ok := byKey[key]; ok && other != name {
+				return nil, fmt.Errorf("configuration error: key '%s' is in more than one '%s' group at path: %s", key, field, displayPath)
+			}
+			byKey[key] = name
+		}
+	}
+
+	return byKey, nil
+}
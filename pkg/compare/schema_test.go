@@ -0,0 +1,489 @@
+/*
+Copyright © 2022 david amick git@davidamick.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package compare
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"go.yaml.in/yaml/v3"
+)
+
+func TestParseSchema(t *testing.T) {
+	type testCase struct {
+		note         string
+		schemaYaml   string
+		expectedYaml string
+		expectedErr  error
+	}
+
+	testCases := []testCase{
+		{
+			note: "maps, sequences, dittos, and prose comments",
+			schemaYaml: `---
+# Deployments are the most common workload.
+kind: Deployment
+apiVersion: apps/v1
+order: [apiVersion, kind, metadata, spec]
+first: apiVersion
+required: [apiVersion, kind, metadata, spec]
+children:
+  apiVersion:
+    value: apps/v1
+  kind:
+    value: Deployment
+  metadata:
+    order: [name, labels]
+    first: name
+    required: [name]
+    children:
+      # labels may hold anything
+      labels:
+        open: true
+  spec:
+    order: [replicas, template]
+    preferred: [replicas]
+    children:
+      replicas:
+        value: "1"
+      template:
+        ditto: Pod
+`,
+			expectedYaml: `# predictable-yaml: kind=Deployment, apiVersion=apps/v1
+apiVersion: apps/v1 # first, required
+kind: Deployment # required
+metadata: # required
+  name: TODO # first, required
+  labels: {} # open
+spec: # required
+  replicas: 1 # preferred
+  template: {} # ditto=Pod
+`,
+		},
+		{
+			note: "items can not have dittos",
+			schemaYaml: `---
+fragment: container
+order: [name, ports, args, env]
+first: name
+required: [name]
+children:
+  name:
+  ports:
+    items:
+      order: [name, containerPort]
+      required: [containerPort]
+  args:
+    type: sequence
+  env:
+    items:
+      type: map
+      ditto: "@env"
+`,
+			expectedErr: fmt.Errorf("configuration error: 'open' and 'ditto' belong on the sequence, not its 'items', at path: .env"),
+		},
+		{
+			note: "sequence items and null placeholders",
+			schemaYaml: `---
+fragment: container
+order: [name, ports, args]
+first: name
+required: [name]
+children:
+  name:
+  ports:
+    items:
+      order: [name, containerPort]
+      required: [containerPort]
+  args:
+    type: sequence
+`,
+			expectedYaml: `# predictable-yaml: fragment=container
+name: TODO # first, required
+ports:
+  - name: TODO
+    containerPort: TODO # required
+args: []
+`,
+		},
+		{
+			note: "keys must be listed in order",
+			schemaYaml: `---
+kind: Service
+order: [apiVersion, kind]
+required: [metadata]
+`,
+			expectedErr: fmt.Errorf("configuration error: key 'metadata' in 'required' is not listed in 'order' at path: ."),
+		},
+		{
+			note: "children must be listed in order",
+			schemaYaml: `---
+kind: Service
+order: [spec]
+children:
+  spec:
+    order: [ports]
+    children:
+      selector:
+        open: true
+`,
+			expectedErr: fmt.Errorf("configuration error: key 'selector' in 'children' is not listed in 'order' at path: .spec"),
+		},
+		{
+			note: "keys can only be in one group",
+			schemaYaml: `---
+kind: Pod
+order: [spec]
+children:
+  spec:
+    order: [configMap, secret, emptyDir]
+    one-of:
+      source: [configMap, secret]
+      storage: [secret, emptyDir]
+`,
+			expectedErr: fmt.Errorf("configuration error: key 'secret' is in more than one 'one-of' group at path: .spec"),
+		},
+		{
+			note: "unknown fields are rejected",
+			schemaYaml: `---
+kind: Service
+order: [spec]
+requried: [spec]
+`,
+			expectedErr: fmt.Errorf("error unmarshaling schema: yaml: unmarshal errors:\n  line 4: field requried not found in type compare.Schema"),
+		},
+		{
+			note: "unknown type",
+			schemaYaml: `---
+kind: Service
+order: [spec]
+children:
+  spec:
+    type: list
+`,
+			expectedErr: fmt.Errorf("configuration error: unknown type 'list' at path: .spec, expected one of 'map', 'sequence', 'scalar'"),
+		},
+	}
+
+	for _, tc := range testCases {
+		node, err := ParseSchema([]byte(tc.schemaYaml))
+		if fmt.Sprint(err) != fmt.Sprint(tc.expectedErr) {
+			t.Errorf("Description: %s: compare.ParseSchema(...) error: \n-expected:\n%v\n+got:\n%v\n", tc.note, tc.expectedErr, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		var buffer bytes.Buffer
+		encoder := yaml.NewEncoder(&buffer)
+		encoder.SetIndent(2)
+		err = encoder.Encode(node)
+		if err != nil {
+			t.Fatalf("Description: %s: compare.ParseSchema(...): failed encoding: %v", tc.note, err)
+		}
+		if buffer.String() != tc.expectedYaml {
+			t.Errorf("Description: %s: compare.ParseSchema(...): \n-expected:\n%v\n+got:\n%v\n", tc.note, tc.expectedYaml, buffer.String())
+		}
+	}
+}
+
+func TestParseSchemaDirectives(t *testing.T) {
+	node, err := ParseSchema([]byte(`---
+kind: Pod
+order: [apiVersion, kind, spec]
+first: apiVersion
+required: [kind]
+children:
+  spec:
+    order: [containers, volumes]
+    required: [containers]
+    children:
+      containers:
+        items:
+          order: [name, image]
+          first: name
+          preferred: [image]
+      volumes:
+        type: sequence
+        ditto: "@volumes"
+`))
+	if err != nil {
+		t.Fatalf("Description: compare.ParseSchema(...): unexpected error: %v", err)
+	}
+	configNode := &Node{Node: node}
+	WalkConvertYamlNodeToMainNode(configNode)
+	WalkParseLoadConfigComments(configNode)
+	if err := WalkAndValidateConfig(configNode); err != nil {
+		t.Fatalf("Description: compare.ParseSchema(...): converted config is invalid: %v", err)
+	}
+	if name := GetConfigName(configNode); name != "Pod" {
+		t.Errorf("Description: compare.ParseSchema(...): config name: \n-expected:\n%v\n+got:\n%v\n", "Pod", name)
+	}
+
+	type directives struct {
+		first, required, preferred, open bool
+		ditto                            string
+	}
+	type testCase struct {
+		path     string
+		expected directives
+	}
+	testCases := []testCase{
+		{".apiVersion", directives{first: true}},
+		{".kind", directives{required: true}},
+		{".spec", directives{}},
+		{".spec.containers", directives{required: true}},
+		{".spec.containers[0].name", directives{first: true}},
+		{".spec.containers[0].image", directives{preferred: true}},
+		{".spec.volumes", directives{ditto: "@volumes"}},
+	}
+	for _, tc := range testCases {
+		keyNode := findSchemaTestKey(configNode.NodeContent[0], tc.path, "")
+		if keyNode == nil {
+			t.Errorf("Description: compare.ParseSchema(...): no key at path '%s'", tc.path)
+			continue
+		}
+		got := directives{keyNode.MustBeFirst, keyNode.Required, keyNode.Preferred, keyNode.Open, keyNode.Ditto}
+		if got != tc.expected {
+			t.Errorf("Description: compare.ParseSchema(...): directives at '%s': \n-expected:\n%+v\n+got:\n%+v\n", tc.path, tc.expected, got)
+		}
+	}
+}
+
+// findSchemaTestKey returns the key node at a path like '.spec.containers[0].name'
+func findSchemaTestKey(node *Node, path, current string) *Node {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for _, pair := range GetKeyValuePairs(node.NodeContent) {
+			keyPath := current + "." + pair.Key
+			if keyPath == path {
+				return pair.KeyNode
+			}
+			if found := findSchemaTestKey(pair.ValueNode, path, keyPath); found != nil {
+				return found
+			}
+		}
+	case yaml.SequenceNode:
+		if len(node.NodeContent) != 0 {
+			return findSchemaTestKey(node.NodeContent[0], path, current+"[0]")
+		}
+	}
+
+	return nil
+}
+
+func TestConfigToSchema(t *testing.T) {
+	configYaml := `---
+# predictable-yaml: kind=Deployment, apiVersion=apps/v1
+apiVersion: apps/v1  # first, required
+kind: Deployment  # required
+metadata:  # required
+  name: TODO  # first, required
+  labels: {}  # open
+  annotations: {}  # open, omit-empty
+spec:  # required
+  replicas: 1  # preferred
+  template: {}  # ditto=Pod
+  strategy: {}  # style=block
+  containers:
+  - name: TODO  # first
+    command: []  # any-of=entrypoint
+    args: []  # any-of=entrypoint
+  volumes: []  # ditto=@volumes
+  selector: {}  # subset-of=.metadata.labels
+  ports: []  # blank-before, no-blank
+`
+	expectedSchema := `kind: Deployment
+apiVersion: apps/v1
+order: [apiVersion, kind, metadata, spec]
+first: apiVersion
+required: [apiVersion, kind, metadata, spec]
+children:
+  apiVersion:
+    value: apps/v1
+  kind:
+    value: Deployment
+  metadata:
+    order: [name, labels, annotations]
+    first: name
+    required: [name]
+    children:
+      annotations:
+        open: true
+        omit-empty: true
+      labels:
+        open: true
+  spec:
+    order: [replicas, template, strategy, containers, volumes, selector, ports]
+    preferred: [replicas]
+    children:
+      containers:
+        items:
+          order: [name, command, args]
+          first: name
+          any-of:
+            entrypoint: [command, args]
+          children:
+            args:
+              type: sequence
+            command:
+              type: sequence
+      ports:
+        type: sequence
+        blank-before: true
+        no-blank: true
+      replicas:
+        value: "1"
+      selector:
+        type: map
+        subset-of: .metadata.labels
+      strategy:
+        type: map
+        style: block
+      template:
+        ditto: Pod
+      volumes:
+        type: sequence
+        ditto: '@volumes'
+`
+
+	n := &yaml.Node{}
+	err := yaml.Unmarshal([]byte(configYaml), n)
+	if err != nil {
+		t.Fatalf("Description: compare.ConfigToSchema(...): failed unmarshaling test data: %v", err)
+	}
+	configNode := &Node{Node: n}
+	WalkConvertYamlNodeToMainNode(configNode)
+	WalkParseLoadConfigComments(configNode)
+
+	schema, err := ConfigToSchema(configNode)
+	if err != nil {
+		t.Fatalf("Description: compare.ConfigToSchema(...): unexpected error: %v", err)
+	}
+	schemaYaml, err := MarshalSchema(schema)
+	if err != nil {
+		t.Fatalf("Description: compare.ConfigToSchema(...): failed marshaling: %v", err)
+	}
+	if string(schemaYaml) != expectedSchema {
+		t.Errorf("Description: compare.ConfigToSchema(...): \n-expected:\n%v\n+got:\n%v\n", expectedSchema, string(schemaYaml))
+	}
+
+	// converting back gives the original config
+	node, err := ParseSchema(schemaYaml)
+	if err != nil {
+		t.Fatalf("Description: compare.ConfigToSchema(...): round trip: unexpected error: %v", err)
+	}
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	_ = encoder.Encode(node)
+	expectedConfig := `# predictable-yaml: kind=Deployment, apiVersion=apps/v1
+apiVersion: apps/v1 # first, required
+kind: Deployment # required
+metadata: # required
+  name: TODO # first, required
+  labels: {} # open
+  annotations: {} # open, omit-empty
+spec: # required
+  replicas: 1 # preferred
+  template: {} # ditto=Pod
+  strategy: {} # style=block
+  containers:
+    - name: TODO # first
+      command: [] # any-of=entrypoint
+      args: [] # any-of=entrypoint
+  volumes: [] # ditto=@volumes
+  selector: {} # subset-of=.metadata.labels
+  ports: [] # blank-before, no-blank
+`
+	if buffer.String() != expectedConfig {
+		t.Errorf("Description: compare.ConfigToSchema(...): round trip: \n-expected:\n%v\n+got:\n%v\n", expectedConfig, buffer.String())
+	}
+}
+
+func TestConfigToSchemaDelete(t *testing.T) {
+	baseYaml := `---
+kind: Deployment  # first
+metadata:
+  name: TODO  # first
+  labels: {}  # open
+  annotations: {}  # open
+`
+	configYaml := `---
+# predictable-yaml: kind=Rollout, extends=Deployment
+metadata:
+  annotations: {}  # delete
+`
+	expectedSchema := `kind: Rollout
+extends: Deployment
+order: [metadata]
+children:
+  metadata:
+    order: [annotations]
+    children:
+      annotations:
+        type: map
+        delete: true
+`
+
+	n := &yaml.Node{}
+	if err := yaml.Unmarshal([]byte(configYaml), n); err != nil {
+		t.Fatalf("Description: compare.ConfigToSchema(...): failed unmarshaling test data: %v", err)
+	}
+	configNode := &Node{Node: n}
+	WalkConvertYamlNodeToMainNode(configNode)
+	WalkParseLoadConfigComments(configNode)
+
+	schema, err := ConfigToSchema(configNode)
+	if err != nil {
+		t.Fatalf("Description: compare.ConfigToSchema(...): unexpected error: %v", err)
+	}
+	schemaYaml, err := MarshalSchema(schema)
+	if err != nil {
+		t.Fatalf("Description: compare.ConfigToSchema(...): failed marshaling: %v", err)
+	}
+	if string(schemaYaml) != expectedSchema {
+		t.Errorf("Description: compare.ConfigToSchema(...): \n-expected:\n%v\n+got:\n%v\n", expectedSchema, string(schemaYaml))
+	}
+
+	// the converted config still deletes the inherited key when merged
+	node, err := ParseSchema(schemaYaml)
+	if err != nil {
+		t.Fatalf("Description: compare.ConfigToSchema(...): round trip: unexpected error: %v", err)
+	}
+	b := &yaml.Node{}
+	if err := yaml.Unmarshal([]byte(baseYaml), b); err != nil {
+		t.Fatalf("Description: compare.ConfigToSchema(...): failed unmarshaling test data: %v", err)
+	}
+	merged, err := MergeConfig(&Node{Node: b}, &Node{Node: node})
+	if err != nil {
+		t.Fatalf("Description: compare.ConfigToSchema(...): round trip: merging: unexpected error: %v", err)
+	}
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	_ = encoder.Encode(merged.Node)
+	expectedMerged := `kind: Deployment # first
+metadata:
+  name: TODO # first
+  labels: {} # open
+`
+	if buffer.String() != expectedMerged {
+		t.Errorf("Description: compare.ConfigToSchema(...): round trip: merged: \n-expected:\n%v\n+got:\n%v\n", expectedMerged, buffer.String())
+	}
+}
@@ -0,0 +1,38 @@
+/*
+Copyright © 2022 david amick git@davidamick.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package compare
+
+// Spacing holds the target file keys whose config keys have blank line directives
+type Spacing struct {
+	BlankBefore []*Node // key nodes marked 'blank-before', which must have a blank line above them
+	NoBlank     []*Node // key nodes marked 'no-blank', whose values must not contain blank lines
+}
+
+// WalkFindSpacing walks the config and file trees together, returning the file keys
+// whose config keys are marked 'blank-before' or 'no-blank'
+func WalkFindSpacing(configNode, fileNode *Node, sortConfs SortConfigs) Spacing {
+	spacing := Spacing{}
+	walkConfigKeys(configNode, fileNode, sortConfs, func(configKeyNode *Node, filePair KeyValuePair) {
+		if configKeyNode.BlankBefore {
+			spacing.BlankBefore = append(spacing.BlankBefore, filePair.KeyNode)
+		}
+		if configKeyNode.NoBlank {
+			spacing.NoBlank = append(spacing.NoBlank, filePair.KeyNode)
+		}
+	})
+
+	return spacing
+}
@@ -0,0 +1,85 @@
+/*
+Copyright © 2022 david amick git@davidamick.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package compare
+
+import (
+	"go.yaml.in/yaml/v3"
+)
+
+// the values of the 'style=' directive
+const (
+	styleBlock = "block"
+	styleFlow  = "flow"
+)
+
+// WalkFindStyleErrors walks the config and file trees together, returning errors for maps and sequences
+// whose style doesn't match the 'style=block|flow' of their config key. This should be called before WalkAndSort,
+// so reported line numbers are those of the original file.
+func WalkFindStyleErrors(configNode, fileNode *Node, sortConfs SortConfigs, errs ValidationErrors) ValidationErrors {
+	walkConfigKeys(configNode, fileNode, sortConfs, func(configKeyNode *Node, filePair KeyValuePair) {
+		if styleMismatch(configKeyNode.CollectionStyle, filePair.ValueNode) {
+			errs = append(errs, NewDiagnostic(RuleStyle, filePair.KeyNode, "validation error: '%s' (line %d) should be %s style", GetReferencePath(filePair.KeyNode, 0, ""), filePair.KeyNode.Line, configKeyNode.CollectionStyle))
+		}
+	})
+
+	return errs
+}
+
+// WalkFixStyles walks the config and file trees together, converting maps and sequences
+// to the 'style=block|flow' of their config key. Returns whether any styles were changed.
+func WalkFixStyles(configNode, fileNode *Node, sortConfs SortConfigs) bool {
+	changed := false
+	walkConfigKeys(configNode, fileNode, sortConfs, func(configKeyNode *Node, filePair KeyValuePair) {
+		style := configKeyNode.CollectionStyle
+		if !styleMismatch(style, filePair.ValueNode) {
+			return
+		}
+		if style == styleFlow {
+			filePair.ValueNode.Style |= yaml.FlowStyle
+		} else {
+			filePair.ValueNode.Style &^= yaml.FlowStyle
+		}
+		changed = true
+		sortConfs.recordChange(Change{
+			Type:      StyleChanged,
+			Path:      GetReferencePath(filePair.KeyNode.ParentNode, 0, ""),
+			Key:       filePair.Key,
+			From:      filePair.KeyNode.Index / 2,
+			To:        filePair.KeyNode.Index / 2,
+			ValueKind: filePair.ValueNode.Kind,
+			Value:     style,
+			Node:      filePair.KeyNode,
+		})
+	})
+
+	return changed
+}
+
+// styleMismatch reports whether a map or sequence doesn't have the given style, empty ones are always flow
+func styleMismatch(style string, valueNode *Node) bool {
+	if valueNode.Kind != yaml.MappingNode && valueNode.Kind != yaml.SequenceNode {
+		return false
+	}
+	isFlow := valueNode.Style&yaml.FlowStyle != 0
+	switch style {
+	case styleFlow:
+		return !isFlow
+	case styleBlock:
+		return isFlow && len(valueNode.NodeContent) != 0
+	}
+
+	return false
+}
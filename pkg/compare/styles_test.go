@@ -0,0 +1,139 @@
+/*
+Copyright © 2022 david amick git@davidamick.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package compare
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"go.yaml.in/yaml/v3"
+)
+
+func TestWalkFixStyles(t *testing.T) {
+	configYaml := `---
+kind: Pod  # first, required
+metadata:  # style=block
+  name: TODO
+  labels: {}  # open
+spec:
+  containers:
+  - name: TODO  # first
+    args: []  # style=flow
+    env: []  # style=block
+`
+
+	type testCase struct {
+		note            string
+		fileYaml        string
+		expectedErrs    ValidationErrors
+		expectedYaml    string
+		expectedChanges []string
+	}
+
+	testCases := []testCase{
+		{
+			note: "styles match",
+			fileYaml: `---
+kind: Pod
+metadata:
+  name: example
+  labels: {app: example}
+spec:
+  containers:
+  - name: example
+    args: [--a, --b]
+    env: []
+`,
+			expectedErrs:    ValidationErrors{},
+			expectedChanges: []string{},
+		},
+		{
+			note: "styles converted",
+			fileYaml: `---
+kind: Pod
+metadata: {name: example, labels: {app: example}}
+spec:
+  containers:
+  - name: example
+    args:
+    - --a
+    - --b
+    env: [{name: A, value: a}]
+`,
+			expectedErrs: ValidationErrors{
+				fmt.Errorf("validation error: '.metadata' (line 3) should be block style"),
+				fmt.Errorf("validation error: '.spec.containers[0].args' (line 7) should be flow style"),
+				fmt.Errorf("validation error: '.spec.containers[0].env' (line 10) should be block style"),
+			},
+			expectedYaml: `kind: Pod
+metadata:
+  name: example
+  labels: {app: example}
+spec:
+  containers:
+    - name: example
+      args: [--a, --b]
+      env:
+        - {name: A, value: a}
+`,
+			expectedChanges: []string{
+				"StyleChanged .metadata -> block",
+				"StyleChanged .spec.containers[0].args -> flow",
+				"StyleChanged .spec.containers[0].env -> block",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		configNode, fileNode, sortConfigs := parseConfigAndFileTestNodes(t, configYaml, tc.fileYaml)
+		sortConfigs.ConfigNodes = ConfigNodes{"Pod": configNode}
+
+		gotErrs := WalkFindStyleErrors(configNode, fileNode, sortConfigs, ValidationErrors{})
+		expected := GetValidationErrorStrings(tc.expectedErrs)
+		got := GetValidationErrorStrings(gotErrs)
+		if got != expected {
+			t.Errorf("Description: %s: compare.WalkFindStyleErrors(...): \n-expected:\n%v\n+got:\n%v\n", tc.note, expected, got)
+		}
+
+		changes := []Change{}
+		sortConfigs.Changes = &changes
+		changed := WalkFixStyles(configNode, fileNode, sortConfigs)
+		if changed != (len(tc.expectedChanges) != 0) {
+			t.Errorf("Description: %s: compare.WalkFixStyles(...): expected changed to be %v", tc.note, !changed)
+		}
+		gotChanges := []string{}
+		for _, change := range changes {
+			gotChanges = append(gotChanges, change.String())
+		}
+		if strings.Join(gotChanges, "\n") != strings.Join(tc.expectedChanges, "\n") {
+			t.Errorf("Description: %s: compare.WalkFixStyles(...): changes: \n-expected:\n%v\n+got:\n%v\n", tc.note, strings.Join(tc.expectedChanges, "\n"), strings.Join(gotChanges, "\n"))
+		}
+		if tc.expectedYaml == "" {
+			continue
+		}
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(fileNode.Node); err != nil {
+			t.Fatalf("failed encoding: %v", err)
+		}
+		if buf.String() != tc.expectedYaml {
+			t.Errorf("Description: %s: compare.WalkFixStyles(...): \n-expected:\n%v\n+got:\n%v\n", tc.note, tc.expectedYaml, buf.String())
+		}
+	}
+}
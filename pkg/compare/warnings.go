@@ -0,0 +1,99 @@
+/*
+Copyright © 2022 david amick git@davidamick.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package compare
+
+import (
+	"fmt"
+	"strings"
+)
+
+// suggestKeys returns the config keys that an unknown file key is likely a misspelling of.
+// A candidate differs only by case, or is at most two edits away (one for short keys).
+// Config keys already present in the file are not candidates.
+func suggestKeys(key string, configPairs []KeyValuePair, fileIndex map[string]int) []string {
+	maxDistance := maxEditDistance(key)
+	candidates := []string{}
+	for _, configPair := range configPairs {
+		if _, ok := fileIndex[configPair.Key]; ok {
+			continue
+		}
+		if strings.EqualFold(key, configPair.Key) || editDistance(key, configPair.Key) <= maxDistance {
+			candidates = append(candidates, configPair.Key)
+		}
+	}
+
+	return candidates
+}
+
+// suggestionWarning formats a "did you mean" warning for an unknown key
+func suggestionWarning(keyNode *Node, candidates []string) error {
+	return NewDiagnostic(RuleUnknownKey, keyNode, "warning: unknown key at '%s' (line %d) — did you mean '%s'?", GetReferencePath(keyNode, 0, ""), keyNode.Line, strings.Join(candidates, "' or '"))
+}
+
+// missingPreferredWarning formats a warning for a preferred key missing from a map
+func missingPreferredWarning(mapNode *Node, key string, line int) error {
+	if line == 0 {
+		return NewDiagnostic(RulePreferred, mapNode, "warning: missing preferred key '%s' at '%s'", key, GetReferencePath(mapNode, 0, ""))
+	}
+
+	return NewDiagnostic(RulePreferred, mapNode, "warning: missing preferred key '%s' at '%s' (line %d)", key, GetReferencePath(mapNode, 0, ""), line)
+}
+
+// FileConfigWarnings returns warnings for unknown or malformed directives in a target file's '# predictable-yaml:' comments
+func FileConfigWarnings(node *Node) ValidationErrors {
+	warnings := ValidationErrors{}
+	for _, problem := range CheckFileConfigs(node) {
+		warnings = append(warnings, &Diagnostic{
+			Rule:    RuleDirective,
+			Line:    problem.Line,
+			Message: fmt.Sprintf("warning: %v (line %d)", problem.Err, problem.Line),
+		})
+	}
+
+	return warnings
+}
+
+// maxEditDistance returns how many edits away a name may be from another to likely be a misspelling of it
+func maxEditDistance(name string) int {
+	if len([]rune(name)) <= 4 {
+		return 1
+	}
+
+	return 2
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	aRunes, bRunes := []rune(a), []rune(b)
+	previous := make([]int, len(bRunes)+1)
+	current := make([]int, len(bRunes)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(aRunes); i++ {
+		current[0] = i
+		for j := 1; j <= len(bRunes); j++ {
+			cost := 1
+			if aRunes[i-1] == bRunes[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(bRunes)]
+}
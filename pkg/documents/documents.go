@@ -0,0 +1,110 @@
+/*
+Copyright © 2022 david amick git@davidamick.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package documents
+
+import (
+	"regexp"
+	"strings"
+)
+
+var markerRegex = regexp.MustCompile(`^---\s*(#.*)?$`)
+
+// Document is one YAML document of a file
+type Document struct {
+	Prefix string // the comment lines directly above the document's '---' and the '---' line, empty if it has none
+	Body   string // the rest of the document
+	Line   int    // the file line the body starts on
+}
+
+// Split splits file content into its YAML documents, so that joining them returns the same content.
+// Comment lines directly above a '---' belong to the document it starts,
+// as does everything above the first '---' when there's no YAML there.
+func Split(content []byte) []Document {
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	// find where each document starts, and its '---' line
+	starts, markers := []int{0}, []int{-1}
+	for index, line := range lines {
+		if !markerRegex.MatchString(strings.TrimRight(line, "\r\n")) {
+			continue
+		}
+		start := index
+		for start > starts[len(starts)-1] && strings.HasPrefix(lines[start-1], "#") {
+			start--
+		}
+		if len(starts) == 1 && markers[0] == -1 && !hasContent(lines[:start]) {
+			starts[0], markers[0] = 0, index
+			continue
+		}
+		starts, markers = append(starts, start), append(markers, index)
+	}
+
+	docs := []Document{}
+	for i, start := range starts {
+		end := len(lines)
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		bodyStart := start
+		if markers[i] != -1 {
+			bodyStart = markers[i] + 1
+		}
+		docs = append(docs, Document{
+			Prefix: strings.Join(lines[start:bodyStart], ""),
+			Body:   strings.Join(lines[bodyStart:end], ""),
+			Line:   bodyStart + 1,
+		})
+	}
+
+	return docs
+}
+
+// Join joins documents into file content, adding a '---' to documents after the first that don't have one
+func Join(docs []Document) []byte {
+	var stringBuilder strings.Builder
+	for index, doc := range docs {
+		if index != 0 && doc.Prefix == "" {
+			stringBuilder.WriteString("---\n")
+		}
+		stringBuilder.WriteString(doc.Prefix)
+		stringBuilder.WriteString(doc.Body)
+		if index != len(docs)-1 && doc.Body != "" && !strings.HasSuffix(doc.Body, "\n") {
+			stringBuilder.WriteString("\n")
+		}
+	}
+
+	return []byte(stringBuilder.String())
+}
+
+// Padded returns the body preceded by empty lines, so that parsed line numbers are those of the file
+func (doc Document) Padded() []byte {
+	return []byte(strings.Repeat("\n", doc.Line-1) + doc.Body)
+}
+
+// hasContent reports whether any of the lines aren't empty or comments
+func hasContent(lines []string) bool {
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			return true
+		}
+	}
+
+	return false
+}
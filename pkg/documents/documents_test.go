@@ -0,0 +1,199 @@
+/*
+Copyright © 2022 david amick git@davidamick.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package documents
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	type testCase struct {
+		note     string
+		content  string
+		expected []Document
+	}
+
+	testCases := []testCase{
+		{
+			note:     "single document without a marker",
+			content:  "kind: Service\n",
+			expected: []Document{{Body: "kind: Service\n", Line: 1}},
+		},
+		{
+			note:     "single document with a marker and header comments",
+			content:  "# header\n\n---\nkind: Service\n",
+			expected: []Document{{Prefix: "# header\n\n---\n", Body: "kind: Service\n", Line: 4}},
+		},
+		{
+			note:    "comments above a marker start the next document",
+			content: "kind: Service\n# the namespace\n---  # two\nkind: Namespace\n---\n",
+			expected: []Document{
+				{Body: "kind: Service\n", Line: 1},
+				{Prefix: "# the namespace\n---  # two\n", Body: "kind: Namespace\n", Line: 4},
+				{Prefix: "---\n", Body: "", Line: 6},
+			},
+		},
+		{
+			note:    "indented comments and block scalars stay in their document",
+			content: "---\nkind: ConfigMap\ndata:\n  script: |\n    ---\n  # end\n---\nkind: Service",
+			expected: []Document{
+				{Prefix: "---\n", Body: "kind: ConfigMap\ndata:\n  script: |\n    ---\n  # end\n", Line: 2},
+				{Prefix: "---\n", Body: "kind: Service", Line: 8},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		got := Split([]byte(tc.content))
+		if !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("Description: %s: documents.Split(...): \n-expected:\n%#v\n+got:\n%#v\n", tc.note, tc.expected, got)
+		}
+		joined := string(Join(got))
+		if joined != tc.content {
+			t.Errorf("Description: %s: documents.Join(...): \n-expected:\n%v\n+got:\n%v\n", tc.note, tc.content, joined)
+		}
+	}
+}
+
+func TestOrder(t *testing.T) {
+	content := `---
+kind: Service
+metadata:
+  name: web
+---
+kind: Deployment
+metadata:
+  name: web
+# the service comes after the namespace
+---
+kind: Service
+metadata:
+  name: api
+---
+just: data
+---
+kind: Namespace
+metadata:
+  name: example
+`
+
+	type testCase struct {
+		note              string
+		order             Order
+		expectedIndexes   []int
+		expectedErr       error
+		expectedReordered string
+	}
+
+	testCases := []testCase{
+		{
+			note:            "listed kinds, then other kinds, then documents without a kind",
+			order:           Order{Kinds: []string{"Namespace", "Service"}},
+			expectedIndexes: []int{4, 2, 0, 1, 3},
+			expectedErr:     fmt.Errorf("validation error: documents are out of order, expected: Namespace 'example' (line 17), Service 'api' (line 11), Service 'web' (line 2), Deployment 'web' (line 6), document (line 15)"),
+			expectedReordered: `---
+kind: Namespace
+metadata:
+  name: example
+# the service comes after the namespace
+---
+kind: Service
+metadata:
+  name: api
+---
+kind: Service
+metadata:
+  name: web
+---
+kind: Deployment
+metadata:
+  name: web
+---
+just: data
+`,
+		},
+		{
+			note:            "other kinds placed by '*'",
+			order:           Order{Kinds: []string{"*", "Service"}, SortKey: ".kind"},
+			expectedIndexes: []int{1, 4, 0, 2, 3},
+			expectedErr:     fmt.Errorf("validation error: documents are out of order, expected: Deployment 'Deployment' (line 6), Namespace 'Namespace' (line 17), Service 'Service' (line 2), Service 'Service' (line 11), document (line 15)"),
+		},
+	}
+
+	docs := Split([]byte(content))
+	for _, tc := range testCases {
+		gotIndexes := tc.order.Sort(docs)
+		if !reflect.DeepEqual(gotIndexes, tc.expectedIndexes) {
+			t.Errorf("Description: %s: Order.Sort(...): \n-expected:\n%v\n+got:\n%v\n", tc.note, tc.expectedIndexes, gotIndexes)
+		}
+		gotErr := tc.order.Check(docs)
+		if fmt.Sprint(gotErr) != fmt.Sprint(tc.expectedErr) {
+			t.Errorf("Description: %s: Order.Check(...): \n-expected:\n%v\n+got:\n%v\n", tc.note, tc.expectedErr, gotErr)
+		}
+		if tc.expectedReordered != "" {
+			got := string(Join(Reorder(docs, gotIndexes)))
+			if got != tc.expectedReordered {
+				t.Errorf("Description: %s: documents.Join(documents.Reorder(...)): \n-expected:\n%v\n+got:\n%v\n", tc.note, tc.expectedReordered, got)
+			}
+		}
+		sorted := Split(Join(Reorder(docs, gotIndexes)))
+		if err := tc.order.Check(sorted); err != nil {
+			t.Errorf("Description: %s: Order.Check(sorted): \n-expected:\n%v\n+got:\n%v\n", tc.note, nil, err)
+		}
+	}
+}
+
+func TestReorderKeepsHeader(t *testing.T) {
+	content := `# Copyright example
+# generated by a tool
+
+# the config map z
+---
+kind: ConfigMap
+metadata:
+  name: z
+---
+kind: Namespace
+metadata:
+  name: example
+`
+	expected := `# Copyright example
+# generated by a tool
+
+---
+kind: Namespace
+metadata:
+  name: example
+# the config map z
+---
+kind: ConfigMap
+metadata:
+  name: z
+`
+
+	docs := Split([]byte(content))
+	got := string(Join(Reorder(docs, []int{1, 0})))
+	if got != expected {
+		t.Errorf("Description: documents.Reorder(...): \n-expected:\n%v\n+got:\n%v\n", expected, got)
+	}
+
+	// without reordering, the file is unchanged
+	if got := string(Join(Reorder(docs, []int{0, 1}))); got != content {
+		t.Errorf("Description: documents.Reorder(...): unchanged order: \n-expected:\n%v\n+got:\n%v\n", content, got)
+	}
+}
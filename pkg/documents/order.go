@@ -0,0 +1,202 @@
+/*
+Copyright © 2022 david amick git@davidamick.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package documents
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/snarlysodboxer/predictable-yaml/pkg/compare"
+	"go.yaml.in/yaml/v3"
+)
+
+// DefaultSortKey is the path of the value that orders documents of the same kind
+const DefaultSortKey = ".metadata.name"
+
+// otherKinds is the Kinds entry that places kinds not in the list, which otherwise go after all listed kinds
+const otherKinds = "*"
+
+// Order is a policy for the order of the documents in a file
+type Order struct {
+	Kinds   []string // kinds in the order their documents should be in
+	SortKey string   // path of the value that orders documents of the same kind, like '.metadata.name'
+}
+
+// sortable is what documents are ordered by
+type sortable struct {
+	rank  int
+	kind  string
+	value string
+}
+
+// Validate returns an error if the policy can't be used
+func (order Order) Validate() error {
+	if !strings.HasPrefix(order.sortKey(), ".") {
+		return fmt.Errorf("configuration error: documents sort-key '%s' must start with '.'", order.SortKey)
+	}
+
+	return nil
+}
+
+// Sort returns the indexes of the documents in the order of the policy.
+// Documents are ordered by the rank of their kind, then by kind, then by their sort key value,
+// and otherwise keep their order. Documents without a kind go last.
+func (order Order) Sort(docs []Document) []int {
+	keys := make([]sortable, len(docs))
+	indexes := make([]int, len(docs))
+	for index, doc := range docs {
+		keys[index] = order.sortable(doc)
+		indexes[index] = index
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		a, b := keys[indexes[i]], keys[indexes[j]]
+		if a.rank != b.rank {
+			return a.rank < b.rank
+		}
+		if a.kind != b.kind {
+			return a.kind < b.kind
+		}
+
+		return a.value < b.value
+	})
+
+	return indexes
+}
+
+// Check returns an error describing the expected order if the documents aren't in the order of the policy
+func (order Order) Check(docs []Document) error {
+	indexes := order.Sort(docs)
+	if IsOrdered(indexes) {
+		return nil
+	}
+	expected := []string{}
+	for _, index := range indexes {
+		expected = append(expected, order.describe(docs[index]))
+	}
+
+	return fmt.Errorf("validation error: documents are out of order, expected: %s", strings.Join(expected, ", "))
+}
+
+// IsOrdered reports whether indexes returned by Sort leave the documents where they are
+func IsOrdered(indexes []int) bool {
+	for position, index := range indexes {
+		if position != index {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Reorder returns the documents in the order of indexes returned by Sort.
+// The file's header, the lines above the comment lines directly above the first '---', like a license,
+// stays at the top of the file, while the comment lines directly above each '---' move with their document.
+func Reorder(docs []Document, indexes []int) []Document {
+	if len(docs) == 0 {
+		return docs
+	}
+	header, firstPrefix := splitHeader(docs[0].Prefix)
+	reordered := make([]Document, len(indexes))
+	for position, index := range indexes {
+		reordered[position] = docs[index]
+		if index == 0 {
+			reordered[position].Prefix = firstPrefix
+		}
+	}
+	reordered[0].Prefix = header + reordered[0].Prefix
+
+	return reordered
+}
+
+// splitHeader splits the prefix of a file's first document into the file's header
+// and the comment lines directly above its '---' with the '---' line
+func splitHeader(prefix string) (string, string) {
+	lines := strings.SplitAfter(prefix, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return "", prefix
+	}
+	start := len(lines) - 1
+	for start > 0 && strings.HasPrefix(lines[start-1], "#") {
+		start--
+	}
+
+	return strings.Join(lines[:start], ""), strings.Join(lines[start:], "")
+}
+
+// sortable returns what a document is ordered by
+func (order Order) sortable(doc Document) sortable {
+	kind, value := order.kindAndValue(doc)
+	if kind == "" {
+		return sortable{rank: len(order.Kinds) + 1}
+	}
+	rank, otherRank := -1, len(order.Kinds)
+	for index, orderKind := range order.Kinds {
+		switch orderKind {
+		case kind:
+			rank = index
+		case otherKinds:
+			otherRank = index
+		}
+	}
+	if rank == -1 {
+		rank = otherRank
+	}
+
+	return sortable{rank: rank, kind: kind, value: value}
+}
+
+// describe returns how a document is shown in errors, like "Service 'my-app' (line 12)"
+func (order Order) describe(doc Document) string {
+	kind, value := order.kindAndValue(doc)
+	if kind == "" {
+		kind = "document"
+	}
+	if value == "" {
+		return fmt.Sprintf("%s (line %d)", kind, doc.Line)
+	}
+
+	return fmt.Sprintf("%s '%s' (line %d)", kind, value, doc.Line)
+}
+
+// kindAndValue returns the kind of a document and its sort key value, which are empty if it doesn't have them
+func (order Order) kindAndValue(doc Document) (string, string) {
+	yamlNode := &yaml.Node{}
+	if err := yaml.Unmarshal([]byte(doc.Body), yamlNode); err != nil || len(yamlNode.Content) == 0 {
+		return "", ""
+	}
+	node := &compare.Node{Node: yamlNode}
+	compare.WalkConvertYamlNodeToMainNode(node)
+	kind := compare.GetFileConfigs(node).Kind
+	valueNode := compare.FindValueNode(node, order.sortKey())
+	if valueNode == nil || valueNode.Kind != yaml.ScalarNode {
+		return kind, ""
+	}
+
+	return kind, valueNode.Value
+}
+
+// sortKey returns the sort key, or the default
+func (order Order) sortKey() string {
+	if order.SortKey == "" {
+		return DefaultSortKey
+	}
+
+	return order.SortKey
+}
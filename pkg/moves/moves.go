@@ -29,6 +29,10 @@ type KeyInfo struct {
 	Key       string
 	ValueKind yaml.Kind // ScalarNode, MappingNode, SequenceNode
 	Value     string    // scalar value, empty for maps/sequences
+	Line      int       // line of the key in the target file
+	Column    int       // column of the key in the target file
+	After     string    // key it comes after once sorted, empty at the top
+	AfterLine int       // line of the After key in the target file, 0 if it was added
 }
 
 // valueDisplay returns the YAML-like value representation.
@@ -50,75 +54,35 @@ type MoveDescription struct {
 	Action string // e.g., "move before labels", "move to top"
 }
 
-// ComputeDescriptions compares old and new YAML node trees and returns
-// structural descriptions of what moved.
-func ComputeDescriptions(oldNode, newNode *compare.Node) []MoveDescription {
-	var descriptions []MoveDescription
-	walkDescriptions(oldNode, newNode, "", &descriptions)
-
-	return descriptions
-}
-
-func walkDescriptions(oldNode, newNode *compare.Node, path string, descriptions *[]MoveDescription) {
-	switch oldNode.Kind {
-	case yaml.DocumentNode:
-		if newNode.Kind != yaml.DocumentNode || len(oldNode.NodeContent) == 0 || len(newNode.NodeContent) == 0 {
-			return
-		}
-		walkDescriptions(oldNode.NodeContent[0], newNode.NodeContent[0], path, descriptions)
-
-	case yaml.MappingNode:
-		if newNode.Kind != yaml.MappingNode {
-			return
-		}
-
-		oldPairs := compare.GetKeyValuePairs(oldNode.NodeContent)
-		newPairs := compare.GetKeyValuePairs(newNode.NodeContent)
-
-		// Find keys that moved position
-		moves := findMoves(oldPairs, newPairs)
-		if len(moves) > 0 {
-			for _, m := range moves {
-				desc := MoveDescription{
-					Path:   path,
-					Keys:   m.keys,
-					Action: m.action,
-				}
-				*descriptions = append(*descriptions, desc)
-			}
+// DescribeChanges turns the moves in a change log into descriptions of keys
+// that were promoted (moved earlier) within their map. Keys that were merely
+// pushed down as a consequence are not reported.
+func DescribeChanges(changes []compare.Change) []MoveDescription {
+	// group moves by the map they happened in, in the order the maps were sorted
+	paths := []string{}
+	movesByPath := map[string][]compare.Change{}
+	for _, change := range changes {
+		if change.Type != compare.KeyMoved && change.Type != compare.UnmatchedRelocated {
+			continue
 		}
-
-		// Recurse into children
-		for _, newPair := range newPairs {
-			for _, oldPair := range oldPairs {
-				if oldPair.Key != newPair.Key {
-					continue
-				}
-				childPath := newPair.Key
-				if path != "" {
-					childPath = path + "." + newPair.Key
-				}
-
-				if oldPair.ValueNode.Kind == yaml.MappingNode || oldPair.ValueNode.Kind == yaml.SequenceNode {
-					walkDescriptions(oldPair.ValueNode, newPair.ValueNode, childPath, descriptions)
-				}
-				break
-			}
+		if _, ok := movesByPath[change.Path]; !ok {
+			paths = append(paths, change.Path)
 		}
+		movesByPath[change.Path] = append(movesByPath[change.Path], change)
+	}
 
-	case yaml.SequenceNode:
-		if newNode.Kind != yaml.SequenceNode {
-			return
-		}
-		// Sequences aren't reordered, but recurse into items
-		for i, oldItem := range oldNode.NodeContent {
-			if i >= len(newNode.NodeContent) {
-				break
-			}
-			itemPath := fmt.Sprintf("%s[%d]", path, i)
-			walkDescriptions(oldItem, newNode.NodeContent[i], itemPath, descriptions)
+	var descriptions []MoveDescription
+	for _, path := range paths {
+		for _, m := range findMoves(movesByPath[path]) {
+			descriptions = append(descriptions, MoveDescription{
+				Path:   strings.TrimPrefix(path, "."),
+				Keys:   m.keys,
+				Action: m.action,
+			})
 		}
 	}
+
+	return descriptions
 }
 
 type moveGroup struct {
@@ -126,59 +90,25 @@ type moveGroup struct {
 	action string
 }
 
-// findMoves compares old and new key orderings and returns descriptions
-// of keys that were promoted (moved earlier). Keys that were merely pushed
-// down as a consequence are not reported.
-func findMoves(oldPairs, newPairs []compare.KeyValuePair) []moveGroup {
-	if len(oldPairs) == 0 || len(newPairs) == 0 {
-		return nil
-	}
-
-	// Build position maps
-	oldPos := map[string]int{}
-	for i, pair := range oldPairs {
-		oldPos[pair.Key] = i
-	}
-	newPos := map[string]int{}
-	newPairMap := map[string]compare.KeyValuePair{}
-	for i, pair := range newPairs {
-		newPos[pair.Key] = i
-		newPairMap[pair.Key] = pair
-	}
-
-	// Get shared keys in new order
-	type keyEntry struct {
-		key    string
-		oldIdx int
-		newIdx int
-	}
-	var sharedKeyEntries []keyEntry
-	for _, pair := range newPairs {
-		if oldIndex, ok := oldPos[pair.Key]; ok {
-			sharedKeyEntries = append(sharedKeyEntries, keyEntry{key: pair.Key, oldIdx: oldIndex, newIdx: newPos[pair.Key]})
-		}
-	}
-
-	if len(sharedKeyEntries) == 0 {
-		return nil
-	}
-
-	// Only report keys that moved earlier (promoted) and whose relative
-	// order actually changed vs at least one other key.
+// findMoves returns descriptions of keys in one map that moved earlier and
+// whose order relative to at least one other moved key changed. Keys that
+// kept their position can't change order relative to each other, so
+// comparing against the moved keys alone is enough.
+func findMoves(changes []compare.Change) []moveGroup {
 	var moves []moveGroup
-	for _, sharedKeyEntry := range sharedKeyEntries {
-		if sharedKeyEntry.newIdx >= sharedKeyEntry.oldIdx {
+	for _, change := range changes {
+		if change.To >= change.From {
 			continue // didn't move earlier
 		}
 
 		// Verify relative order actually changed
 		relativelyMoved := false
-		for _, other := range sharedKeyEntries {
-			if other.key == sharedKeyEntry.key {
+		for _, other := range changes {
+			if other.Key == change.Key {
 				continue
 			}
-			oldBefore := sharedKeyEntry.oldIdx < other.oldIdx
-			newBefore := sharedKeyEntry.newIdx < other.newIdx
+			oldBefore := change.From < other.From
+			newBefore := change.To < other.To
 			if oldBefore != newBefore {
 				relativelyMoved = true
 				break
@@ -189,21 +119,14 @@ func findMoves(oldPairs, newPairs []compare.KeyValuePair) []moveGroup {
 		}
 
 		action := ""
-		if sharedKeyEntry.newIdx == 0 {
+		if change.To == 0 {
 			action = "move to top"
 		} else {
 			action = "move up"
 		}
 
-		pair := newPairMap[sharedKeyEntry.key]
-		keyInfo := KeyInfo{
-			Key:       sharedKeyEntry.key,
-			ValueKind: pair.ValueNode.Kind,
-			Value:     pair.ValueNode.Value,
-		}
-
 		moves = append(moves, moveGroup{
-			keys:   []KeyInfo{keyInfo},
+			keys:   []KeyInfo{keyInfoForChange(change)},
 			action: action,
 		})
 	}
@@ -211,6 +134,33 @@ func findMoves(oldPairs, newPairs []compare.KeyValuePair) []moveGroup {
 	return mergeConsecutiveMoves(moves)
 }
 
+func keyInfoForChange(change compare.Change) KeyInfo {
+	info := KeyInfo{
+		Key:       change.Key,
+		ValueKind: change.ValueKind,
+		Value:     change.Value,
+		Line:      change.Line,
+		Column:    change.Column,
+	}
+	if change.After != nil {
+		info.After = change.After.Value
+		info.AfterLine, _ = AfterPosition(change)
+	}
+
+	return info
+}
+
+// AfterPosition returns the line and column of the key a change's key comes after in the target file,
+// or zeros if it comes first or the key it comes after was added
+func AfterPosition(change compare.Change) (int, int) {
+	// added keys have no line of their own
+	if change.After == nil || change.After.Line == 0 {
+		return 0, 0
+	}
+
+	return compare.FilePosition(change.After)
+}
+
 func mergeConsecutiveMoves(moves []moveGroup) []moveGroup {
 	if len(moves) <= 1 {
 		return moves
@@ -233,7 +183,12 @@ func mergeConsecutiveMoves(moves []moveGroup) []moveGroup {
 type summaryNode struct {
 	segment  string // path segment, e.g. "metadata", "containers[0]"
 	moves    []MoveDescription
-	added    []string // leaf keys that were added as required fields
+	added    []compare.Change // leaf keys that were added as required fields
+	items    []KeyInfo        // items added to an empty sequence
+	renamed  []compare.Change
+	restyled []compare.Change // maps and sequences converted to block or flow style
+	removed  []compare.Change // empty maps and sequences removed for 'omit-empty'
+	copied   []compare.Change // entries copied for 'equals' and 'subset-of'
 	children []*summaryNode
 }
 
@@ -259,17 +214,20 @@ func splitPath(path string) []string {
 	return strings.Split(path, ".")
 }
 
-// FormatSummary produces a human-readable summary of changes for a file.
-// The output nests paths hierarchically to resemble a YAML structure.
-func FormatSummary(filePath string, descriptions []MoveDescription, addedFields []compare.AddedField, commentCount int) string {
-	if len(descriptions) == 0 && len(addedFields) == 0 {
+// FormatSummary produces a human-readable summary of the changes in a change log.
+// The output nests paths hierarchically to resemble a YAML structure. The summary is headed
+// with name, and keys are annotated with their position in the file at filePath.
+func FormatSummary(name, filePath string, changes []compare.Change, commentCount int) string {
+	descriptions := DescribeChanges(changes)
+	additions := Additions(changes)
+	if len(descriptions) == 0 && len(additions) == 0 {
 		return ""
 	}
 
 	color := IsTerminal()
 
 	var stringBuilder strings.Builder
-	fmt.Fprintf(&stringBuilder, "File: %s\n", filePath)
+	fmt.Fprintf(&stringBuilder, "File: %s\n", name)
 
 	// Merge descriptions that share the same path and action
 	descriptions = mergeDescriptions(descriptions)
@@ -286,20 +244,40 @@ func FormatSummary(filePath string, descriptions []MoveDescription, addedFields
 	}
 
 	// Insert added fields into the same tree.
-	// Each AddedField has a separate Path (e.g. ".metadata.labels") and
+	// Each addition has a separate Path (e.g. ".metadata.labels") and
 	// Key (e.g. "app.kubernetes.io/name"), so we can split the path
 	// correctly without ambiguity from dots in key names.
-	for _, field := range addedFields {
-		segments := splitPath(strings.TrimPrefix(field.Path, "."))
+	for _, change := range additions {
+		segments := splitPath(strings.TrimPrefix(change.Path, "."))
 		node := root
 		for _, seg := range segments {
 			node = node.findOrCreateChild(seg)
 		}
-		node.added = append(node.added, field.Key)
+		if change.Type == compare.SequenceItemAdded {
+			node.items = append(node.items, keyInfoForChange(change))
+			continue
+		}
+		if change.Type == compare.KeyRenamed {
+			node.renamed = append(node.renamed, change)
+			continue
+		}
+		if change.Type == compare.StyleChanged {
+			node.restyled = append(node.restyled, change)
+			continue
+		}
+		if change.Type == compare.KeyRemoved {
+			node.removed = append(node.removed, change)
+			continue
+		}
+		if change.Type == compare.EntryCopied {
+			node.copied = append(node.copied, change)
+			continue
+		}
+		node.added = append(node.added, change)
 	}
 
 	stringBuilder.WriteString("\n  Changes:\n")
-	renderTree(&stringBuilder, root, "    ", color)
+	renderTree(&stringBuilder, root, "    ", filePath, color)
 
 	if commentCount > 0 {
 		fmt.Fprintf(&stringBuilder, "\n  Comments: all %d comments preserved\n", commentCount)
@@ -308,6 +286,36 @@ func FormatSummary(filePath string, descriptions []MoveDescription, addedFields
 	return stringBuilder.String()
 }
 
+// Additions returns the changes of a change log that add, copy, rename, restyle or remove keys,
+// which are summarized along with the moves of DescribeChanges
+func Additions(changes []compare.Change) []compare.Change {
+	additions := []compare.Change{}
+	for _, change := range changes {
+		switch change.Type {
+		case compare.KeyAdded, compare.SequenceItemAdded, compare.EntryCopied, compare.KeyRenamed, compare.StyleChanged, compare.KeyRemoved:
+			additions = append(additions, change)
+		}
+	}
+
+	return additions
+}
+
+// Action returns what one of the Additions does, as summaries annotate it, e.g. "add" or "rename from nmae"
+func Action(change compare.Change) string {
+	switch change.Type {
+	case compare.KeyRenamed:
+		return fmt.Sprintf("rename from %s", change.OldKey)
+	case compare.StyleChanged:
+		return fmt.Sprintf("%s style", change.Value)
+	case compare.KeyRemoved:
+		return "remove"
+	case compare.EntryCopied:
+		return fmt.Sprintf("copy from %s", change.Source)
+	}
+
+	return "add"
+}
+
 // IsTerminal reports whether stdout is connected to a terminal.
 func IsTerminal() bool {
 	file, err := os.Stdout.Stat()
@@ -352,7 +360,16 @@ const (
 	colorYellow = "\033[33;1m" // bold yellow — attention-grabbing but not "error red"
 )
 
-func renderTree(stringBuilder *strings.Builder, node *summaryNode, indent string, color bool) {
+// annotation returns the comment annotating a key in a summary, with its position in the file, if known
+func annotation(action, filePath string, line, column int) string {
+	if line == 0 {
+		return "# " + action
+	}
+
+	return fmt.Sprintf("# %s (%s:%d:%d)", action, filePath, line, column)
+}
+
+func renderTree(stringBuilder *strings.Builder, node *summaryNode, indent, filePath string, color bool) {
 	// Render this node's segment as a heading if it has one
 	if node.segment != "" {
 		fmt.Fprintf(stringBuilder, "%s%s:\n", indent, node.segment)
@@ -362,7 +379,7 @@ func renderTree(stringBuilder *strings.Builder, node *summaryNode, indent string
 	// Render moves at this level - each key on its own line, YAML-style
 	for _, move := range node.moves {
 		for _, keyInfo := range move.Keys {
-			comment := fmt.Sprintf("# %s", move.Action)
+			comment := annotation(move.Action, filePath, keyInfo.Line, keyInfo.Column)
 			if color {
 				comment = colorGreen + comment + colorReset
 			}
@@ -370,18 +387,67 @@ func renderTree(stringBuilder *strings.Builder, node *summaryNode, indent string
 		}
 	}
 
+	// Render renamed keys at this level
+	for _, change := range node.renamed {
+		comment := annotation(Action(change), filePath, change.Line, change.Column)
+		if color {
+			comment = colorYellow + comment + colorReset
+		}
+		fmt.Fprintf(stringBuilder, "%s%s: %s  %s\n", indent, change.Key, keyInfoForChange(change).valueDisplay(), comment)
+	}
+
+	// Render restyled keys at this level
+	for _, change := range node.restyled {
+		comment := annotation(Action(change), filePath, change.Line, change.Column)
+		if color {
+			comment = colorGreen + comment + colorReset
+		}
+		fmt.Fprintf(stringBuilder, "%s%s: %s  %s\n", indent, change.Key, KeyInfo{ValueKind: change.ValueKind}.valueDisplay(), comment)
+	}
+
+	// Render removed keys at this level
+	for _, change := range node.removed {
+		comment := annotation(Action(change), filePath, change.Line, change.Column)
+		if color {
+			comment = colorYellow + comment + colorReset
+		}
+		value := "{}"
+		if change.ValueKind == yaml.SequenceNode {
+			value = "[]"
+		}
+		fmt.Fprintf(stringBuilder, "%s%s: %s  %s\n", indent, change.Key, value, comment)
+	}
+
 	// Render added fields at this level
-	for _, key := range node.added {
-		comment := "# add"
+	for _, change := range node.added {
+		comment := annotation(Action(change), filePath, change.Line, change.Column)
+		if color {
+			comment = colorYellow + comment + colorReset
+		}
+		fmt.Fprintf(stringBuilder, "%s%s: TODO  %s\n", indent, change.Key, comment)
+	}
+
+	// Render copied entries at this level, with the values they were copied with
+	for _, change := range node.copied {
+		comment := annotation(Action(change), filePath, change.Line, change.Column)
+		if color {
+			comment = colorYellow + comment + colorReset
+		}
+		fmt.Fprintf(stringBuilder, "%s%s: %s  %s\n", indent, change.Key, keyInfoForChange(change).valueDisplay(), comment)
+	}
+
+	// Render items added to empty sequences at this level
+	for _, item := range node.items {
+		comment := annotation("add", filePath, item.Line, item.Column)
 		if color {
 			comment = colorYellow + comment + colorReset
 		}
-		fmt.Fprintf(stringBuilder, "%s%s: TODO  %s\n", indent, key, comment)
+		fmt.Fprintf(stringBuilder, "%s- %s  %s\n", indent, item.valueDisplay(), comment)
 	}
 
 	// Render children
 	for _, child := range node.children {
-		renderTree(stringBuilder, child, indent, color)
+		renderTree(stringBuilder, child, indent, filePath, color)
 	}
 }
 
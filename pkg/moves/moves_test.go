@@ -16,6 +16,7 @@ limitations under the License.
 package moves
 
 import (
+	"fmt"
 	"strings"
 	"testing"
 
@@ -35,23 +36,36 @@ func parseToNode(t *testing.T, content string) *compare.Node {
 	return node
 }
 
-func scalarKey(key, value string) KeyInfo {
-	return KeyInfo{Key: key, ValueKind: yaml.ScalarNode, Value: value}
+// sortToChanges sorts fileYAML using configYAML as the config, returning the change log.
+func sortToChanges(t *testing.T, configYAML, fileYAML string) []compare.Change {
+	t.Helper()
+	configNode := parseToNode(t, configYAML)
+	compare.WalkParseLoadConfigComments(configNode)
+	fileNode := parseToNode(t, fileYAML)
+	changes := []compare.Change{}
+	sortConfs := compare.SortConfigs{ConfigNodes: compare.ConfigNodes{}, Changes: &changes}
+	errs, _ := compare.WalkAndSort(configNode, fileNode, sortConfs, compare.ValidationErrors{})
+	if len(errs) != 0 {
+		t.Fatalf("unexpected sort errors:\n%s", compare.GetValidationErrorStrings(errs))
+	}
+	return changes
 }
 
-func mappingKey(key string) KeyInfo {
-	return KeyInfo{Key: key, ValueKind: yaml.MappingNode}
+// moved builds a KeyMoved change for a scalar value.
+func moved(path, key, value string, from, to int) compare.Change {
+	return compare.Change{Type: compare.KeyMoved, Path: path, Key: key, From: from, To: to, ValueKind: yaml.ScalarNode, Value: value}
 }
 
-func sequenceKey(key string) KeyInfo {
-	return KeyInfo{Key: key, ValueKind: yaml.SequenceNode}
+// movedKind builds a KeyMoved change for a map or sequence value.
+func movedKind(path, key string, kind yaml.Kind, from, to int) compare.Change {
+	return compare.Change{Type: compare.KeyMoved, Path: path, Key: key, From: from, To: to, ValueKind: kind}
 }
 
-func TestComputeDescriptions(t *testing.T) {
+func TestDescribeChanges(t *testing.T) {
 	tests := []struct {
 		name        string
 		oldYAML     string
-		newYAML     string
+		configYAML  string
 		wantDescs   int
 		wantContain string // substring to look for in FormatSummary output
 	}{
@@ -61,7 +75,7 @@ func TestComputeDescriptions(t *testing.T) {
 kind: Deployment
 metadata:
   name: test`,
-			newYAML: `apiVersion: apps/v1
+			configYAML: `apiVersion: apps/v1
 kind: Deployment
 metadata:
   name: test`,
@@ -74,7 +88,7 @@ metadata:
     app: test
   name: test
   namespace: default`,
-			newYAML: `metadata:
+			configYAML: `metadata:
   name: test
   namespace: default
   labels:
@@ -89,7 +103,7 @@ metadata:
     app: test
   namespace: default
   name: test`,
-			newYAML: `metadata:
+			configYAML: `metadata:
   name: test
   labels:
     app: test
@@ -109,7 +123,7 @@ spec:
         - containerPort: 8080
         name: app
         image: img`,
-			newYAML: `apiVersion: apps/v1
+			configYAML: `apiVersion: apps/v1
 kind: Deployment
 spec:
   template:
@@ -122,21 +136,40 @@ spec:
 			wantDescs:   2, // name move to top, image move up
 			wantContain: "name",
 		},
+		{
+			name: "unmatched key relocated",
+			oldYAML: `metadata:
+  extra: value
+  name: test`,
+			configYAML: `metadata:
+  name: test`,
+			wantDescs:   1,
+			wantContain: "name: test  # move to top",
+		},
+		{
+			name: "required key and sequence item added",
+			oldYAML: `spec:
+  listeners: []`,
+			configYAML: `spec:
+  listeners:  # required
+  - name: TODO  # first, required`,
+			wantDescs:   0,
+			wantContain: "name: TODO  # add",
+		},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			oldNode := parseToNode(t, tc.oldYAML)
-			newNode := parseToNode(t, tc.newYAML)
+			changes := sortToChanges(t, tc.configYAML, tc.oldYAML)
 
-			descs := ComputeDescriptions(oldNode, newNode)
+			descs := DescribeChanges(changes)
 
 			if len(descs) != tc.wantDescs {
 				t.Errorf("got %d descriptions, want %d. descriptions: %+v", len(descs), tc.wantDescs, descs)
 			}
 
 			if tc.wantContain != "" {
-				summary := FormatSummary("test.yaml", descs, nil, 0)
+				summary := FormatSummary("test.yaml", "test.yaml", changes, 0)
 				if !strings.Contains(summary, tc.wantContain) {
 					t.Errorf("summary doesn't contain %q:\n%s", tc.wantContain, summary)
 				}
@@ -145,6 +178,37 @@ spec:
 	}
 }
 
+func TestWalkAndSortRecordsChanges(t *testing.T) {
+	changes := sortToChanges(t, `metadata:
+  name: test  # required
+  labels:  # required
+    app: test  # required
+spec:
+  containers:  # required
+  - name: app  # required`, `spec:
+  containers: []
+metadata:
+  extra: value
+  labels: {}`)
+
+	got := []string{}
+	for _, change := range changes {
+		got = append(got, change.String())
+	}
+	want := []string{
+		"KeyMoved .metadata (1 -> 0)",
+		"KeyMoved .spec (0 -> 1)",
+		"KeyAdded .metadata.name",
+		"UnmatchedRelocated .metadata.extra (0 -> 2)",
+		"KeyAdded .metadata.labels.app",
+		"SequenceItemAdded .spec.containers[0]",
+		"KeyAdded .spec.containers[0].name",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("change log mismatch:\n-expected:\n%s\n+got:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
+
 func TestCountComments(t *testing.T) {
 	node := parseToNode(t, `apiVersion: apps/v1 # inline
 # head comment
@@ -160,13 +224,16 @@ metadata:
 }
 
 func TestFormatSummary(t *testing.T) {
-	descs := []MoveDescription{
-		{Path: "metadata", Keys: []KeyInfo{scalarKey("name", "cool-app"), scalarKey("namespace", "default")}, Action: "move up"},
-		{Path: "spec.template.spec.containers[0]", Keys: []KeyInfo{scalarKey("name", "app")}, Action: "move to top"},
+	changes := []compare.Change{
+		movedKind(".metadata", "labels", yaml.MappingNode, 1, 3),
+		moved(".metadata", "name", "cool-app", 2, 1),
+		moved(".metadata", "namespace", "default", 3, 2),
+		moved(".spec.template.spec.containers[0]", "name", "app", 1, 0),
+		moved(".spec.template.spec.containers[0]", "image", "img", 0, 1),
+		{Type: compare.KeyAdded, Path: ".spec.template.spec.containers[0]", Key: "imagePullPolicy", From: -1, To: 2},
 	}
-	added := []compare.AddedField{{Path: ".spec.template.spec.containers[0]", Key: "imagePullPolicy"}}
 
-	summary := FormatSummary("deployment.yaml", descs, added, 3)
+	summary := FormatSummary("deployment.yaml", "deployment.yaml", changes, 3)
 
 	if !strings.Contains(summary, "deployment.yaml") {
 		t.Error("summary missing file path")
@@ -202,12 +269,14 @@ func TestFormatSummary(t *testing.T) {
 
 func TestFormatSummaryGrouping(t *testing.T) {
 	// Two moves at the same path should be grouped under one heading
-	descs := []MoveDescription{
-		{Path: "metadata", Keys: []KeyInfo{scalarKey("name", "test")}, Action: "move to top"},
-		{Path: "metadata", Keys: []KeyInfo{scalarKey("namespace", "default")}, Action: "move up"},
+	changes := []compare.Change{
+		moved(".metadata", "name", "test", 3, 0),
+		moved(".metadata", "namespace", "default", 2, 1),
+		movedKind(".metadata", "labels", yaml.MappingNode, 0, 2),
+		moved(".metadata", "extra", "value", 1, 3),
 	}
 
-	summary := FormatSummary("test.yaml", descs, nil, 0)
+	summary := FormatSummary("test.yaml", "test.yaml", changes, 0)
 
 	metadataCount := strings.Count(summary, "metadata:\n")
 	if metadataCount != 1 {
@@ -222,14 +291,13 @@ func TestFormatSummaryGrouping(t *testing.T) {
 }
 
 func TestFormatSummaryValueKinds(t *testing.T) {
-	descs := []MoveDescription{
-		{Path: "spec", Keys: []KeyInfo{
-			mappingKey("selector"),
-			sequenceKey("ports"),
-		}, Action: "move up"},
+	changes := []compare.Change{
+		movedKind(".spec", "selector", yaml.MappingNode, 2, 1),
+		movedKind(".spec", "ports", yaml.SequenceNode, 3, 2),
+		moved(".spec", "type", "ClusterIP", 1, 3),
 	}
 
-	summary := FormatSummary("test.yaml", descs, nil, 0)
+	summary := FormatSummary("test.yaml", "test.yaml", changes, 0)
 
 	if !strings.Contains(summary, "selector: {...}  # move up") {
 		t.Errorf("mapping value should show {...}:\n%s", summary)
@@ -242,12 +310,13 @@ func TestFormatSummaryValueKinds(t *testing.T) {
 func TestFormatSummaryMergesSameAction(t *testing.T) {
 	// Two moves at the same path with the same action should merge keys.
 	// Since keys are now on separate lines, both should appear.
-	descs := []MoveDescription{
-		{Path: "spec", Keys: []KeyInfo{scalarKey("port", "8080")}, Action: "move up"},
-		{Path: "spec", Keys: []KeyInfo{scalarKey("targetPort", "8080")}, Action: "move up"},
+	changes := []compare.Change{
+		moved(".spec", "port", "8080", 2, 1),
+		moved(".spec", "targetPort", "8080", 3, 2),
+		moved(".spec", "protocol", "TCP", 1, 3),
 	}
 
-	summary := FormatSummary("test.yaml", descs, nil, 0)
+	summary := FormatSummary("test.yaml", "test.yaml", changes, 0)
 
 	if !strings.Contains(summary, "port: 8080  # move up") {
 		t.Errorf("missing port move:\n%s", summary)
@@ -256,3 +325,114 @@ func TestFormatSummaryMergesSameAction(t *testing.T) {
 		t.Errorf("missing targetPort move:\n%s", summary)
 	}
 }
+
+func TestFormatSummarySequenceItemAdded(t *testing.T) {
+	changes := []compare.Change{
+		{Type: compare.SequenceItemAdded, Path: ".spec.listeners", From: -1, ValueKind: yaml.MappingNode},
+		{Type: compare.KeyAdded, Path: ".spec.listeners[0]", Key: "name", From: -1},
+	}
+
+	summary := FormatSummary("test.yaml", "test.yaml", changes, 0)
+
+	if !strings.Contains(summary, "    spec:\n      listeners:\n        - {...}  # add\n      listeners[0]:\n        name: TODO  # add\n") {
+		t.Errorf("expected added sequence item:\n%s", summary)
+	}
+}
+
+func TestFormatSummaryKeyRenamed(t *testing.T) {
+	changes := []compare.Change{
+		{Type: compare.KeyRenamed, Path: ".spec", Key: "replicas", OldKey: "replica", From: 1, To: 1, ValueKind: yaml.ScalarNode, Value: "3"},
+	}
+
+	summary := FormatSummary("test.yaml", "test.yaml", changes, 0)
+
+	if !strings.Contains(summary, "    spec:\n      replicas: 3  # rename from replica\n") {
+		t.Errorf("expected renamed key:\n%s", summary)
+	}
+}
+
+func TestFormatSummaryStyleChanged(t *testing.T) {
+	changes := []compare.Change{
+		{Type: compare.StyleChanged, Path: ".spec.containers[0]", Key: "args", From: 1, To: 1, ValueKind: yaml.SequenceNode, Value: "flow"},
+	}
+
+	summary := FormatSummary("test.yaml", "test.yaml", changes, 0)
+
+	if !strings.Contains(summary, "    spec:\n      containers[0]:\n        args: [...]  # flow style\n") {
+		t.Errorf("expected restyled key:\n%s", summary)
+	}
+}
+
+func TestFormatSummaryKeyRemoved(t *testing.T) {
+	changes := []compare.Change{
+		{Type: compare.KeyRemoved, Path: ".metadata", Key: "annotations", From: 2, To: -1, ValueKind: yaml.MappingNode},
+		{Type: compare.KeyRemoved, Path: ".spec.containers[0]", Key: "envFrom", From: 3, To: -1, ValueKind: yaml.SequenceNode},
+	}
+
+	summary := FormatSummary("test.yaml", "test.yaml", changes, 0)
+
+	if !strings.Contains(summary, "    metadata:\n      annotations: {}  # remove\n    spec:\n      containers[0]:\n        envFrom: []  # remove\n") {
+		t.Errorf("expected removed keys:\n%s", summary)
+	}
+}
+
+func TestFormatSummaryEntryCopied(t *testing.T) {
+	changes := []compare.Change{
+		{Type: compare.EntryCopied, Path: ".spec.template.metadata.labels", Key: "tier", From: -1, To: 1, ValueKind: yaml.ScalarNode, Value: "web", Source: ".spec.selector.matchLabels"},
+		{Type: compare.EntryCopied, Path: ".spec.template.metadata.annotations", Key: "links", From: -1, To: 1, ValueKind: yaml.MappingNode, Source: ".metadata.annotations"},
+	}
+
+	summary := FormatSummary("test.yaml", "test.yaml", changes, 0)
+
+	if !strings.Contains(summary, "          labels:\n            tier: web  # copy from .spec.selector.matchLabels\n") {
+		t.Errorf("expected copied scalar entry:\n%s", summary)
+	}
+	if !strings.Contains(summary, "          annotations:\n            links: {...}  # copy from .metadata.annotations\n") {
+		t.Errorf("expected copied map entry:\n%s", summary)
+	}
+	if strings.Contains(summary, "TODO") || strings.Contains(summary, "# add") {
+		t.Errorf("copied entries shouldn't be summarized as additions:\n%s", summary)
+	}
+}
+
+func TestFormatSummaryPositions(t *testing.T) {
+	changes := sortToChanges(t, `metadata:
+  name: test  # first
+  namespace: TODO  # required
+  labels: {}`, `metadata:
+  labels: {}
+  name: test`)
+
+	summary := FormatSummary("app.yaml (document 2)", "app.yaml", changes, 0)
+
+	for _, want := range []string{
+		"File: app.yaml (document 2)\n",
+		"      name: test  # move to top (app.yaml:3:3)\n",
+		"      namespace: TODO  # add (app.yaml:2:3)\n",
+	} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("summary doesn't contain %q:\n%s", want, summary)
+		}
+	}
+
+	// moved keys know the key they come after, and its line unless it was added
+	got := []string{}
+	for _, description := range DescribeChanges(changes) {
+		for _, key := range description.Keys {
+			got = append(got, fmt.Sprintf("%s after '%s' (line %d)", key.Key, key.After, key.AfterLine))
+		}
+	}
+	for _, change := range changes {
+		line, _ := AfterPosition(change)
+		got = append(got, fmt.Sprintf("%s %s after line %d", change.Type, change.Key, line))
+	}
+	want := []string{
+		"name after '' (line 0)",
+		"KeyMoved name after line 0",
+		"KeyAdded namespace after line 3",
+		"KeyMoved labels after line 0",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("after positions: \n-expected:\n%s\n+got:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
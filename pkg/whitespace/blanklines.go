@@ -0,0 +1,220 @@
+/*
+Copyright © 2022 david amick git@davidamick.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package whitespace
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/snarlysodboxer/predictable-yaml/pkg/compare"
+	"go.yaml.in/yaml/v3"
+)
+
+// blankLineEdit is a blank line to insert above a line, or a blank line to remove
+type blankLineEdit struct {
+	line   int
+	insert bool
+	err    error
+}
+
+// CheckBlankLines returns errors for keys marked 'blank-before' in the config that have no blank line above them,
+// and for blank lines in the values of keys marked 'no-blank'. Line numbers are those of content.
+func CheckBlankLines(content []byte, configNode *compare.Node, sortConfs compare.SortConfigs) (compare.ValidationErrors, error) {
+	edits, err := getBlankLineEdits(content, configNode, sortConfs)
+	if err != nil {
+		return compare.ValidationErrors{}, err
+	}
+	errs := compare.ValidationErrors{}
+	for _, edit := range edits {
+		errs = append(errs, edit.err)
+	}
+
+	return errs, nil
+}
+
+// FixBlankLines inserts and removes empty lines in content to match the 'blank-before' and 'no-blank' keys of the config
+func FixBlankLines(content []byte, configNode *compare.Node, sortConfs compare.SortConfigs) ([]byte, error) {
+	edits, err := getBlankLineEdits(content, configNode, sortConfs)
+	if err != nil || len(edits) == 0 {
+		return content, err
+	}
+
+	// edit from the bottom up so line numbers above stay correct
+	lines := strings.Split(string(content), "\n")
+	for i := len(edits) - 1; i >= 0; i-- {
+		edit := edits[i]
+		if edit.insert {
+			lines = insertLine(lines, edit.line-1, "")
+			continue
+		}
+		lines = append(lines[:edit.line-1], lines[edit.line:]...)
+	}
+
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+// getBlankLineEdits returns the edits needed to match the config's blank line directives, sorted by line
+func getBlankLineEdits(content []byte, configNode *compare.Node, sortConfs compare.SortConfigs) ([]blankLineEdit, error) {
+	yamlNode := &yaml.Node{}
+	err := yaml.Unmarshal(content, yamlNode)
+	if err != nil {
+		return nil, err
+	}
+	fileNode := &compare.Node{Node: yamlNode}
+	compare.WalkConvertYamlNodeToMainNode(fileNode)
+	spacing := compare.WalkFindSpacing(configNode, fileNode, sortConfs)
+
+	lines := strings.Split(string(content), "\n")
+	isBlank := func(line int) bool {
+		return line >= 1 && line <= len(lines) && strings.TrimSpace(lines[line-1]) == ""
+	}
+
+	// lines of block scalars that keep their trailing line breaks ('|+' and '>+'), where blank lines are
+	//   part of the value, so they can't be removed, and a blank line can't be inserted below them
+	keptLines := getKeptScalarLines(lines, fileNode, map[int]bool{})
+
+	edits := []blankLineEdit{}
+	blankBeforeLines := map[int]bool{}
+	for _, keyNode := range spacing.BlankBefore {
+		if !needsBlankBefore(keyNode) {
+			continue
+		}
+		blankBeforeLines[keyNode.Line] = true
+		start := commentBlockStart(lines, keyNode.Line)
+		if start <= 1 || isBlank(start-1) || strings.TrimSpace(lines[start-2]) == "---" || keptLines[start-1] {
+			continue
+		}
+		edits = append(edits, blankLineEdit{
+			line:   start,
+			insert: true,
+			err:    compare.NewDiagnostic(compare.RuleBlankBefore, keyNode, "validation error: expected a blank line before '%s' (line %d)", compare.GetReferencePath(keyNode, 0, ""), keyNode.Line),
+		})
+	}
+
+	removed := map[int]bool{}
+	for _, keyNode := range spacing.NoBlank {
+		path := compare.GetReferencePath(keyNode, 0, "")
+		valueNode := keyNode.ParentNode.NodeContent[keyNode.Index+1]
+		for _, line := range getBlockStartLines(valueNode, []int{}) {
+			if blankBeforeLines[line] {
+				continue
+			}
+			for blank := commentBlockStart(lines, line) - 1; blank > keyNode.Line && isBlank(blank) && !keptLines[blank]; blank-- {
+				if removed[blank] {
+					continue
+				}
+				removed[blank] = true
+				edits = append(edits, blankLineEdit{
+					line: blank,
+					err: &compare.Diagnostic{
+						Rule:    compare.RuleNoBlank,
+						Path:    path,
+						Line:    blank,
+						Column:  1,
+						Message: fmt.Sprintf("validation error: unexpected blank line at line %d in '%s'", blank, path),
+					},
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(edits, func(i, j int) bool {
+		return edits[i].line < edits[j].line
+	})
+
+	return edits, nil
+}
+
+// needsBlankBefore reports whether a key marked 'blank-before' needs a blank line above it.
+// Keys that start their parent's block don't, except for the first key of a sequence item after the first.
+func needsBlankBefore(keyNode *compare.Node) bool {
+	mapNode := keyNode.ParentNode
+	if mapNode.Style == yaml.FlowStyle {
+		return false
+	}
+	if keyNode.Index != 0 {
+		return true
+	}
+	parent := mapNode.ParentNode
+
+	return parent != nil && parent.Kind == yaml.SequenceNode && parent.Style != yaml.FlowStyle && mapNode.Index > 0
+}
+
+// getBlockStartLines returns the lines of the keys and sequence items in a block style node and its children
+func getBlockStartLines(node *compare.Node, startLines []int) []int {
+	if node.Style == yaml.FlowStyle {
+		return startLines
+	}
+	switch node.Kind {
+	case yaml.MappingNode:
+		for _, pair := range compare.GetKeyValuePairs(node.NodeContent) {
+			startLines = append(startLines, pair.KeyNode.Line)
+			startLines = getBlockStartLines(pair.ValueNode, startLines)
+		}
+	case yaml.SequenceNode:
+		for _, item := range node.NodeContent {
+			startLines = append(startLines, item.Line)
+			startLines = getBlockStartLines(item, startLines)
+		}
+	}
+
+	return startLines
+}
+
+// getKeptScalarLines returns the lines below the indicators of literal and folded block scalars with keep
+// chomping, through their trailing blank lines
+func getKeptScalarLines(lines []string, node *compare.Node, keptLines map[int]bool) map[int]bool {
+	for _, child := range node.NodeContent {
+		getKeptScalarLines(lines, child, keptLines)
+	}
+	if node.Kind != yaml.ScalarNode || (node.Style != yaml.LiteralStyle && node.Style != yaml.FoldedStyle) ||
+		node.Line < 1 || node.Line > len(lines) || node.Column < 1 || node.Column > len(lines[node.Line-1]) {
+		return keptLines
+	}
+	// the header, e.g. '|+' or '>2+', marks keep chomping with '+'
+	header, _, _ := strings.Cut(lines[node.Line-1][node.Column-1:], " ")
+	if !strings.Contains(header, "+") {
+		return keptLines
+	}
+
+	// the block is its blank lines and the lines indented at least as much as its first non-blank line
+	indentation := -1
+	for line := node.Line + 1; line <= len(lines); line++ {
+		text := lines[line-1]
+		if strings.TrimSpace(text) != "" {
+			lineIndentation := len(text) - len(strings.TrimLeft(text, " "))
+			if indentation == -1 && strings.TrimRight(node.Value, "\n") != "" {
+				indentation = lineIndentation
+			}
+			if indentation == -1 || lineIndentation < indentation {
+				break
+			}
+		}
+		keptLines[line] = true
+	}
+
+	return keptLines
+}
+
+// commentBlockStart returns the first line of the comment lines directly above a line, or the line itself
+func commentBlockStart(lines []string, line int) int {
+	for line > 1 && strings.HasPrefix(strings.TrimSpace(lines[line-2]), "#") {
+		line--
+	}
+
+	return line
+}
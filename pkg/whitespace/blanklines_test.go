@@ -0,0 +1,207 @@
+/*
+Copyright © 2022 david amick git@davidamick.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package whitespace
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/snarlysodboxer/predictable-yaml/pkg/compare"
+	"go.yaml.in/yaml/v3"
+)
+
+func TestBlankLines(t *testing.T) {
+	configYaml := `---
+kind: Deployment  # first, required
+metadata:  # no-blank
+  name: TODO
+  labels: {}  # open
+spec:  # blank-before
+  containers:
+  - name: TODO  # first, blank-before
+    image: TODO
+    command: []
+`
+
+	type testCase struct {
+		note            string
+		content         string
+		expectedErrs    compare.ValidationErrors
+		expectedContent string
+	}
+
+	testCases := []testCase{
+		{
+			note: "spacing matches",
+			content: `---
+kind: Deployment
+metadata:
+  name: example
+  labels: {app: example}
+
+spec:
+  containers:
+  - name: one
+    image: example
+
+  # the sidecar
+  - name: two
+    command:
+    - |
+      echo one
+
+      echo two
+`,
+			expectedErrs: compare.ValidationErrors{},
+		},
+		{
+			note: "spacing fixed",
+			content: `---
+kind: Deployment
+metadata:
+
+  name: example
+  # the labels
+
+  labels:
+    app: example
+
+    team: cool-team
+spec:
+  containers:
+
+  - name: one
+    image: example
+  # the sidecar
+  - name: two
+`,
+			expectedErrs: compare.ValidationErrors{
+				fmt.Errorf("validation error: unexpected blank line at line 4 in '.metadata'"),
+				fmt.Errorf("validation error: unexpected blank line at line 7 in '.metadata'"),
+				fmt.Errorf("validation error: unexpected blank line at line 10 in '.metadata'"),
+				fmt.Errorf("validation error: expected a blank line before '.spec' (line 12)"),
+				fmt.Errorf("validation error: expected a blank line before '.spec.containers[1].name' (line 18)"),
+			},
+			expectedContent: `---
+kind: Deployment
+metadata:
+  name: example
+  # the labels
+  labels:
+    app: example
+    team: cool-team
+
+spec:
+  containers:
+
+  - name: one
+    image: example
+
+  # the sidecar
+  - name: two
+`,
+		},
+		{
+			note: "blank lines kept by block scalars aren't removed or added below them",
+			content: `---
+kind: Deployment
+metadata:
+  name: |+
+    line
+
+  labels: >+
+    folded
+    line
+
+
+spec:
+  containers:
+  - name: one
+    image: |+
+      example
+  - name: two
+`,
+			expectedErrs: compare.ValidationErrors{},
+		},
+		{
+			note: "blank lines below block scalars that don't keep them are removed",
+			content: `---
+kind: Deployment
+metadata:
+  name: |
+    line
+
+  labels: >-
+    folded
+
+spec:
+  containers:
+  - name: one
+`,
+			expectedErrs: compare.ValidationErrors{
+				fmt.Errorf("validation error: unexpected blank line at line 6 in '.metadata'"),
+			},
+			expectedContent: `---
+kind: Deployment
+metadata:
+  name: |
+    line
+  labels: >-
+    folded
+
+spec:
+  containers:
+  - name: one
+`,
+		},
+	}
+
+	cN := &yaml.Node{}
+	err := yaml.Unmarshal([]byte(configYaml), cN)
+	if err != nil {
+		t.Fatalf("failed unmarshaling config test data: %v", err)
+	}
+	configNode := &compare.Node{Node: cN}
+	compare.WalkConvertYamlNodeToMainNode(configNode)
+	compare.WalkParseLoadConfigComments(configNode)
+	sortConfigs := compare.SortConfigs{ConfigNodes: compare.ConfigNodes{"Deployment": configNode}}
+
+	for _, tc := range testCases {
+		gotErrs, err := CheckBlankLines([]byte(tc.content), configNode, sortConfigs)
+		if err != nil {
+			t.Errorf("Description: %s: whitespace.CheckBlankLines(...): unexpected error: %v", tc.note, err)
+			continue
+		}
+		expected := compare.GetValidationErrorStrings(tc.expectedErrs)
+		got := compare.GetValidationErrorStrings(gotErrs)
+		if got != expected {
+			t.Errorf("Description: %s: whitespace.CheckBlankLines(...): \n-expected:\n%v\n+got:\n%v\n", tc.note, expected, got)
+		}
+
+		expectedContent := tc.expectedContent
+		if expectedContent == "" {
+			expectedContent = tc.content
+		}
+		gotContent, err := FixBlankLines([]byte(tc.content), configNode, sortConfigs)
+		if err != nil {
+			t.Errorf("Description: %s: whitespace.FixBlankLines(...): unexpected error: %v", tc.note, err)
+			continue
+		}
+		if string(gotContent) != expectedContent {
+			t.Errorf("Description: %s: whitespace.FixBlankLines(...): \n-expected:\n%v\n+got:\n%v\n", tc.note, expectedContent, string(gotContent))
+		}
+	}
+}